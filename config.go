@@ -22,80 +22,598 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // quiet hours time zones on systems without zoneinfo
+
 	"github.com/BurntSushi/toml"
+	"github.com/dkorunic/e-dnevnik-bot/fetch"
+	"github.com/dkorunic/e-dnevnik-bot/format"
 	"github.com/dkorunic/e-dnevnik-bot/logger"
+	"github.com/dkorunic/e-dnevnik-bot/messenger"
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+	"golang.org/x/oauth2"
+)
+
+const (
+	discordName       = "discord"
+	telegramName      = "telegram"
+	slackName         = "slack"
+	mailName          = "mail"
+	calendarName      = "calendar"
+	teamsName         = "teams"
+	pushoverName      = "pushover"
+	jsonLinesName     = "jsonlines"
+	gotifyName        = "gotify"
+	mastodonName      = "mastodon"
+	appriseName       = "apprise"
+	twilioName        = "twilio"
+	rocketChatName    = "rocketchat"
+	mqttName          = "mqtt"
+	ircName           = "irc"
+	pushbulletName    = "pushbullet"
+	homeAssistantName = "homeassistant"
+
+	PushoverMinPriority = -2 // lowest Pushover priority
+	PushoverMaxPriority = 1  // highest Pushover priority not requiring acknowledgement
+
+	GotifyDefaultPriority = 5 // default Gotify priority (shown as a notification)
+
+	MQTTDefaultQoS = 1 // default MQTT QoS (at least once delivery)
+	MQTTMaxQoS     = 2 // highest MQTT QoS (exactly once delivery)
+
+	IRCDefaultPort    = 6667 // default IRC port
+	IRCDefaultTLSPort = 6697 // default IRC over TLS port
+
+	digestTimeFormat = "15:04" // digest time of day format
+
+	AliasPrefix = "@" // prefix of a named recipient alias in messenger recipient lists
+
+	ConfigStdin   = "-"                // configuration file name for reading configuration from standard input
+	ConfigEnv     = "E_DNEVNIK_CONFIG" // environment variable holding full configuration, used if -f is not set
+	configEnvFile = "$" + ConfigEnv    // configuration file name for reading configuration from the environment
+)
+
+var (
+	ErrInvalidTarget    = errors.New("unknown messenger in user targets")
+	ErrConfigNotFile    = errors.New("configuration has to be read from a file")
+	ErrInvalidRateLimit = errors.New("rate limit and window must be positive")
+	ErrInvalidWebhook   = errors.New("invalid Microsoft Teams webhook URL, must be an absolute HTTPS URL")
+	ErrInvalidDiscord   = errors.New("invalid Discord configuration")
+	ErrInvalidTelegram  = errors.New("invalid Telegram configuration")
+	ErrInvalidPushover  = errors.New("invalid Pushover configuration")
+	ErrInvalidRelevance = errors.New("relevance period must be set for " + codeNames(relevanceCodes) +
+		" and not negative")
+	ErrInvalidGotify        = errors.New("invalid Gotify configuration")
+	ErrInvalidMastodon      = errors.New("invalid Mastodon configuration")
+	ErrInvalidDigest        = errors.New("invalid digest configuration")
+	ErrInvalidQuietHours    = errors.New("invalid quiet hours configuration")
+	ErrInvalidCalendar      = errors.New("invalid Google Calendar configuration")
+	ErrInvalidMail          = errors.New("invalid e-mail configuration")
+	ErrInvalidTwilio        = errors.New("invalid Twilio configuration")
+	ErrInvalidHTTP          = errors.New("invalid HTTP client configuration")
+	ErrInvalidRocketChat    = errors.New("invalid Rocket.Chat configuration")
+	ErrInvalidMQTT          = errors.New("invalid MQTT configuration")
+	ErrInvalidIRC           = errors.New("invalid IRC configuration")
+	ErrInvalidPushbullet    = errors.New("invalid Pushbullet configuration")
+	ErrInvalidHomeAssistant = errors.New("invalid Home Assistant configuration")
+	ErrInvalidInterval      = errors.New("user poll interval has to be positive")
+	ErrInvalidAlias         = errors.New("invalid recipient alias")
+	ErrInvalidCoalesce      = errors.New("unknown or unsupported messenger in coalesce")
+
+	// tlsVersions maps supported minimum TLS versions for scraping.
+	tlsVersions = map[string]uint16{"1.2": tls.VersionTLS12, "1.3": tls.VersionTLS13}
+
+	messengerNames = []string{discordName, telegramName, slackName, mailName, calendarName, teamsName, pushoverName,
+		jsonLinesName, gotifyName, mastodonName, appriseName, twilioName, rocketChatName, mqttName, ircName,
+		pushbulletName, homeAssistantName}
+
+	// mastodonVisibilities are permitted Mastodon status visibilities
+	mastodonVisibilities = []string{"public", "unlisted", "private", "direct"}
+
+	// mqttSchemes are MQTT broker URL schemes supported by the MQTT client
+	mqttSchemes = []string{"tcp", "mqtt", "ssl", "tls", "mqtts", "mqtt+ssl", "tcps", "ws", "wss", "unix"}
+
+	// phoneRegexp matches phone numbers in E.164 format
+	phoneRegexp = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+	// homeAssistantServiceRegexp matches Home Assistant notify service names (without the notify domain)
+	homeAssistantServiceRegexp = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+	// weekdays are permitted weekly digest weekdays
+	weekdays = []time.Weekday{
+		time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday,
+		time.Sunday,
+	}
+
+	// relevanceCodes are event types with a past date, which can have their own relevance period
+	relevanceCodes = []msgtypes.EventCode{msgtypes.Grade, msgtypes.Absence, msgtypes.Note, msgtypes.NationalExam}
+
+	// eventCodes are all event types, which can be referenced by name in messenger configuration
+	eventCodes = []msgtypes.EventCode{
+		msgtypes.Grade, msgtypes.Exam, msgtypes.Absence, msgtypes.EnrollmentChange, msgtypes.Note,
+		msgtypes.Digest, msgtypes.Schedule, msgtypes.NationalExam,
+	}
 )
 
+// rateLimit struct holds optional messenger rate limit overrides (zero means messenger default).
+type rateLimit struct {
+	RateLimit int           `toml:"ratelimit"`
+	Window    time.Duration `toml:"window"`
+}
+
 // user struct holds a single AAI/SSO username.
 type user struct {
-	Username string `toml:"username"`
-	Password string `toml:"password"`
+	Username        string        `toml:"username"`
+	Password        string        `toml:"password"`
+	Targets         []string      `toml:"targets"`          // messengers receiving alerts for this user (empty means all)
+	IncludeSubjects []string      `toml:"include_subjects"` // subjects to alert on (empty means all)
+	ExcludeSubjects []string      `toml:"exclude_subjects"` // subjects never to alert on
+	DisplayName     string        `toml:"display_name"`     // student name shown in messages instead of username
+	Interval        time.Duration `toml:"interval"`         // poll interval for this user (zero means -i flag value)
+}
+
+// interval returns poll interval of the user, defaulting to the global poll interval.
+func (u user) interval() time.Duration {
+	if u.Interval > 0 {
+		return u.Interval
+	}
+
+	return *tickInterval
+}
+
+// wantsSubject reports if alerts for the subject should be sent for the user, matching subject names without class
+// name suffix case-insensitively.
+func (u user) wantsSubject(subject string) bool {
+	subject, _, _ = strings.Cut(subject, " / ")
+	subject = strings.TrimSpace(subject)
+
+	match := func(s string) bool {
+		return strings.EqualFold(strings.TrimSpace(s), subject)
+	}
+
+	if len(u.IncludeSubjects) > 0 && !slices.ContainsFunc(u.IncludeSubjects, match) {
+		return false
+	}
+
+	return !slices.ContainsFunc(u.ExcludeSubjects, match)
 }
 
 // telegram struct holds Telegram messenger configuration.
 type telegram struct {
-	Token   string   `toml:"token"`
-	ChatIDs []string `toml:"chatids"`
+	Topics     map[string]int `toml:"topics"` // event type (grade, exam, absence, enrollment, note, digest, schedule, national_exam or default) to topic ID
+	Token      string         `toml:"token"`
+	ChatIDs    []string       `toml:"chatids"`
+	UrgentDays int            `toml:"urgent_days"` // emphasize exams taking place in at most this many days (0 disables)
+	PinUrgent  bool           `toml:"pin_urgent"`  // pin urgent exam messages (bot has to be a chat administrator)
+	Silent     []string       `toml:"silent"`      // event types sent without a notification sound
+	rateLimit
 }
 
 // discord struct holds Discord messenger configuration.
 type discord struct {
-	Token   string   `toml:"token"`
-	UserIDs []string `toml:"userids"`
+	Token    string   `toml:"token"`
+	UserIDs  []string `toml:"userids"`
+	Webhooks []string `toml:"webhooks"` // channel webhook URLs, used instead of a bot token
+	rateLimit
 }
 
 // slack struct holds Slack messenger configuration.
 type slack struct {
 	Token   string   `toml:"token"`
 	ChatIDs []string `toml:"chatids"`
+	Threads bool     `toml:"threads"` // thread alerts of the same user and subject within a run
+	rateLimit
+}
+
+// teams struct holds Microsoft Teams messenger configuration.
+type teams struct {
+	Webhooks []string `toml:"webhooks"`
+	rateLimit
+}
+
+// pushover struct holds Pushover messenger configuration.
+type pushover struct {
+	Token        string   `toml:"token"`
+	UserKeys     []string `toml:"userkeys"`
+	Priority     int      `toml:"priority"`      // priority of grades and other events
+	ExamPriority *int     `toml:"exam_priority"` // priority of exams (default is one above priority)
+	rateLimit
+}
+
+// examPriority returns Pushover priority for exams, defaulting to one level above the regular priority.
+func (p pushover) examPriority() int {
+	if p.ExamPriority != nil {
+		return *p.ExamPriority
+	}
+
+	return min(p.Priority+1, PushoverMaxPriority)
+}
+
+// gotify struct holds Gotify messenger configuration.
+type gotify struct {
+	Server             string `toml:"server"`
+	Token              string `toml:"token"`
+	Priority           *int   `toml:"priority"`             // message priority (default is GotifyDefaultPriority)
+	InsecureSkipVerify bool   `toml:"insecure_skip_verify"` // skip TLS certificate verification
+	rateLimit
+}
+
+// priority returns Gotify message priority, defaulting to GotifyDefaultPriority.
+func (g gotify) priority() int {
+	if g.Priority != nil {
+		return *g.Priority
+	}
+
+	return GotifyDefaultPriority
+}
+
+// mastodon struct holds Mastodon messenger configuration.
+type mastodon struct {
+	Instance           string   `toml:"instance"`
+	Token              string   `toml:"token"`
+	Visibility         string   `toml:"visibility"`           // status visibility (default is direct)
+	Accounts           []string `toml:"accounts"`             // mentioned accounts receiving direct statuses
+	InsecureSkipVerify bool     `toml:"insecure_skip_verify"` // skip TLS certificate verification
+	rateLimit
+}
+
+// twilio struct holds Twilio SMS messenger configuration.
+type twilio struct {
+	AccountSID string   `toml:"account_sid"`
+	Token      string   `toml:"token"`
+	From       string   `toml:"from"` // sender phone number
+	To         []string `toml:"to"`   // recipient phone numbers
+	rateLimit
+}
+
+// rocketChat struct holds Rocket.Chat messenger configuration.
+type rocketChat struct {
+	Server             string   `toml:"server"`
+	UserID             string   `toml:"userid"`
+	Token              string   `toml:"token"`
+	Channels           []string `toml:"channels"`             // channels (#channel), users (@user) or room IDs
+	InsecureSkipVerify bool     `toml:"insecure_skip_verify"` // skip TLS certificate verification
+	rateLimit
+}
+
+// mqtt struct holds MQTT publisher configuration.
+type mqtt struct {
+	Broker      string `toml:"broker"`
+	ClientID    string `toml:"client_id"`
+	TopicPrefix string `toml:"topic_prefix"`
+	Username    string `toml:"username"`
+	Password    string `toml:"password"`
+	QoS         *int   `toml:"qos"`      // MQTT QoS level 0-2 (default is 1)
+	Retained    bool   `toml:"retained"` // publish with retained flag
+	rateLimit
+}
+
+// qos returns configured MQTT QoS level or the default one.
+func (m mqtt) qos() byte {
+	if m.QoS != nil {
+		return byte(*m.QoS) //nolint:gosec
+	}
+
+	return MQTTDefaultQoS
+}
+
+// irc struct holds IRC messenger configuration.
+type irc struct {
+	Server       string   `toml:"server"`
+	Port         int      `toml:"port"` // server port (default is 6697 with TLS, 6667 otherwise)
+	TLS          bool     `toml:"tls"`
+	Nick         string   `toml:"nick"`
+	Channels     []string `toml:"channels"` // channels (#channel) or nicks
+	SASLUser     string   `toml:"sasl_user"`
+	SASLPassword string   `toml:"sasl_password"`
+	rateLimit
+}
+
+// port returns configured IRC server port or the default one.
+func (i irc) port() int {
+	switch {
+	case i.Port != 0:
+		return i.Port
+	case i.TLS:
+		return IRCDefaultTLSPort
+	default:
+		return IRCDefaultPort
+	}
+}
+
+// pushbullet struct holds Pushbullet messenger configuration.
+type pushbullet struct {
+	Token     string   `toml:"token"`
+	DeviceIDs []string `toml:"device_ids"` // device idens to push to (empty means all devices)
+	rateLimit
+}
+
+// homeAssistant struct holds Home Assistant messenger configuration.
+type homeAssistant struct {
+	Server             string   `toml:"server"`
+	Token              string   `toml:"token"`                // long-lived access token
+	Services           []string `toml:"services"`             // notify service names (ie. mobile_app_phone)
+	InsecureSkipVerify bool     `toml:"insecure_skip_verify"` // skip TLS certificate verification
+	rateLimit
+}
+
+// jsonLines struct holds JSON Lines file sink configuration.
+type jsonLines struct {
+	Path string `toml:"path"`
 }
 
 // mail struct hold e-mail messenger configuration.
 type mail struct {
-	Server   string   `toml:"server"`
-	Port     string   `toml:"port"`
-	Username string   `toml:"username"`
-	Password string   `toml:"password"`
-	From     string   `toml:"from"`
-	Subject  string   `toml:"subject"`
-	To       []string `toml:"to"`
+	Server             string   `toml:"server"`
+	Port               string   `toml:"port"`
+	Username           string   `toml:"username"`
+	Password           string   `toml:"password"`
+	Auth               string   `toml:"auth"`              // SMTP authentication mechanism (plain, login or xoauth2)
+	TLS                string   `toml:"tls"`               // SMTP TLS mode (none, opportunistic, mandatory or implicit)
+	OAuthCredentials   string   `toml:"oauth_credentials"` // OAuth2 client credentials file for xoauth2
+	OAuthToken         string   `toml:"oauth_token"`       // OAuth2 token file for xoauth2 (default is mail_token.json)
+	OAuthScopes        []string `toml:"oauth_scopes"`      // OAuth2 scopes for xoauth2 (default is Gmail scope)
+	From               string   `toml:"from"`              // sender address, optionally in "Name <address>" form
+	FromName           string   `toml:"from_name"`         // sender display name, overriding the one in from
+	Subject            string   `toml:"subject"`
+	To                 []string `toml:"to"`
+	AttachICS          bool     `toml:"attach_ics"`           // attach ICS event to exam messages
+	InsecureSkipVerify bool     `toml:"insecure_skip_verify"` // skip TLS certificate verification
+	rateLimit
+}
+
+// family struct holds family digest configuration (delivered through mail messenger SMTP settings).
+type family struct {
+	To      string `toml:"to"`
+	Subject string `toml:"subject"`
+}
+
+// digestMode struct holds digest mode configuration, where alerts are combined and sent at a time of day (optionally
+// only on a weekday) or every few polls.
+type digestMode struct {
+	Time    string `toml:"time"`    // time of day to send digest at (HH:MM)
+	Weekday string `toml:"weekday"` // optional weekday to send weekly digest on
+	Ticks   int    `toml:"ticks"`   // number of polls between digests
+}
+
+// due reports if the digest should be sent now, given the time of the last sent digest and the number of polls since.
+// Outside of daemon mode digest is sent at the end of every run.
+func (d digestMode) due(now, last time.Time, runs int) bool {
+	if !*daemon {
+		return true
+	}
+
+	if d.Ticks > 0 {
+		return runs >= d.Ticks
+	}
+
+	if d.Weekday != "" && !strings.EqualFold(now.Weekday().String(), d.Weekday) {
+		return false
+	}
+
+	t, err := time.Parse(digestTimeFormat, d.Time)
+	if err != nil {
+		return true
+	}
+
+	at := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+
+	return !now.Before(at) && last.Before(at)
+}
+
+// quietHours struct holds quiet hours configuration, when alerts are held back until the end of the window.
+type quietHours struct {
+	Start       string `toml:"start"`        // start of quiet hours (HH:MM)
+	End         string `toml:"end"`          // end of quiet hours (HH:MM)
+	Timezone    string `toml:"timezone"`     // IANA time zone of start and end (default is local time zone)
+	BypassExams bool   `toml:"bypass_exams"` // send exams immediately, even during quiet hours
+}
+
+// active reports if the time is within quiet hours, which can span midnight. Outside of daemon mode quiet hours are
+// never active, as held back alerts would be lost.
+func (q quietHours) active(now time.Time) bool {
+	if !*daemon {
+		return false
+	}
+
+	start, err := time.Parse(digestTimeFormat, q.Start)
+	if err != nil {
+		return false
+	}
+
+	end, err := time.Parse(digestTimeFormat, q.End)
+	if err != nil {
+		return false
+	}
+
+	if q.Timezone != "" {
+		if loc, err := time.LoadLocation(q.Timezone); err == nil {
+			now = now.In(loc)
+		}
+	}
+
+	minutes := func(t time.Time) int { return t.Hour()*60 + t.Minute() }
+	m, s, e := minutes(now), minutes(start), minutes(end)
+
+	if s <= e {
+		return m >= s && m < e
+	}
+
+	return m >= s || m < e
+}
+
+// holds reports if the alert should be held back at the given time.
+func (q quietHours) holds(g msgtypes.Message, now time.Time) bool {
+	return q.active(now) && (!q.BypassExams || g.Code != msgtypes.Exam)
 }
 
 // calendar struct hold Google Calendar configuration.
 type calendar struct {
-	Name string `toml:"name"`
+	Name           string        `toml:"name"`
+	Reminders      []int         `toml:"reminders"`       // reminder times in minutes before exam (default is calendar default)
+	ReminderMethod string        `toml:"reminder_method"` // reminder method (popup or email, default is popup)
+	Duration       time.Duration `toml:"duration"`        // timed exam event duration (default is all-day events only)
+	EventPrefix    string        `toml:"event_prefix"`    // prefix prepended to exam event summaries
+	ColorID        int           `toml:"color_id"`        // exam event color ID 1-11 (default is calendar color)
+	rateLimit
+}
+
+// httpClient struct holds optional e-dnevnik HTTP client transport tuning.
+type httpClient struct {
+	MaxIdleConns  int    `toml:"max_idle_conns"`  // maximum idle (keep-alive) connections (default is net/http default)
+	TLSMinVersion string `toml:"tls_min_version"` // minimum TLS version (1.2 or 1.3, default is 1.2)
+	UserAgent     string `toml:"user_agent"`      // fixed User-Agent (default is random per session)
+	CABundle      string `toml:"ca_bundle"`       // PEM CA bundle added to system root CAs (ie. for a corporate proxy)
+	ClientCert    string `toml:"client_cert"`     // PEM client certificate
+	ClientKey     string `toml:"client_key"`      // PEM client certificate key
 }
 
 // tomlConfig struct holds all other configuration structures.
 type tomlConfig struct {
-	Calendar        calendar `toml:"calendar"`
-	Mail            mail     `toml:"mail"`
-	Telegram        telegram `toml:"telegram"`
-	Discord         discord  `toml:"discord"`
-	Slack           slack    `toml:"slack"`
-	User            []user   `toml:"user"`
-	telegramEnabled bool     `toml:"telegram_enabled"`
-	discordEnabled  bool     `toml:"discord_enabled"`
-	slackEnabled    bool     `toml:"slack_enabled"`
-	mailEnabled     bool     `toml:"mail_enabled"`
-	calendarEnabled bool     `toml:"calendar_enabled"`
+	Proxy                string                   `toml:"proxy"`          // optional HTTP/SOCKS proxy URL for scraping
+	Language             string                   `toml:"language"`       // message language (hr or en)
+	Template             string                   `toml:"template"`       // custom message template file
+	Prefixes             map[string]string        `toml:"prefixes"`       // title prefix overrides per event type
+	Relevance            map[string]time.Duration `toml:"relevance"`      // relevance periods per event type
+	Apprise              []string                 `toml:"apprise"`        // Apprise-style notification URLs
+	FriendlyNames        bool                     `toml:"friendly_names"` // show scraped student names instead of usernames
+	TriggerSecret        string                   `toml:"trigger_secret"` // shared secret for on-demand scrape trigger
+	Aliases              map[string][]string      `toml:"aliases"`        // named recipient lists, referenced as @name
+	Coalesce             []string                 `toml:"coalesce"`       // messengers receiving one combined message per user and run
+	HTTP                 httpClient               `toml:"http"`
+	Family               family                   `toml:"family"`
+	Digest               digestMode               `toml:"digest"`
+	QuietHours           quietHours               `toml:"quiet_hours"`
+	Calendar             calendar                 `toml:"calendar"`
+	Mail                 mail                     `toml:"mail"`
+	Telegram             telegram                 `toml:"telegram"`
+	Discord              discord                  `toml:"discord"`
+	Slack                slack                    `toml:"slack"`
+	Teams                teams                    `toml:"teams"`
+	Pushover             pushover                 `toml:"pushover"`
+	Gotify               gotify                   `toml:"gotify"`
+	Mastodon             mastodon                 `toml:"mastodon"`
+	Twilio               twilio                   `toml:"twilio"`
+	RocketChat           rocketChat               `toml:"rocketchat"`
+	MQTT                 mqtt                     `toml:"mqtt"`
+	IRC                  irc                      `toml:"irc"`
+	Pushbullet           pushbullet               `toml:"pushbullet"`
+	HomeAssistant        homeAssistant            `toml:"homeassistant"`
+	JSONLines            jsonLines                `toml:"jsonlines"`
+	User                 []user                   `toml:"user"`
+	telegramEnabled      bool                     `toml:"telegram_enabled"`
+	discordEnabled       bool                     `toml:"discord_enabled"`
+	slackEnabled         bool                     `toml:"slack_enabled"`
+	teamsEnabled         bool                     `toml:"teams_enabled"`
+	pushoverEnabled      bool                     `toml:"pushover_enabled"`
+	gotifyEnabled        bool                     `toml:"gotify_enabled"`
+	mastodonEnabled      bool                     `toml:"mastodon_enabled"`
+	twilioEnabled        bool                     `toml:"twilio_enabled"`
+	rocketChatEnabled    bool                     `toml:"rocketchat_enabled"`
+	mqttEnabled          bool                     `toml:"mqtt_enabled"`
+	ircEnabled           bool                     `toml:"irc_enabled"`
+	pushbulletEnabled    bool                     `toml:"pushbullet_enabled"`
+	homeAssistantEnabled bool                     `toml:"homeassistant_enabled"`
+	appriseEnabled       bool                     `toml:"apprise_enabled"`
+	jsonLinesEnabled     bool                     `toml:"jsonlines_enabled"`
+	mailEnabled          bool                     `toml:"mail_enabled"`
+	calendarEnabled      bool                     `toml:"calendar_enabled"`
+	familyEnabled        bool                     `toml:"family_enabled"`
+	digestEnabled        bool                     `toml:"digest_enabled"`
+	quietEnabled         bool                     `toml:"quiet_enabled"`
+	mailTokens           oauth2.TokenSource       `toml:"-"` // OAuth2 token source for XOAUTH2 mail authentication
+	httpRootCAs          *x509.CertPool           `toml:"-"` // root CAs for scraping, including the CA bundle
+	httpCerts            []tls.Certificate        `toml:"-"` // client certificates for scraping
+	lang                 format.Strings           `toml:"-"` // localized strings with title prefix overrides
+	template             *format.Template         `toml:"-"` // parsed custom message template
+}
+
+// stdinConfig caches configuration read from standard input, as it can be read only once but is reloaded on SIGHUP.
+var (
+	stdinConfig     []byte
+	stdinConfigErr  error
+	stdinConfigOnce sync.Once
+)
+
+// readConfig reads raw configuration from the file, from standard input or from the environment.
+func readConfig(confFile string) ([]byte, error) {
+	switch confFile {
+	case ConfigStdin:
+		stdinConfigOnce.Do(func() {
+			stdinConfig, stdinConfigErr = io.ReadAll(os.Stdin)
+		})
+
+		return stdinConfig, stdinConfigErr
+	case configEnvFile:
+		return []byte(os.Getenv(ConfigEnv)), nil
+	default:
+		return os.ReadFile(confFile)
+	}
 }
 
 // loadConfig attempts to load and decode configuration file in TOML format, doing a minimal sanity checking and
 // optionally returning an error.
-func loadConfig() (tomlConfig, error) {
+func loadConfig(confFile string) (tomlConfig, error) {
+	data, err := readConfig(confFile)
+	if err != nil {
+		return tomlConfig{}, err
+	}
+
+	return decodeConfig(data)
+}
+
+// decodeConfig decodes raw configuration in TOML format, doing a minimal sanity checking and optionally returning an
+// error.
+func decodeConfig(data []byte) (tomlConfig, error) {
 	var config tomlConfig
-	if _, err := toml.DecodeFile(*confFile, &config); err != nil {
+	if _, err := toml.Decode(string(data), &config); err != nil {
+		return config, err
+	}
+
+	// decrypt encrypted secrets
+	key, err := loadKey()
+	if err != nil {
+		return config, err
+	}
+
+	if err := decryptSecrets(reflect.ValueOf(&config).Elem(), key); err != nil {
+		return config, err
+	}
+
+	// expand recipient aliases and comma-separated recipients before messenger validation
+	if err := config.expandRecipients(); err != nil {
 		return config, err
 	}
 
-	if config.Discord.Token != "" && len(config.Discord.UserIDs) > 0 {
+	if (config.Discord.Token != "" && len(config.Discord.UserIDs) > 0) || len(config.Discord.Webhooks) > 0 {
+		if err := checkDiscordConf(config.Discord); err != nil {
+			return config, err
+		}
+
 		logger.Info().Msg("Configuration: Discord messenger enabled")
 
 		config.discordEnabled = true
 	}
 
 	if config.Telegram.Token != "" && len(config.Telegram.ChatIDs) > 0 {
+		if err := checkTelegramConf(config.Telegram); err != nil {
+			return config, err
+		}
+
 		logger.Info().Msg("Configuration: Telegram messenger enabled")
 
 		config.telegramEnabled = true
@@ -107,15 +625,922 @@ func loadConfig() (tomlConfig, error) {
 		config.slackEnabled = true
 	}
 
-	if config.Mail.Server != "" && config.Mail.From != "" && len(config.Mail.To) > 0 {
-		logger.Info().Msg("Configuration: e-mail messenger enabled")
+	if len(config.Teams.Webhooks) > 0 {
+		if err := checkTeamsConf(config.Teams); err != nil {
+			return config, err
+		}
+
+		logger.Info().Msg("Configuration: Microsoft Teams messenger enabled")
+
+		config.teamsEnabled = true
+	}
+
+	if config.Pushover.Token != "" && len(config.Pushover.UserKeys) > 0 {
+		if err := checkPushoverConf(config.Pushover); err != nil {
+			return config, err
+		}
+
+		logger.Info().Msg("Configuration: Pushover messenger enabled")
+
+		config.pushoverEnabled = true
+	}
+
+	if config.Gotify.Server != "" || config.Gotify.Token != "" {
+		if err := checkGotifyConf(config.Gotify); err != nil {
+			return config, err
+		}
+
+		logger.Info().Msg("Configuration: Gotify messenger enabled")
+
+		config.gotifyEnabled = true
+	}
+
+	if config.Mastodon.Instance != "" || config.Mastodon.Token != "" {
+		if err := checkMastodonConf(config.Mastodon); err != nil {
+			return config, err
+		}
+
+		logger.Info().Msg("Configuration: Mastodon messenger enabled")
+
+		config.mastodonEnabled = true
+	}
+
+	if config.Twilio.AccountSID != "" || config.Twilio.Token != "" {
+		if err := checkTwilioConf(config.Twilio); err != nil {
+			return config, err
+		}
+
+		logger.Info().Msg("Configuration: Twilio messenger enabled")
+
+		config.twilioEnabled = true
+	}
+
+	if config.RocketChat.Server != "" || config.RocketChat.Token != "" {
+		if err := checkRocketChatConf(config.RocketChat); err != nil {
+			return config, err
+		}
+
+		logger.Info().Msg("Configuration: Rocket.Chat messenger enabled")
+
+		config.rocketChatEnabled = true
+	}
+
+	if config.MQTT.Broker != "" {
+		if err := checkMQTTConf(config.MQTT); err != nil {
+			return config, err
+		}
+
+		logger.Info().Msg("Configuration: MQTT messenger enabled")
+
+		config.mqttEnabled = true
+	}
+
+	if config.IRC.Server != "" {
+		if err := checkIRCConf(config.IRC); err != nil {
+			return config, err
+		}
+
+		logger.Info().Msg("Configuration: IRC messenger enabled")
+
+		config.ircEnabled = true
+	}
+
+	if config.Pushbullet.Token != "" || len(config.Pushbullet.DeviceIDs) > 0 {
+		if err := checkPushbulletConf(config.Pushbullet); err != nil {
+			return config, err
+		}
+
+		logger.Info().Msg("Configuration: Pushbullet messenger enabled")
+
+		config.pushbulletEnabled = true
+	}
+
+	if config.HomeAssistant.Server != "" || config.HomeAssistant.Token != "" || len(config.HomeAssistant.Services) > 0 {
+		if err := checkHomeAssistantConf(config.HomeAssistant); err != nil {
+			return config, err
+		}
+
+		logger.Info().Msg("Configuration: Home Assistant messenger enabled")
+
+		config.homeAssistantEnabled = true
+	}
+
+	if len(config.Apprise) > 0 {
+		for _, u := range config.Apprise {
+			if _, err := messenger.ParseAppriseURL(u); err != nil {
+				return config, err
+			}
+		}
+
+		logger.Info().Msg("Configuration: Apprise messenger enabled")
+
+		config.appriseEnabled = true
+	}
+
+	if config.JSONLines.Path != "" {
+		logger.Info().Msg("Configuration: JSON Lines messenger enabled")
+
+		config.jsonLinesEnabled = true
+	}
+
+	if config.Mail.Server != "" && len(config.Mail.To) > 0 {
+		if err := checkMailConf(&config.Mail); err != nil {
+			return config, err
+		}
+
+		logger.Info().Msgf("Configuration: e-mail messenger enabled (TLS mode %v)", config.Mail.TLS)
 
 		config.mailEnabled = true
 	}
 
 	if config.Calendar.Name != "" {
+		if err := checkCalendarConf(config.Calendar); err != nil {
+			return config, err
+		}
+
 		config.calendarEnabled = true
 	}
 
+	if config.Mail.Server != "" && config.Family.To != "" {
+		if err := checkMailConf(&config.Mail); err != nil {
+			return config, err
+		}
+
+		logger.Info().Msgf("Configuration: family digest enabled (TLS mode %v)", config.Mail.TLS)
+
+		config.familyEnabled = true
+	}
+
+	if config.Digest.Time != "" || config.Digest.Ticks != 0 {
+		if err := checkDigestConf(config.Digest); err != nil {
+			return config, err
+		}
+
+		logger.Info().Msg("Configuration: digest mode enabled")
+
+		config.digestEnabled = true
+	}
+
+	if config.QuietHours.Start != "" || config.QuietHours.End != "" {
+		if err := checkQuietHoursConf(config.QuietHours); err != nil {
+			return config, err
+		}
+
+		logger.Info().Msgf("Configuration: quiet hours from %v to %v enabled", config.QuietHours.Start,
+			config.QuietHours.End)
+
+		config.quietEnabled = true
+	}
+
+	// validate messenger rate limit overrides
+	for name, rl := range map[string]rateLimit{
+		discordName:       config.Discord.rateLimit,
+		telegramName:      config.Telegram.rateLimit,
+		slackName:         config.Slack.rateLimit,
+		mailName:          config.Mail.rateLimit,
+		calendarName:      config.Calendar.rateLimit,
+		teamsName:         config.Teams.rateLimit,
+		pushoverName:      config.Pushover.rateLimit,
+		gotifyName:        config.Gotify.rateLimit,
+		mastodonName:      config.Mastodon.rateLimit,
+		twilioName:        config.Twilio.rateLimit,
+		rocketChatName:    config.RocketChat.rateLimit,
+		mqttName:          config.MQTT.rateLimit,
+		ircName:           config.IRC.rateLimit,
+		pushbulletName:    config.Pushbullet.rateLimit,
+		homeAssistantName: config.HomeAssistant.rateLimit,
+	} {
+		if rl.RateLimit < 0 || rl.Window < 0 {
+			return config, fmt.Errorf("%w: %v", ErrInvalidRateLimit, name)
+		}
+	}
+
+	// validate HTTP client tuning
+	if config.HTTP.MaxIdleConns < 0 {
+		return config, fmt.Errorf("%w: max_idle_conns cannot be negative", ErrInvalidHTTP)
+	}
+
+	if _, ok := tlsVersions[config.HTTP.TLSMinVersion]; config.HTTP.TLSMinVersion != "" && !ok {
+		return config, fmt.Errorf("%w: unsupported TLS version %v (1.2 or 1.3)", ErrInvalidHTTP, config.HTTP.TLSMinVersion)
+	}
+
+	config.HTTP.UserAgent = strings.TrimSpace(config.HTTP.UserAgent)
+	if strings.ContainsAny(config.HTTP.UserAgent, "\r\n") {
+		return config, fmt.Errorf("%w: user_agent cannot contain line breaks", ErrInvalidHTTP)
+	}
+
+	if (config.HTTP.ClientCert == "") != (config.HTTP.ClientKey == "") {
+		return config, fmt.Errorf("%w: client_cert and client_key have to be set together", ErrInvalidHTTP)
+	}
+
+	if config.httpRootCAs, config.httpCerts, err = fetch.LoadTLS(config.HTTP.CABundle, config.HTTP.ClientCert,
+		config.HTTP.ClientKey); err != nil {
+		return config, fmt.Errorf("%w: %w", ErrInvalidHTTP, err)
+	}
+
+	// normalize and validate relevance periods per event type
+	relevance := make(map[string]time.Duration, len(config.Relevance))
+
+	for k, v := range config.Relevance {
+		k = strings.ToLower(strings.TrimSpace(k))
+		if v < 0 || !slices.ContainsFunc(relevanceCodes, func(c msgtypes.EventCode) bool { return c.String() == k }) {
+			return config, fmt.Errorf("%w: %v", ErrInvalidRelevance, k)
+		}
+
+		relevance[k] = v
+	}
+
+	config.Relevance = relevance
+
+	// normalize and validate per-user messenger targets
+	for i := range config.User {
+		for j, t := range config.User[i].Targets {
+			t = strings.ToLower(strings.TrimSpace(t))
+			if !slices.Contains(messengerNames, t) {
+				return config, fmt.Errorf("%w: %v (user %v)", ErrInvalidTarget, t, config.User[i].Username)
+			}
+
+			config.User[i].Targets[j] = t
+		}
+	}
+
+	// normalize and validate coalescing messengers, Google Calendar handles only individual exams
+	for i, c := range config.Coalesce {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if !slices.Contains(messengerNames, c) || c == calendarName {
+			return config, fmt.Errorf("%w: %v", ErrInvalidCoalesce, c)
+		}
+
+		config.Coalesce[i] = c
+	}
+
+	// validate per-user poll intervals, raising too short ones to the minimal permitted poll interval
+	for i, u := range config.User {
+		if u.Interval < 0 {
+			return config, fmt.Errorf("%w: %v (user %v)", ErrInvalidInterval, u.Interval, u.Username)
+		}
+
+		if u.Interval == 0 {
+			continue
+		}
+
+		if minimal := minTickInterval(); u.Interval < minimal {
+			logger.Info().Msgf("Poll interval of user %v is below %v, so I will default to %v", u.Username, minimal,
+				minimal)
+
+			config.User[i].Interval = minimal
+		}
+
+		logger.Info().Msgf("Configuration: user %v polled every %v", u.Username, config.User[i].interval())
+	}
+
+	// validate message language and title prefixes, which are set only once the configuration is accepted
+	config.lang, err = format.Localize(config.Language, config.Prefixes)
+	if err != nil {
+		return config, err
+	}
+
+	if len(config.Prefixes) > 0 {
+		logger.Info().Msgf("Configuration: %v message title prefixes overridden", len(config.Prefixes))
+	}
+
+	// parse custom message template, which is set only once the configuration is accepted
+	config.template, err = loadTemplate(config.Template)
+	if err != nil {
+		return config, err
+	}
+
 	return config, nil
 }
+
+// loadTemplate reads and parses custom message template from the file, returning nil for built-in formatting if
+// unset.
+func loadTemplate(file string) (*format.Template, error) {
+	if file == "" {
+		return nil, nil
+	}
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", format.ErrTemplate, err)
+	}
+
+	t, err := format.ParseTemplate(string(b))
+	if err != nil {
+		return nil, fmt.Errorf("%w (%v)", err, file)
+	}
+
+	logger.Info().Msgf("Configuration: custom message template %v enabled", file)
+
+	return t, nil
+}
+
+// applyFormat sets message language, title prefixes and custom message template of the accepted configuration for
+// all formatted messages.
+func (c *tomlConfig) applyFormat() {
+	format.SetStrings(c.lang)
+	format.UseTemplate(c.template)
+}
+
+// messengerSwitches returns pointers to enabled flags of all messengers, keyed by messenger name.
+func (c *tomlConfig) messengerSwitches() map[string]*bool {
+	return map[string]*bool{
+		discordName:       &c.discordEnabled,
+		telegramName:      &c.telegramEnabled,
+		slackName:         &c.slackEnabled,
+		mailName:          &c.mailEnabled,
+		calendarName:      &c.calendarEnabled,
+		teamsName:         &c.teamsEnabled,
+		pushoverName:      &c.pushoverEnabled,
+		jsonLinesName:     &c.jsonLinesEnabled,
+		gotifyName:        &c.gotifyEnabled,
+		mastodonName:      &c.mastodonEnabled,
+		appriseName:       &c.appriseEnabled,
+		twilioName:        &c.twilioEnabled,
+		rocketChatName:    &c.rocketChatEnabled,
+		mqttName:          &c.mqttEnabled,
+		ircName:           &c.ircEnabled,
+		pushbulletName:    &c.pushbulletEnabled,
+		homeAssistantName: &c.homeAssistantEnabled,
+	}
+}
+
+// fetchOptions returns e-dnevnik HTTP client options from configuration, request timeout and snapshot flags.
+func (c tomlConfig) fetchOptions() fetch.Options {
+	return fetch.Options{
+		Proxy:         c.Proxy,
+		Timeout:       *fetchTimeout,
+		MaxIdleConns:  c.HTTP.MaxIdleConns,
+		TLSMinVersion: tlsVersions[c.HTTP.TLSMinVersion],
+		SaveDir:       *saveHTML,
+		UserAgent:     c.HTTP.UserAgent,
+		RootCAs:       c.httpRootCAs,
+		Certificates:  c.httpCerts,
+	}
+}
+
+// checkGotifyConf validates that Gotify server is an absolute HTTP(S) URL and that application token is set.
+func checkGotifyConf(conf gotify) error {
+	u, err := url.Parse(conf.Server)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: invalid server URL %v", ErrInvalidGotify, conf.Server)
+	}
+
+	if conf.Token == "" {
+		return fmt.Errorf("%w: empty application token", ErrInvalidGotify)
+	}
+
+	return nil
+}
+
+// checkMastodonConf validates that Mastodon instance is an absolute HTTPS URL, that access token is set and that status
+// visibility is known.
+func checkMastodonConf(conf mastodon) error {
+	u, err := url.Parse(conf.Instance)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("%w: invalid instance URL %v", ErrInvalidMastodon, conf.Instance)
+	}
+
+	if conf.Token == "" {
+		return fmt.Errorf("%w: empty access token", ErrInvalidMastodon)
+	}
+
+	if conf.Visibility != "" && !slices.Contains(mastodonVisibilities, conf.Visibility) {
+		return fmt.Errorf("%w: unknown visibility %v", ErrInvalidMastodon, conf.Visibility)
+	}
+
+	return nil
+}
+
+// checkTwilioConf validates that Twilio account SID and auth token are set and that sender and all recipient phone
+// numbers are in E.164 format.
+func checkTwilioConf(conf twilio) error {
+	if !strings.HasPrefix(conf.AccountSID, "AC") || conf.Token == "" {
+		return fmt.Errorf("%w: account SID (AC...) and auth token have to be set", ErrInvalidTwilio)
+	}
+
+	if len(conf.To) == 0 {
+		return fmt.Errorf("%w: empty list of recipient phone numbers", ErrInvalidTwilio)
+	}
+
+	for _, n := range append([]string{conf.From}, conf.To...) {
+		if !isValidPhone(n) {
+			return fmt.Errorf("%w: invalid phone number %q, expected E.164 format (ie. +385911234567)",
+				ErrInvalidTwilio, n)
+		}
+	}
+
+	return nil
+}
+
+// checkRocketChatConf validates that Rocket.Chat server is an absolute HTTP(S) URL and that user ID, auth token and
+// channels are set.
+func checkRocketChatConf(conf rocketChat) error {
+	u, err := url.Parse(conf.Server)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("%w: invalid server URL %v", ErrInvalidRocketChat, conf.Server)
+	}
+
+	if conf.UserID == "" || conf.Token == "" {
+		return fmt.Errorf("%w: user ID and auth token have to be set", ErrInvalidRocketChat)
+	}
+
+	if len(conf.Channels) == 0 {
+		return fmt.Errorf("%w: empty list of channels", ErrInvalidRocketChat)
+	}
+
+	return nil
+}
+
+// checkMQTTConf validates that MQTT broker is an URL with a supported scheme and that QoS level is in range.
+func checkMQTTConf(conf mqtt) error {
+	u, err := url.Parse(conf.Broker)
+	if err != nil || !slices.Contains(mqttSchemes, u.Scheme) || (u.Host == "" && u.Scheme != "unix") {
+		return fmt.Errorf("%w: invalid broker URL %v", ErrInvalidMQTT, conf.Broker)
+	}
+
+	if conf.QoS != nil && (*conf.QoS < 0 || *conf.QoS > MQTTMaxQoS) {
+		return fmt.Errorf("%w: QoS has to be between 0 and %v", ErrInvalidMQTT, MQTTMaxQoS)
+	}
+
+	return nil
+}
+
+// checkIRCConf validates IRC server port, nick, channels and that SASL username and password are set together.
+func checkIRCConf(conf irc) error {
+	if conf.Port < 0 || conf.Port > 65535 {
+		return fmt.Errorf("%w: invalid port %v", ErrInvalidIRC, conf.Port)
+	}
+
+	if conf.Nick == "" || strings.ContainsAny(conf.Nick, " ,*?!@#:") {
+		return fmt.Errorf("%w: invalid nick %q", ErrInvalidIRC, conf.Nick)
+	}
+
+	if len(conf.Channels) == 0 {
+		return fmt.Errorf("%w: empty list of channels", ErrInvalidIRC)
+	}
+
+	for _, c := range conf.Channels {
+		if c == "" || strings.ContainsAny(c, " ,\x07") {
+			return fmt.Errorf("%w: invalid channel %q", ErrInvalidIRC, c)
+		}
+	}
+
+	if (conf.SASLUser == "") != (conf.SASLPassword == "") {
+		return fmt.Errorf("%w: SASL username and password have to be set together", ErrInvalidIRC)
+	}
+
+	return nil
+}
+
+// checkPushbulletConf validates that Pushbullet access token is set and that device idens are not empty.
+func checkPushbulletConf(conf pushbullet) error {
+	if conf.Token == "" {
+		return fmt.Errorf("%w: empty access token", ErrInvalidPushbullet)
+	}
+
+	if slices.Contains(conf.DeviceIDs, "") {
+		return fmt.Errorf("%w: empty device iden", ErrInvalidPushbullet)
+	}
+
+	return nil
+}
+
+// checkHomeAssistantConf validates that Home Assistant server is an absolute HTTP(S) URL, that access token is set and
+// that notify services are valid service names.
+func checkHomeAssistantConf(conf homeAssistant) error {
+	u, err := url.Parse(conf.Server)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: invalid server URL %v", ErrInvalidHomeAssistant, conf.Server)
+	}
+
+	if conf.Token == "" {
+		return fmt.Errorf("%w: empty access token", ErrInvalidHomeAssistant)
+	}
+
+	if len(conf.Services) == 0 {
+		return fmt.Errorf("%w: empty list of notify services", ErrInvalidHomeAssistant)
+	}
+
+	for _, s := range conf.Services {
+		if !homeAssistantServiceRegexp.MatchString(s) {
+			return fmt.Errorf("%w: invalid notify service %q", ErrInvalidHomeAssistant, s)
+		}
+	}
+
+	return nil
+}
+
+// isValidPhone reports if the phone number is in E.164 format.
+func isValidPhone(n string) bool {
+	return phoneRegexp.MatchString(n)
+}
+
+// checkMailConf validates sender address, SMTP authentication mechanism and TLS mode, sets the sender display name and
+// the effective TLS mode based on the port if unset and, for XOAUTH2, sets the default OAuth2 token file and checks that
+// OAuth2 client credentials file is set.
+func checkMailConf(conf *mail) error {
+	from, err := messenger.MailFrom(conf.From, conf.FromName)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidMail, err)
+	}
+
+	conf.From = from
+	conf.TLS = messenger.MailTLSMode(strings.TrimSpace(conf.TLS), conf.Port)
+
+	switch conf.TLS {
+	case messenger.MailTLSNone, messenger.MailTLSOpportunistic, messenger.MailTLSMandatory, messenger.MailTLSImplicit:
+	default:
+		return fmt.Errorf("%w: unknown TLS mode %v", ErrInvalidMail, conf.TLS)
+	}
+
+	conf.Auth = strings.ToLower(strings.TrimSpace(conf.Auth))
+
+	switch conf.Auth {
+	case "", messenger.MailAuthPlain, messenger.MailAuthLogin:
+	case messenger.MailAuthXOAUTH2:
+		if conf.OAuthCredentials == "" {
+			return fmt.Errorf("%w: xoauth2 requires oauth_credentials", ErrInvalidMail)
+		}
+
+		if conf.OAuthToken == "" {
+			conf.OAuthToken = DefaultMailToken
+		}
+	default:
+		return fmt.Errorf("%w: unknown auth %v", ErrInvalidMail, conf.Auth)
+	}
+
+	return nil
+}
+
+// checkCalendarConf validates Google Calendar reminder times and method, timed event duration and event color ID.
+func checkCalendarConf(conf calendar) error {
+	if len(conf.Reminders) > messenger.CalendarMaxReminders {
+		return fmt.Errorf("%w: at most %v reminders permitted", ErrInvalidCalendar, messenger.CalendarMaxReminders)
+	}
+
+	for _, r := range conf.Reminders {
+		if r < 0 || r > messenger.CalendarMaxReminder {
+			return fmt.Errorf("%w: reminder %v not in range 0 to %v minutes", ErrInvalidCalendar, r,
+				messenger.CalendarMaxReminder)
+		}
+	}
+
+	if conf.ReminderMethod != "" && conf.ReminderMethod != messenger.CalendarReminderPopup &&
+		conf.ReminderMethod != messenger.CalendarReminderEmail {
+		return fmt.Errorf("%w: unknown reminder method %v", ErrInvalidCalendar, conf.ReminderMethod)
+	}
+
+	if conf.Duration < 0 {
+		return fmt.Errorf("%w: negative event duration", ErrInvalidCalendar)
+	}
+
+	if conf.ColorID < 0 || conf.ColorID > messenger.CalendarMaxColorID {
+		return fmt.Errorf("%w: color ID %v not in range 1 to %v", ErrInvalidCalendar, conf.ColorID,
+			messenger.CalendarMaxColorID)
+	}
+
+	return nil
+}
+
+// checkDigestConf validates that exactly one of digest time of day and number of polls is set, and that the weekday
+// is only used together with the time of day.
+func checkDigestConf(conf digestMode) error {
+	if conf.Ticks < 0 || (conf.Ticks > 0) == (conf.Time != "") {
+		return fmt.Errorf("%w: either time or a positive number of ticks has to be set", ErrInvalidDigest)
+	}
+
+	if conf.Time != "" {
+		if _, err := time.Parse(digestTimeFormat, conf.Time); err != nil {
+			return fmt.Errorf("%w: invalid time %v, expected HH:MM", ErrInvalidDigest, conf.Time)
+		}
+	}
+
+	if conf.Weekday != "" {
+		if conf.Time == "" || !slices.ContainsFunc(weekdays, func(w time.Weekday) bool {
+			return strings.EqualFold(w.String(), conf.Weekday)
+		}) {
+			return fmt.Errorf("%w: invalid weekday %v", ErrInvalidDigest, conf.Weekday)
+		}
+	}
+
+	return nil
+}
+
+// checkQuietHoursConf validates quiet hours start and end times and time zone.
+func checkQuietHoursConf(conf quietHours) error {
+	for _, t := range []string{conf.Start, conf.End} {
+		if _, err := time.Parse(digestTimeFormat, t); err != nil {
+			return fmt.Errorf("%w: invalid time %q, expected HH:MM", ErrInvalidQuietHours, t)
+		}
+	}
+
+	if conf.Start == conf.End {
+		return fmt.Errorf("%w: start and end have to differ", ErrInvalidQuietHours)
+	}
+
+	if _, err := time.LoadLocation(conf.Timezone); err != nil {
+		return fmt.Errorf("%w: invalid time zone %v", ErrInvalidQuietHours, conf.Timezone)
+	}
+
+	return nil
+}
+
+// relevance returns maximum relevance period for the event type (0 = unlimited), defaulting to the global relevance
+// period.
+func (c tomlConfig) relevance(code msgtypes.EventCode) time.Duration {
+	if p, ok := c.Relevance[code.String()]; ok {
+		return p
+	}
+
+	return *relevancePeriod
+}
+
+// checkDiscordConf validates that bot token and webhooks are not used together and that all webhooks are absolute
+// HTTPS Discord webhook URLs.
+func checkDiscordConf(conf discord) error {
+	if len(conf.Webhooks) == 0 {
+		return nil
+	}
+
+	if conf.Token != "" {
+		return fmt.Errorf("%w: bot token and webhooks cannot be used together", ErrInvalidDiscord)
+	}
+
+	for _, w := range conf.Webhooks {
+		u, err := url.Parse(w)
+		if err != nil || u.Scheme != "https" || !isDiscordHost(u.Hostname()) ||
+			!strings.HasPrefix(u.Path, "/api/webhooks/") {
+			return fmt.Errorf("%w: invalid webhook URL %v", ErrInvalidDiscord, w)
+		}
+	}
+
+	return nil
+}
+
+// isDiscordHost reports if the host is Discord API host (including PTB and Canary clients).
+func isDiscordHost(host string) bool {
+	for _, d := range []string{"discord.com", "discordapp.com"} {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// expandRecipients splits comma-separated recipients and replaces named recipient aliases (@name) with alias
+// recipients in all messenger recipient lists, dropping duplicates. Recipients starting with @ which are not aliases
+// (ie. Rocket.Chat users or Mastodon accounts) are kept as they are.
+func (c *tomlConfig) expandRecipients() error {
+	for name, recipients := range c.Aliases {
+		if name == "" || strings.ContainsAny(name, AliasPrefix+", ") {
+			return fmt.Errorf("%w: invalid alias name %q", ErrInvalidAlias, name)
+		}
+
+		if len(splitRecipients(recipients)) == 0 {
+			return fmt.Errorf("%w: alias %v has no recipients", ErrInvalidAlias, name)
+		}
+	}
+
+	for _, l := range []*[]string{
+		&c.Telegram.ChatIDs, &c.Discord.UserIDs, &c.Slack.ChatIDs, &c.Pushover.UserKeys, &c.Mastodon.Accounts,
+		&c.Twilio.To, &c.RocketChat.Channels, &c.IRC.Channels, &c.Pushbullet.DeviceIDs, &c.HomeAssistant.Services,
+		&c.Mail.To,
+	} {
+		if len(*l) == 0 {
+			continue
+		}
+
+		expanded := make([]string, 0, len(*l))
+
+		for _, r := range splitRecipients(*l) {
+			recipients := []string{r}
+			if alias, ok := c.Aliases[strings.TrimPrefix(r, AliasPrefix)]; ok && strings.HasPrefix(r, AliasPrefix) {
+				recipients = splitRecipients(alias)
+			}
+
+			for _, r := range recipients {
+				if !slices.Contains(expanded, r) {
+					expanded = append(expanded, r)
+				}
+			}
+		}
+
+		*l = expanded
+	}
+
+	return nil
+}
+
+// splitRecipients splits comma-separated recipients, trimming whitespace and dropping empty ones. Commas within
+// double-quoted strings, ie. e-mail display names like "Korunic, Dinko" <d@skole.hr>, do not split recipients.
+func splitRecipients(recipients []string) []string {
+	var split []string
+
+	add := func(s string) {
+		if s = strings.TrimSpace(s); s != "" {
+			split = append(split, s)
+		}
+	}
+
+	for _, r := range recipients {
+		var quoted, escaped bool
+
+		start := 0
+
+		for i, c := range r {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\' && quoted:
+				escaped = true
+			case c == '"':
+				quoted = !quoted
+			case c == ',' && !quoted:
+				add(r[start:i])
+				start = i + 1
+			}
+		}
+
+		add(r[start:])
+	}
+
+	return split
+}
+
+// codeNames lists event type names as an enumeration for error messages, e.g. "grade, absence or note".
+func codeNames(codes []msgtypes.EventCode) string {
+	names := make([]string, 0, len(codes))
+	for _, c := range codes {
+		names = append(names, c.String())
+	}
+
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+// checkTelegramConf validates Telegram urgent exam period and silent event types.
+func checkTelegramConf(conf telegram) error {
+	if conf.UrgentDays < 0 {
+		return fmt.Errorf("%w: urgent_days cannot be negative", ErrInvalidTelegram)
+	}
+
+	for _, k := range conf.Silent {
+		if !slices.ContainsFunc(eventCodes, func(c msgtypes.EventCode) bool { return c.String() == k }) {
+			return fmt.Errorf("%w: unknown silent event type %v", ErrInvalidTelegram, k)
+		}
+	}
+
+	return nil
+}
+
+// checkTeamsConf validates that all Microsoft Teams webhooks are absolute HTTPS URLs.
+func checkTeamsConf(conf teams) error {
+	for _, w := range conf.Webhooks {
+		u, err := url.Parse(w)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("%w: %v", ErrInvalidWebhook, w)
+		}
+	}
+
+	return nil
+}
+
+// checkPushoverConf validates Pushover user keys and message priorities.
+func checkPushoverConf(conf pushover) error {
+	for _, k := range conf.UserKeys {
+		if strings.TrimSpace(k) == "" {
+			return fmt.Errorf("%w: empty user key", ErrInvalidPushover)
+		}
+	}
+
+	for _, p := range []int{conf.Priority, conf.examPriority()} {
+		if p < PushoverMinPriority || p > PushoverMaxPriority {
+			return fmt.Errorf("%w: priority %v not in range %v to %v", ErrInvalidPushover, p, PushoverMinPriority,
+				PushoverMaxPriority)
+		}
+	}
+
+	return nil
+}
+
+// reloadConfig replaces profile configuration with the newly loaded one, logging all changes between the current and
+// the new configuration.
+func reloadConfig(ctx context.Context, p *profile, config tomlConfig) {
+	// Google Calendar API setup
+	if config.calendarEnabled {
+		checkCalendar(ctx, &config, p.calTokFile)
+	}
+
+	// mail OAuth2 setup
+	checkMailOAuth(ctx, &config)
+
+	logConfigChanges(p.config, config)
+
+	p.config = config
+}
+
+// logConfigChanges logs enabled, disabled and changed messengers and added and removed users.
+func logConfigChanges(current, config tomlConfig) {
+	type section struct {
+		enabled bool
+		conf    any
+	}
+
+	for _, name := range messengerNames {
+		var old, cur section
+
+		switch name {
+		case discordName:
+			old, cur = section{current.discordEnabled, current.Discord}, section{config.discordEnabled, config.Discord}
+		case telegramName:
+			old, cur = section{current.telegramEnabled, current.Telegram}, section{config.telegramEnabled, config.Telegram}
+		case slackName:
+			old, cur = section{current.slackEnabled, current.Slack}, section{config.slackEnabled, config.Slack}
+		case mailName:
+			old, cur = section{current.mailEnabled, current.Mail}, section{config.mailEnabled, config.Mail}
+		case calendarName:
+			old, cur = section{current.calendarEnabled, current.Calendar}, section{config.calendarEnabled, config.Calendar}
+		case teamsName:
+			old, cur = section{current.teamsEnabled, current.Teams}, section{config.teamsEnabled, config.Teams}
+		case pushoverName:
+			old, cur = section{current.pushoverEnabled, current.Pushover}, section{config.pushoverEnabled, config.Pushover}
+		case gotifyName:
+			old, cur = section{current.gotifyEnabled, current.Gotify}, section{config.gotifyEnabled, config.Gotify}
+		case mastodonName:
+			old, cur = section{current.mastodonEnabled, current.Mastodon}, section{config.mastodonEnabled, config.Mastodon}
+		case jsonLinesName:
+			old, cur = section{current.jsonLinesEnabled, current.JSONLines}, section{config.jsonLinesEnabled, config.JSONLines}
+		case twilioName:
+			old, cur = section{current.twilioEnabled, current.Twilio}, section{config.twilioEnabled, config.Twilio}
+		case rocketChatName:
+			old, cur = section{current.rocketChatEnabled, current.RocketChat}, section{config.rocketChatEnabled, config.RocketChat}
+		case mqttName:
+			old, cur = section{current.mqttEnabled, current.MQTT}, section{config.mqttEnabled, config.MQTT}
+		case ircName:
+			old, cur = section{current.ircEnabled, current.IRC}, section{config.ircEnabled, config.IRC}
+		case pushbulletName:
+			old, cur = section{current.pushbulletEnabled, current.Pushbullet}, section{config.pushbulletEnabled, config.Pushbullet}
+		case homeAssistantName:
+			old, cur = section{current.homeAssistantEnabled, current.HomeAssistant}, section{config.homeAssistantEnabled, config.HomeAssistant}
+		case appriseName:
+			old, cur = section{current.appriseEnabled, current.Apprise}, section{config.appriseEnabled, config.Apprise}
+		}
+
+		switch {
+		case !old.enabled && cur.enabled:
+			logger.Info().Msgf("Configuration reload: %v messenger enabled", name)
+		case old.enabled && !cur.enabled:
+			logger.Info().Msgf("Configuration reload: %v messenger disabled", name)
+		case cur.enabled && !reflect.DeepEqual(old.conf, cur.conf):
+			logger.Info().Msgf("Configuration reload: %v messenger configuration changed", name)
+		}
+	}
+
+	if current.digestEnabled != config.digestEnabled || !reflect.DeepEqual(current.Digest, config.Digest) {
+		logger.Info().Msg("Configuration reload: digest mode configuration changed")
+	}
+
+	if current.quietEnabled != config.quietEnabled || current.QuietHours != config.QuietHours {
+		logger.Info().Msg("Configuration reload: quiet hours configuration changed")
+	}
+
+	if current.familyEnabled != config.familyEnabled || !reflect.DeepEqual(current.Family, config.Family) {
+		logger.Info().Msg("Configuration reload: family digest configuration changed")
+	}
+
+	if current.Proxy != config.Proxy || current.HTTP != config.HTTP || current.Language != config.Language ||
+		current.Template != config.Template || !maps.Equal(current.Prefixes, config.Prefixes) {
+		logger.Info().Msg("Configuration reload: proxy, HTTP client, language, message template or prefixes changed")
+	}
+
+	if !reflect.DeepEqual(current.Relevance, config.Relevance) {
+		logger.Info().Msg("Configuration reload: relevance periods changed")
+	}
+
+	oldUsers := make(map[string]user, len(current.User))
+	for _, u := range current.User {
+		oldUsers[u.Username] = u
+	}
+
+	for _, u := range config.User {
+		o, ok := oldUsers[u.Username]
+
+		switch {
+		case !ok:
+			logger.Info().Msgf("Configuration reload: user %v added", u.Username)
+		case !reflect.DeepEqual(o, u):
+			logger.Info().Msgf("Configuration reload: user %v changed", u.Username)
+		}
+
+		delete(oldUsers, u.Username)
+	}
+
+	for name := range oldUsers {
+		logger.Info().Msgf("Configuration reload: user %v removed", name)
+	}
+}
@@ -22,25 +22,69 @@
 package db
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
+	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dgraph-io/badger/v4"
 	"github.com/dkorunic/e-dnevnik-bot/logger"
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+	"github.com/goccy/go-json"
 )
 
 const (
-	DefaultDBPath       = ".e-dnevnik.db"  // default BadgerDB folder
-	DefaultTTL          = time.Hour * 9000 // a bit more than 1 year TTL
-	DefaultDiscardRatio = 0.5              // recommended discard ratio from Badger docs
+	DefaultDBPath       = ".e-dnevnik.db"   // default BadgerDB folder
+	DefaultTTL          = time.Hour * 9000  // a bit more than 1 year TTL
+	DefaultDiscardRatio = 0.5               // recommended discard ratio from Badger docs
+	BackupTimeFormat    = "20060102-150405" // corrupted database backup suffix
+	RenotifyPrefix      = "renotify/"       // key prefix for last notification timestamps
+	RenotifyGrace       = time.Hour * 24    // keep last notification timestamps a day after the event
+	RemindPrefix        = "remind/"         // key prefix for sent reminder timestamps
+	SetPrefix           = "set/"            // key prefix for stored string sets
+	QueuePrefix         = "queue/"          // key prefix for queues of messages waiting to be sent
+	FailedQueue         = "failed/"         // queue name prefix for messages a messenger failed to send
+	SchemaKey           = "schema/version"  // key holding alert database schema version
+	SchemaVersion       = 2                 // current schema version, alert keys include event type since version 2
 )
 
+var (
+	ErrCorrupted     = errors.New("database is corrupted")
+	ErrInvalidExport = errors.New("invalid database export entry")
+)
+
+// ExportEntry is a portable JSON representation of a single database entry.
+type ExportEntry struct {
+	Key       string     `json:"key"`                  // hex encoded key
+	Value     string     `json:"value"`                // base64 encoded value
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // expiry time (missing if the key never expires)
+}
+
+// Stats holds alert database size and key counts.
+type Stats struct {
+	Keys     int   // live keys
+	Expired  int   // expired keys not yet removed by garbage collection
+	Alerts   int   // live alert keys
+	Renotify int   // live last notification timestamps
+	Remind   int   // live sent reminder timestamps
+	Sets     int   // live stored string sets
+	Queues   int   // live queues of messages waiting to be sent
+	LSMSize  int64 // on-disk LSM tree size in bytes
+	VlogSize int64 // on-disk value log size in bytes
+}
+
 // Edb holds e-dnevnik structure including Bardger struct.
 type Edb struct {
 	db         *badger.DB
 	isExisting bool // already created/initialized db
+	version    int  // schema version of the db (0 for a new db)
 }
 
 // New opens a new database, flagging if the database already preexisting.
@@ -49,7 +93,7 @@ func New(filePath string) (*Edb, error) {
 		filePath = DefaultDBPath
 	}
 
-	isExisting := dbExists(filePath)
+	isExisting := Exists(filePath)
 
 	logger.Debug().Msgf("Opening database: %v", filePath)
 	opts := badger.DefaultOptions(filePath)
@@ -73,9 +117,75 @@ func New(filePath string) (*Edb, error) {
 
 	edb := &Edb{db: db, isExisting: isExisting}
 
+	// schema version of a new database is stored after the initial run
+	if !isExisting {
+		return edb, nil
+	}
+
+	edb.version, err = edb.schemaVersion()
+	if err != nil {
+		db.Close()
+
+		return nil, fmt.Errorf("could not read database schema version: %w", err)
+	}
+
+	if edb.Upgraded() {
+		logger.Info().Msgf("Detected database schema version %v, upgrading to version %v", edb.version,
+			SchemaVersion)
+	}
+
 	return edb, nil
 }
 
+// schemaVersion reads schema version of an existing database, where databases without a version are version 1.
+func (db *Edb) schemaVersion() (int, error) {
+	version := 1
+
+	err := db.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(SchemaKey))
+
+		switch {
+		// key not found (version 1)
+		case errors.Is(err, badger.ErrKeyNotFound):
+			return nil
+		// key found
+		case err == nil:
+			return item.Value(func(val []byte) error {
+				version, err = strconv.Atoi(string(val))
+
+				return err
+			})
+		}
+
+		// all other errors
+		return err
+	})
+
+	return version, err
+}
+
+// PutSchemaVersion stores the current schema version, once all alerts have been flagged with current keys, returning
+// error if encountered.
+func (db *Edb) PutSchemaVersion() error {
+	if db.version == SchemaVersion {
+		return nil
+	}
+
+	err := db.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(SchemaKey), []byte(strconv.Itoa(SchemaVersion)))
+	})
+	if err == nil {
+		db.version = SchemaVersion
+	}
+
+	return err
+}
+
+// Upgraded returns if the database was created with an older schema version, so its alert keys are not current.
+func (db *Edb) Upgraded() bool {
+	return db.isExisting && db.version < SchemaVersion
+}
+
 // Close closes database, optionally running GC (removing state data from value log file).
 func (db *Edb) Close() error {
 	logger.Debug().Msg("Running database GC")
@@ -91,15 +201,51 @@ again:
 	return db.db.Close()
 }
 
-// CheckAndFlag checks presence of a SHA256(bucket, subBucket, []target) in a KV database, returning if it has been
-// found or not, flagging it for the next time and returning error if encountered.
-func (db *Edb) CheckAndFlag(bucket, subBucket string, target []string) (bool, error) {
-	// SHA256 hash of (bucket, subBucket, []target)
-	key := hashContent(bucket, subBucket, target)
+// Check checks presence of a SHA256(eventCode, bucket, subBucket, []target) in a KV database without flagging it,
+// returning if it has been found or not and returning error if encountered.
+func (db *Edb) Check(code msgtypes.EventCode, bucket, subBucket string, target []string) (bool, error) {
+	return db.check(hashEvent(code, bucket, subBucket, target))
+}
+
+// CheckAndFlag checks presence of a SHA256(eventCode, bucket, subBucket, []target) in a KV database, returning if it
+// has been found or not, flagging it for the next time with default TTL and returning error if encountered.
+func (db *Edb) CheckAndFlag(code msgtypes.EventCode, bucket, subBucket string, target []string) (bool, error) {
+	return db.CheckAndFlagTTL(code, bucket, subBucket, target, DefaultTTL)
+}
+
+// CheckAndFlagTTL checks presence of a SHA256(eventCode, bucket, subBucket, []target) in a KV database, returning if
+// it has been found or not, flagging it for the next time with a given TTL and returning error if encountered.
+func (db *Edb) CheckAndFlagTTL(code msgtypes.EventCode, bucket, subBucket string, target []string,
+	ttl time.Duration,
+) (bool, error) {
+	// SHA256 hash of (eventCode, bucket, subBucket, []target)
+	key := hashEvent(code, bucket, subBucket, target)
+
+	found, err := db.check(key)
+	if err != nil {
+		// return quickly: (fatal) error + found=false
+		return false, err
+	} else if found {
+		// return quickly: no error + found=true
+		return true, nil
+	}
+
+	// key hasn't been found yet, so mark the key and set TTL
+	err = db.db.Update(func(txn *badger.Txn) error {
+		e := badger.NewEntry(key, []byte("")).WithTTL(ttl)
 
+		return txn.SetEntry(e)
+	})
+
+	// found=false
+	return false, err
+}
+
+// check checks presence of a key in a KV database, returning if it has been found or not and returning error if
+// encountered.
+func (db *Edb) check(key []byte) (bool, error) {
 	var found bool
 
-	// check if key exists
 	err := db.db.View(func(txn *badger.Txn) error {
 		_, err := txn.Get(key)
 
@@ -118,26 +264,489 @@ func (db *Edb) CheckAndFlag(bucket, subBucket string, target []string) (bool, er
 		return err
 	})
 
+	return found, err
+}
+
+// Renotify checks when was an event with SHA256(bucket, subBucket, []target) last notified of, returning if it is due
+// for a re-notification (interval has elapsed and event is still upcoming) and returning error if encountered. Both
+// unknown and due events get flagged with the current time as the last notification time.
+func (db *Edb) Renotify(bucket, subBucket string, target []string, interval time.Duration, eventTime,
+	now time.Time,
+) (bool, error) {
+	// stop re-notifying once the event has passed
+	if !eventTime.After(now) {
+		return false, nil
+	}
+
+	key := append([]byte(RenotifyPrefix), hashContent(bucket, subBucket, target)...)
+
+	var last time.Time
+
+	var found bool
+
+	// fetch last notification time if it exists
+	err := db.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+
+		switch {
+		// key not found (found=false)
+		case errors.Is(err, badger.ErrKeyNotFound):
+			return nil
+		// key found (found=true)
+		case err == nil:
+			found = true
+
+			return item.Value(func(val []byte) error {
+				return last.UnmarshalBinary(val)
+			})
+		}
+
+		// all other errors (found=false)
+		return err
+	})
 	if err != nil {
-		// return quickly: (fatal) error + found=false
 		return false, err
-	} else if found {
-		// return quickly: no error + found=true
-		return true, nil
 	}
 
-	// key hasn't been found yet, so mark the key and set 1+year TTL
+	// return quickly: interval hasn't elapsed yet
+	if found && now.Sub(last) < interval {
+		return false, nil
+	}
+
+	val, err := now.MarshalBinary()
+	if err != nil {
+		return false, err
+	}
+
+	// flag the current time as the last notification time, expiring after the event has passed
 	err = db.db.Update(func(txn *badger.Txn) error {
-		e := badger.NewEntry(key, []byte("")).WithTTL(DefaultTTL)
+		e := badger.NewEntry(key, val).WithTTL(eventTime.Sub(now) + RenotifyGrace)
 
 		return txn.SetEntry(e)
 	})
 
-	// found=false
-	return false, err
+	// due only if previously notified of
+	return found && err == nil, err
+}
+
+// Remind checks if an upcoming event with SHA256(bucket, subBucket, []target) has already been reminded of, returning
+// if a reminder is due (event is still upcoming and no reminder has been sent) and returning error if encountered. Due
+// events get flagged with the current time as the reminder time, so each event is reminded of only once.
+func (db *Edb) Remind(bucket, subBucket string, target []string, eventTime, now time.Time) (bool, error) {
+	// no reminders once the event has passed
+	if !eventTime.After(now) {
+		return false, nil
+	}
+
+	key := append([]byte(RemindPrefix), hashContent(bucket, subBucket, target)...)
+
+	var due bool
+
+	err := db.db.Update(func(txn *badger.Txn) error {
+		_, err := txn.Get(key)
+
+		switch {
+		// key found, already reminded of
+		case err == nil:
+			return nil
+		// all other errors
+		case !errors.Is(err, badger.ErrKeyNotFound):
+			return err
+		}
+
+		val, err := now.MarshalBinary()
+		if err != nil {
+			return err
+		}
+
+		// flag the current time as the reminder time, expiring after the event has passed
+		e := badger.NewEntry(key, val).WithTTL(eventTime.Sub(now) + RenotifyGrace)
+		if err := txn.SetEntry(e); err != nil {
+			return err
+		}
+
+		due = true
+
+		return nil
+	})
+
+	return due && err == nil, err
+}
+
+// GetSet fetches a string set stored under SHA256(bucket, subBucket), returning the set, if it has been found or not
+// and returning error if encountered.
+func (db *Edb) GetSet(bucket, subBucket string) ([]string, bool, error) {
+	var set []string
+
+	found, err := db.getValue(append([]byte(SetPrefix), hashContent(bucket, subBucket, nil)...), &set)
+
+	return set, found, err
+}
+
+// PutSet stores a string set under SHA256(bucket, subBucket) with 1+year TTL, returning error if encountered.
+func (db *Edb) PutSet(bucket, subBucket string, set []string) error {
+	return db.putValue(append([]byte(SetPrefix), hashContent(bucket, subBucket, nil)...), set)
+}
+
+// GetRows fetches table rows stored under SHA256(bucket, subBucket), returning the rows, if they have been found or not
+// and returning error if encountered.
+func (db *Edb) GetRows(bucket, subBucket string) ([][]string, bool, error) {
+	var rows [][]string
+
+	found, err := db.getValue(append([]byte(SetPrefix), hashContent(bucket, subBucket, nil)...), &rows)
+
+	return rows, found, err
+}
+
+// PutRows stores table rows under SHA256(bucket, subBucket) with 1+year TTL, returning error if encountered.
+func (db *Edb) PutRows(bucket, subBucket string, rows [][]string) error {
+	return db.putValue(append([]byte(SetPrefix), hashContent(bucket, subBucket, nil)...), rows)
+}
+
+// getValue fetches a JSON encoded value stored under the key into v, returning if it has been found or not and
+// returning error if encountered.
+func (db *Edb) getValue(key []byte, v any) (bool, error) {
+	var found bool
+
+	err := db.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+
+		switch {
+		// key not found (found=false)
+		case errors.Is(err, badger.ErrKeyNotFound):
+			return nil
+		// key found (found=true)
+		case err == nil:
+			found = true
+
+			return item.Value(func(val []byte) error {
+				return json.Unmarshal(val, v)
+			})
+		}
+
+		// all other errors (found=false)
+		return err
+	})
+
+	return found, err
+}
+
+// putValue stores a JSON encoded value under the key with 1+year TTL, returning error if encountered.
+func (db *Edb) putValue(key []byte, v any) error {
+	val, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return db.db.Update(func(txn *badger.Txn) error {
+		e := badger.NewEntry(key, val).WithTTL(DefaultTTL)
+
+		return txn.SetEntry(e)
+	})
+}
+
+// AppendQueue appends messages to the named queue of messages waiting to be sent with 1+year TTL, returning error if
+// encountered.
+func (db *Edb) AppendQueue(queue string, msgs ...msgtypes.Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	key := []byte(QueuePrefix + queue)
+
+	return db.db.Update(func(txn *badger.Txn) error {
+		queued, err := getQueue(txn, key)
+		if err != nil {
+			return err
+		}
+
+		val, err := json.Marshal(append(queued, msgs...))
+		if err != nil {
+			return err
+		}
+
+		e := badger.NewEntry(key, val).WithTTL(DefaultTTL)
+
+		return txn.SetEntry(e)
+	})
+}
+
+// PopQueue fetches and removes all messages from the named queue of messages waiting to be sent, returning error if
+// encountered.
+func (db *Edb) PopQueue(queue string) ([]msgtypes.Message, error) {
+	key := []byte(QueuePrefix + queue)
+
+	var msgs []msgtypes.Message
+
+	err := db.db.Update(func(txn *badger.Txn) error {
+		var err error
+
+		msgs, err = getQueue(txn, key)
+		if err != nil || len(msgs) == 0 {
+			return err
+		}
+
+		return txn.Delete(key)
+	})
+
+	return msgs, err
+}
+
+// PutFailed queues messages the messenger failed to send, so that they are sent again in the next run, returning error
+// if encountered.
+func (db *Edb) PutFailed(messenger string, msgs []msgtypes.Message) error {
+	return db.AppendQueue(FailedQueue+messenger, msgs...)
+}
+
+// PopFailed fetches and removes all messages the messenger failed to send, returning error if encountered.
+func (db *Edb) PopFailed(messenger string) ([]msgtypes.Message, error) {
+	return db.PopQueue(FailedQueue + messenger)
+}
+
+// getQueue reads messages of a queue within a transaction, where a missing queue is empty.
+func getQueue(txn *badger.Txn, key []byte) ([]msgtypes.Message, error) {
+	var msgs []msgtypes.Message
+
+	item, err := txn.Get(key)
+
+	switch {
+	// key not found (empty queue)
+	case errors.Is(err, badger.ErrKeyNotFound):
+		return nil, nil
+	// key found
+	case err == nil:
+		err = item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &msgs)
+		})
+	}
+
+	return msgs, err
+}
+
+// Iterate walks through all keys in a KV database, calling fn with the key, value and expiry time (zero if the key
+// never expires) of every entry and stopping on the first returned error.
+func (db *Edb) Iterate(fn func(key, value []byte, expiresAt time.Time) error) error {
+	return db.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+
+			var expiresAt time.Time
+			if e := item.ExpiresAt(); e > 0 {
+				expiresAt = time.Unix(int64(e), 0) //nolint:gosec
+			}
+
+			val, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+
+			if err := fn(item.KeyCopy(nil), val, expiresAt); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Stats counts live keys by type and expired keys not yet removed by garbage collection, along with on-disk database
+// size as measured by Badger when the database was opened and every minute afterwards.
+func (db *Edb) Stats(ctx context.Context) (Stats, error) {
+	var stats Stats
+
+	err := db.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.AllVersions = true // expired keys are skipped otherwise
+		opts.PrefetchValues = false
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		var last []byte
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			item := it.Item()
+
+			// only the latest version of every key counts
+			key := item.Key()
+			if bytes.Equal(key, last) {
+				continue
+			}
+
+			last = item.KeyCopy(last)
+
+			if item.IsDeletedOrExpired() {
+				if e := item.ExpiresAt(); e > 0 && e <= uint64(time.Now().Unix()) { //nolint:gosec
+					stats.Expired++
+				}
+
+				continue
+			}
+
+			stats.Keys++
+
+			switch {
+			case bytes.HasPrefix(key, []byte(RenotifyPrefix)):
+				stats.Renotify++
+			case bytes.HasPrefix(key, []byte(RemindPrefix)):
+				stats.Remind++
+			case bytes.HasPrefix(key, []byte(SetPrefix)):
+				stats.Sets++
+			case bytes.HasPrefix(key, []byte(QueuePrefix)):
+				stats.Queues++
+			case bytes.Equal(key, []byte(SchemaKey)):
+			default:
+				stats.Alerts++
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	stats.LSMSize, stats.VlogSize = db.db.Size()
+
+	return stats, nil
+}
+
+// ExportJSON writes all database entries to w as a JSON array of ExportEntry values.
+func (db *Edb) ExportJSON(ctx context.Context, w io.Writer) error {
+	entries := []ExportEntry{}
+
+	err := db.Iterate(func(key, value []byte, expiresAt time.Time) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		e := ExportEntry{
+			Key:   hex.EncodeToString(key),
+			Value: base64.StdEncoding.EncodeToString(value),
+		}
+
+		if !expiresAt.IsZero() {
+			e.ExpiresAt = &expiresAt
+		}
+
+		entries = append(entries, e)
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(entries)
+}
+
+// ImportJSON reads a JSON array of ExportEntry values from r and stores them in the database, overwriting existing
+// keys and skipping already expired entries, returning the number of imported entries.
+func (db *Edb) ImportJSON(ctx context.Context, r io.Reader) (int, error) {
+	var entries []ExportEntry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return 0, err
+	}
+
+	wb := db.db.NewWriteBatch()
+	defer wb.Cancel()
+
+	now := time.Now()
+	count := 0
+
+	for i, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		key, err := hex.DecodeString(e.Key)
+		if err != nil || len(key) == 0 {
+			return 0, fmt.Errorf("%w: entry %v: invalid key", ErrInvalidExport, i)
+		}
+
+		val, err := base64.StdEncoding.DecodeString(e.Value)
+		if err != nil {
+			return 0, fmt.Errorf("%w: entry %v: invalid value", ErrInvalidExport, i)
+		}
+
+		entry := badger.NewEntry(key, val)
+
+		if e.ExpiresAt != nil {
+			if !e.ExpiresAt.After(now) {
+				continue
+			}
+
+			entry.ExpiresAt = uint64(e.ExpiresAt.Unix()) //nolint:gosec
+		}
+
+		if err := wb.SetEntry(entry); err != nil {
+			return 0, err
+		}
+
+		count++
+	}
+
+	if err := wb.Flush(); err != nil {
+		return 0, err
+	}
+
+	return count, nil
 }
 
 // Existing returns if the database was freshly initialized.
 func (db *Edb) Existing() bool {
 	return db.isExisting
 }
+
+// Verify opens an existing database and verifies checksums of all of its tables, returning ErrCorrupted if database
+// cannot be opened or if any corruption has been detected.
+func Verify(filePath string) error {
+	if filePath == "" {
+		filePath = DefaultDBPath
+	}
+
+	// nothing to verify, database will be freshly created
+	if !Exists(filePath) {
+		return nil
+	}
+
+	logger.Debug().Msgf("Verifying database: %v", filePath)
+
+	db, err := badger.Open(badger.DefaultOptions(filePath).WithLogger(nil))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCorrupted, err)
+	}
+	defer db.Close()
+
+	if err := db.VerifyChecksum(); err != nil {
+		return fmt.Errorf("%w: %w", ErrCorrupted, err)
+	}
+
+	return nil
+}
+
+// Backup moves the database out of the way to a timestamped backup path so that it can be recreated from scratch,
+// returning the backup path and optional error.
+func Backup(filePath string) (string, error) {
+	if filePath == "" {
+		filePath = DefaultDBPath
+	}
+
+	backupPath := strings.Join([]string{filePath, "corrupt", time.Now().Format(BackupTimeFormat)}, ".")
+
+	if err := os.Rename(filePath, backupPath); err != nil {
+		return "", fmt.Errorf("could not back up database: %w", err)
+	}
+
+	return backupPath, nil
+}
@@ -0,0 +1,470 @@
+// @license
+// Copyright (C) 2022  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package db
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+)
+
+func TestVerify(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), DefaultDBPath)
+
+	eDB, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("unable to create database: %v", err)
+	}
+
+	for _, s := range []string{"Matematika", "Fizika", "Kemija"} {
+		if _, err := eDB.CheckAndFlag(msgtypes.Grade, "korisnik@test.domena", s, []string{"1.1.", "5"}); err != nil {
+			t.Fatalf("unable to flag key: %v", err)
+		}
+	}
+
+	// closing flushes memtable to a SST table on disk
+	if err := eDB.Close(); err != nil {
+		t.Fatalf("unable to close database: %v", err)
+	}
+
+	if err := Verify(dbPath); err != nil {
+		t.Fatalf("healthy database failed verification: %v", err)
+	}
+
+	tables, err := filepath.Glob(filepath.Join(dbPath, "*.sst"))
+	if err != nil || len(tables) == 0 {
+		t.Fatalf("unable to find SST tables: %v", err)
+	}
+
+	// deliberately corrupt the first data block
+	f, err := os.OpenFile(tables[0], os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("unable to open SST table: %v", err)
+	}
+
+	if _, err := f.WriteAt([]byte("corrupted"), 16); err != nil {
+		t.Fatalf("unable to corrupt SST table: %v", err)
+	}
+
+	f.Close()
+
+	if err := Verify(dbPath); !errors.Is(err, ErrCorrupted) {
+		t.Fatalf("corrupted database passed verification: %v", err)
+	}
+}
+
+func TestRenotify(t *testing.T) {
+	eDB, err := New(filepath.Join(t.TempDir(), DefaultDBPath))
+	if err != nil {
+		t.Fatalf("unable to create database: %v", err)
+	}
+	defer eDB.Close()
+
+	const interval = 24 * time.Hour
+
+	now := time.Now()
+	exam := now.Add(5 * interval)
+	target := []string{"Matematika", "10.01.2025.", "Pisana provjera"}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want bool
+	}{
+		{"first notification", now, false},
+		{"interval not elapsed", now.Add(interval / 2), false},
+		{"interval elapsed", now.Add(interval), true},
+		{"just re-notified", now.Add(interval + time.Hour), false},
+		{"interval elapsed again", now.Add(2 * interval), true},
+		{"exam passed", exam.Add(time.Hour), false},
+	}
+
+	for _, tt := range tests {
+		got, err := eDB.Renotify("korisnik@test.domena", "Matematika", target, interval, exam, tt.now)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", tt.name, err)
+		}
+
+		if got != tt.want {
+			t.Errorf("%v: Renotify() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRemind(t *testing.T) {
+	eDB, err := New(filepath.Join(t.TempDir(), DefaultDBPath))
+	if err != nil {
+		t.Fatalf("unable to create database: %v", err)
+	}
+	defer eDB.Close()
+
+	now := time.Now()
+	exam := now.Add(24 * time.Hour)
+	target := []string{"Matematika", "10.01.2025.", "Pisana provjera"}
+
+	tests := []struct {
+		name   string
+		target []string
+		now    time.Time
+		want   bool
+	}{
+		{"exam passed", target, exam.Add(time.Hour), false},
+		{"first reminder", target, now, true},
+		{"already reminded", target, now.Add(time.Hour), false},
+		{"edited exam", []string{"Matematika", "11.01.2025.", "Pisana provjera"}, now, true},
+	}
+
+	for _, tt := range tests {
+		got, err := eDB.Remind("korisnik@test.domena", "Matematika", tt.target, exam, tt.now)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", tt.name, err)
+		}
+
+		if got != tt.want {
+			t.Errorf("%v: Remind() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIterate(t *testing.T) {
+	eDB, err := New(filepath.Join(t.TempDir(), DefaultDBPath))
+	if err != nil {
+		t.Fatalf("unable to create database: %v", err)
+	}
+	defer eDB.Close()
+
+	if _, err := eDB.CheckAndFlagTTL(msgtypes.Grade, "korisnik@test.domena", "Matematika", []string{"1.1.", "5"}, time.Hour); err != nil {
+		t.Fatalf("unable to flag key: %v", err)
+	}
+
+	if err := eDB.PutSet("korisnik@test.domena", "classes", []string{"8.a"}); err != nil {
+		t.Fatalf("unable to store set: %v", err)
+	}
+
+	var keys, sets int
+
+	now := time.Now()
+
+	err = eDB.Iterate(func(key, value []byte, expiresAt time.Time) error {
+		keys++
+
+		if strings.HasPrefix(string(key), SetPrefix) {
+			sets++
+
+			if string(value) != `["8.a"]` {
+				t.Errorf("unexpected set value: %s", value)
+			}
+		}
+
+		if expiresAt.Before(now) || expiresAt.After(now.Add(DefaultTTL+time.Minute)) {
+			t.Errorf("unexpected expiry time: %v", expiresAt)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unable to iterate database: %v", err)
+	}
+
+	if keys != 2 || sets != 1 {
+		t.Fatalf("got %d keys and %d sets, want 2 keys and 1 set", keys, sets)
+	}
+}
+
+func TestStats(t *testing.T) {
+	eDB, err := New(filepath.Join(t.TempDir(), DefaultDBPath))
+	if err != nil {
+		t.Fatalf("unable to create database: %v", err)
+	}
+	defer eDB.Close()
+
+	if _, err := eDB.CheckAndFlag(msgtypes.Grade, "korisnik@test.domena", "Matematika", []string{"1.1.", "5"}); err != nil {
+		t.Fatalf("unable to flag key: %v", err)
+	}
+
+	if _, err := eDB.CheckAndFlagTTL(msgtypes.Grade, "korisnik@test.domena", "Fizika", []string{"2.1.", "4"}, time.Second); err != nil {
+		t.Fatalf("unable to flag key: %v", err)
+	}
+
+	if err := eDB.PutSet("korisnik@test.domena", "classes", []string{"8.a"}); err != nil {
+		t.Fatalf("unable to store set: %v", err)
+	}
+
+	if err := eDB.PutSchemaVersion(); err != nil {
+		t.Fatalf("unable to store schema version: %v", err)
+	}
+
+	time.Sleep(2 * time.Second)
+
+	stats, err := eDB.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("unable to get database stats: %v", err)
+	}
+
+	want := Stats{Keys: 3, Expired: 1, Alerts: 1, Sets: 1, LSMSize: stats.LSMSize, VlogSize: stats.VlogSize}
+	if stats != want {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+}
+
+func TestExportImportJSON(t *testing.T) {
+	src, err := New(filepath.Join(t.TempDir(), DefaultDBPath))
+	if err != nil {
+		t.Fatalf("unable to create database: %v", err)
+	}
+	defer src.Close()
+
+	if _, err := src.CheckAndFlagTTL(msgtypes.Grade, "korisnik@test.domena", "Matematika", []string{"1.1.", "5"}, time.Hour); err != nil {
+		t.Fatalf("unable to flag key: %v", err)
+	}
+
+	if err := src.PutSet("korisnik@test.domena", "classes", []string{"8.a"}); err != nil {
+		t.Fatalf("unable to store set: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := src.ExportJSON(context.Background(), &buf); err != nil {
+		t.Fatalf("ExportJSON() = %v", err)
+	}
+
+	dst, err := New(filepath.Join(t.TempDir(), DefaultDBPath))
+	if err != nil {
+		t.Fatalf("unable to create database: %v", err)
+	}
+	defer dst.Close()
+
+	n, err := dst.ImportJSON(context.Background(), &buf)
+	if err != nil || n != 2 {
+		t.Fatalf("ImportJSON() = %v, %v, want 2 entries", n, err)
+	}
+
+	found, err := dst.Check(msgtypes.Grade, "korisnik@test.domena", "Matematika", []string{"1.1.", "5"})
+	if err != nil || !found {
+		t.Errorf("imported key not found: %v", err)
+	}
+
+	set, ok, err := dst.GetSet("korisnik@test.domena", "classes")
+	if err != nil || !ok || len(set) != 1 || set[0] != "8.a" {
+		t.Errorf("GetSet() = %v, %v, %v, want [8.a]", set, ok, err)
+	}
+
+	expired := `[{"key":"6b6579","value":"","expires_at":"2000-01-01T00:00:00Z"},{"key":"6b657932","value":""}]`
+
+	n, err = dst.ImportJSON(context.Background(), strings.NewReader(expired))
+	if err != nil || n != 1 {
+		t.Errorf("ImportJSON() = %v, %v, want 1 entry", n, err)
+	}
+
+	if _, err := dst.ImportJSON(context.Background(), strings.NewReader(`[{"key":"zz","value":""}]`)); !errors.Is(err,
+		ErrInvalidExport) {
+		t.Errorf("ImportJSON() error = %v, want %v", err, ErrInvalidExport)
+	}
+}
+
+func TestCheckEventCode(t *testing.T) {
+	eDB, err := New(filepath.Join(t.TempDir(), DefaultDBPath))
+	if err != nil {
+		t.Fatalf("unable to create database: %v", err)
+	}
+	defer eDB.Close()
+
+	if _, err := eDB.CheckAndFlag(msgtypes.Grade, "korisnik@test.domena", "Matematika", []string{"1.1.", "5"}); err != nil {
+		t.Fatalf("unable to flag key: %v", err)
+	}
+
+	if found, err := eDB.Check(msgtypes.Grade, "korisnik@test.domena", "Matematika", []string{"1.1.", "5"}); err != nil || !found {
+		t.Errorf("flagged grade not found: %v", err)
+	}
+
+	if found, err := eDB.Check(msgtypes.Note, "korisnik@test.domena", "Matematika", []string{"1.1.", "5"}); err != nil || found {
+		t.Errorf("note with the same fields as a grade found: %v", err)
+	}
+}
+
+func TestSchemaVersion(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), DefaultDBPath)
+
+	eDB, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("unable to create database: %v", err)
+	}
+
+	if eDB.Existing() || eDB.Upgraded() {
+		t.Errorf("new database reported as existing or upgraded")
+	}
+
+	// close without storing the schema version, as with a database from an older version
+	if err := eDB.Close(); err != nil {
+		t.Fatalf("unable to close database: %v", err)
+	}
+
+	eDB, err = New(dbPath)
+	if err != nil {
+		t.Fatalf("unable to open database: %v", err)
+	}
+
+	if !eDB.Existing() || !eDB.Upgraded() {
+		t.Errorf("database without a schema version not reported as upgraded")
+	}
+
+	if err := eDB.PutSchemaVersion(); err != nil {
+		t.Fatalf("PutSchemaVersion() = %v", err)
+	}
+
+	if err := eDB.Close(); err != nil {
+		t.Fatalf("unable to close database: %v", err)
+	}
+
+	eDB, err = New(dbPath)
+	if err != nil {
+		t.Fatalf("unable to open database: %v", err)
+	}
+	defer eDB.Close()
+
+	if !eDB.Existing() || eDB.Upgraded() {
+		t.Errorf("database with the current schema version reported as upgraded")
+	}
+}
+
+func TestRowEdits(t *testing.T) {
+	first := []string{"1.1.", "5", "Usmeno ispitivanje"}
+	second := []string{"1.1.", "4", "Pisana provjera"}
+	edited := []string{"1.1.", "3", "Pisana provjera"}
+
+	tests := []struct {
+		name              string
+		previous, current [][]string
+		want              map[int][]string
+	}{
+		{"unchanged", [][]string{first, second}, [][]string{first, second}, nil},
+		{"grade added after", [][]string{first}, [][]string{first, second}, nil},
+		{"grade added before", [][]string{first}, [][]string{second, first}, nil},
+		{"grade edited", [][]string{first, second}, [][]string{first, edited}, map[int][]string{1: second}},
+		{"grade edited and reordered", [][]string{first, second}, [][]string{edited, first}, map[int][]string{0: second}},
+		{"different fields", [][]string{{"1.1.", "5"}}, [][]string{first}, map[int][]string{}},
+	}
+
+	for _, tt := range tests {
+		got := RowEdits(tt.previous, tt.current)
+
+		if len(got) != len(tt.want) {
+			t.Errorf("%v: RowEdits() = %v, want %v", tt.name, got, tt.want)
+
+			continue
+		}
+
+		for i, w := range tt.want {
+			if !slices.Equal(got[i], w) {
+				t.Errorf("%v: RowEdits() = %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestRows(t *testing.T) {
+	eDB, err := New(filepath.Join(t.TempDir(), DefaultDBPath))
+	if err != nil {
+		t.Fatalf("unable to create database: %v", err)
+	}
+	defer eDB.Close()
+
+	if _, found, err := eDB.GetRows("korisnik@test.domena", "Matematika/1.1."); found || err != nil {
+		t.Fatalf("GetRows() of missing rows = %v, %v", found, err)
+	}
+
+	rows := [][]string{{"1.1.", "5"}, {"1.1.", "4"}}
+
+	if err := eDB.PutRows("korisnik@test.domena", "Matematika/1.1.", rows); err != nil {
+		t.Fatalf("unable to store rows: %v", err)
+	}
+
+	got, found, err := eDB.GetRows("korisnik@test.domena", "Matematika/1.1.")
+	if !found || err != nil || len(got) != 2 || !slices.Equal(got[1], rows[1]) {
+		t.Errorf("GetRows() = %v, %v, %v, want %v", got, found, err, rows)
+	}
+}
+
+func TestQueue(t *testing.T) {
+	eDB, err := New(filepath.Join(t.TempDir(), DefaultDBPath))
+	if err != nil {
+		t.Fatalf("unable to create database: %v", err)
+	}
+	defer eDB.Close()
+
+	first := msgtypes.Message{Username: "korisnik@test.domena", Subject: "Matematika", Fields: []string{"1.1.", "5"}}
+	second := msgtypes.Message{Username: "korisnik@test.domena", Subject: "Fizika", Code: msgtypes.Exam}
+
+	if err := eDB.AppendQueue("quiet", first); err != nil {
+		t.Fatalf("unable to queue message: %v", err)
+	}
+
+	if err := eDB.AppendQueue("quiet", second); err != nil {
+		t.Fatalf("unable to queue message: %v", err)
+	}
+
+	msgs, err := eDB.PopQueue("quiet")
+	if err != nil {
+		t.Fatalf("unable to fetch queue: %v", err)
+	}
+
+	if len(msgs) != 2 || msgs[0].Subject != first.Subject || msgs[1].Code != second.Code {
+		t.Fatalf("PopQueue() = %+v, want both queued messages in order", msgs)
+	}
+
+	if msgs, err := eDB.PopQueue("quiet"); err != nil || len(msgs) != 0 {
+		t.Errorf("PopQueue() after pop = %+v, %v, want empty queue", msgs, err)
+	}
+
+	if msgs, err := eDB.PopQueue("digest"); err != nil || len(msgs) != 0 {
+		t.Errorf("PopQueue() of unknown queue = %+v, %v, want empty queue", msgs, err)
+	}
+}
+
+func TestFailed(t *testing.T) {
+	eDB, err := New(filepath.Join(t.TempDir(), DefaultDBPath))
+	if err != nil {
+		t.Fatalf("unable to create database: %v", err)
+	}
+	defer eDB.Close()
+
+	msgs := []msgtypes.Message{{Username: "korisnik@test.domena", Subject: "Matematika"}}
+
+	if err := eDB.PutFailed("discord", msgs); err != nil {
+		t.Fatalf("unable to store failed messages: %v", err)
+	}
+
+	if got, err := eDB.PopFailed("slack"); err != nil || len(got) != 0 {
+		t.Errorf("PopFailed() of another messenger = %+v, %v, want none", got, err)
+	}
+
+	if got, err := eDB.PopFailed("discord"); err != nil || len(got) != 1 || got[0].Subject != "Matematika" {
+		t.Errorf("PopFailed() = %+v, %v, want stored messages", got, err)
+	}
+}
@@ -25,12 +25,14 @@ import (
 	"bytes"
 	"errors"
 	"os"
+	"slices"
 
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
 	"github.com/minio/sha256-simd"
 )
 
-// dbExists checks if the path exists on the filesystem and returns boolean.
-func dbExists(filePath string) bool {
+// Exists checks if the path exists on the filesystem and returns boolean.
+func Exists(filePath string) bool {
 	_, err := os.Lstat(filePath)
 
 	return !errors.Is(err, os.ErrNotExist)
@@ -61,3 +63,68 @@ func hashContent(bucket, subBucket string, target []string) []byte {
 
 	return targetHash256[:]
 }
+
+// hashEvent creates SHA-256 hash of an event from (eventCode, bucket, subBucket, []target) and returns []byte result, so
+// that events of different types never share a key.
+func hashEvent(code msgtypes.EventCode, bucket, subBucket string, target []string) []byte {
+	return hashContent(code.String()+"\x00"+bucket, subBucket, target)
+}
+
+// DiffSets compares previous and current string sets, returning elements added to and removed from the current set.
+func DiffSets(previous, current []string) ([]string, []string) {
+	var added, removed []string
+
+	for _, c := range current {
+		if !slices.Contains(previous, c) {
+			added = append(added, c)
+		}
+	}
+
+	for _, p := range previous {
+		if !slices.Contains(current, p) {
+			removed = append(removed, p)
+		}
+	}
+
+	return added, removed
+}
+
+// RowEdits compares previous and current table rows, returning previous content of edited rows keyed by their index
+// in the current rows. Rows are considered edited only if the number of rows is unchanged, so that a newly added row
+// is never reported as an edit of another one, and then rows no longer present are paired in order with rows not
+// present before, as long as they have the same number of fields.
+func RowEdits(previous, current [][]string) map[int][]string {
+	if len(previous) != len(current) {
+		return nil
+	}
+
+	var removed [][]string
+
+	for _, p := range previous {
+		if !slices.ContainsFunc(current, func(c []string) bool { return slices.Equal(p, c) }) {
+			removed = append(removed, p)
+		}
+	}
+
+	var added []int
+
+	for i, c := range current {
+		if !slices.ContainsFunc(previous, func(p []string) bool { return slices.Equal(p, c) }) {
+			added = append(added, i)
+		}
+	}
+
+	if len(removed) == 0 || len(removed) != len(added) {
+		return nil
+	}
+
+	edits := make(map[int][]string, len(added))
+
+	for i, idx := range added {
+		if len(removed[i]) == len(current[idx]) {
+			edits[idx] = removed[i]
+		}
+	}
+
+	return edits
+}
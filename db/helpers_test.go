@@ -0,0 +1,54 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package db
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDiffSets(t *testing.T) {
+	tests := []struct {
+		name              string
+		previous, current []string
+		added, removed    []string
+	}{
+		{"unchanged", []string{"1.a", "2.b"}, []string{"2.b", "1.a"}, nil, nil},
+		{"added", []string{"1.a"}, []string{"1.a", "2.b"}, []string{"2.b"}, nil},
+		{"removed", []string{"1.a", "2.b"}, []string{"1.a"}, nil, []string{"2.b"}},
+		{"replaced", []string{"1.a"}, []string{"1.b"}, []string{"1.b"}, []string{"1.a"}},
+		{"initial", nil, []string{"1.a"}, []string{"1.a"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, removed := DiffSets(tt.previous, tt.current)
+			if !slices.Equal(added, tt.added) {
+				t.Errorf("DiffSets() added = %v, want %v", added, tt.added)
+			}
+
+			if !slices.Equal(removed, tt.removed) {
+				t.Errorf("DiffSets() removed = %v, want %v", removed, tt.removed)
+			}
+		})
+	}
+}
@@ -23,11 +23,18 @@ package fetch
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/cookiejar"
+	"net/url"
+	"os"
 	"time"
 
 	"github.com/corpix/uarand"
+	"github.com/dkorunic/e-dnevnik-bot/logger"
 )
 
 const (
@@ -35,35 +42,151 @@ const (
 	ClassURL       = "https://ocjene.skole.hr/class"
 	ClassActionURL = "https://ocjene.skole.hr/class_action/%v/course"
 	GradeAllURL    = "https://ocjene.skole.hr/grade/all"
+	AbsentURL      = "https://ocjene.skole.hr/absent"
+	NotesURL       = "https://ocjene.skole.hr/notes"
+	ScheduleURL    = "https://ocjene.skole.hr/schedule"
+	NationalURL    = "https://ocjene.skole.hr/national_exam"
 	CalendarURL    = "https://ocjene.skole.hr/exam/ical"
-	Timeout        = 60 * time.Second // site can get really slow sometimes
+	Timeout        = 60 * time.Second // default request timeout, site can get really slow sometimes
 )
 
-// NewClientWithContext creates new *Client, initializing HTTP Cookie Jar, context and username with password.
-func NewClientWithContext(ctx context.Context, username, password string) (*Client, error) {
+var (
+	ErrInvalidProxy      = errors.New("invalid proxy URL, supported schemes are http, https, socks5 and socks5h")
+	ErrInvalidCABundle   = errors.New("invalid CA bundle, no PEM certificates found")
+	ErrInvalidClientCert = errors.New("invalid client certificate or key")
+)
+
+// NewClientWithContext creates new *Client, initializing HTTP Cookie Jar, context and username with password. Optional
+// proxy URL overrides proxy settings from the environment (HTTP_PROXY, HTTPS_PROXY and NO_PROXY), while request
+// timeout and transport settings can be tuned through options.
+func NewClientWithContext(ctx context.Context, username, password string, opts Options) (*Client, error) {
 	// Cookie Jar needed for SSO and security cookie checks
 	jar, err := cookiejar.New(nil)
 	if err != nil {
 		return nil, err
 	}
 
+	transport, err := newTransport(opts)
+	if err != nil {
+		return nil, err
+	}
+
 	c := &Client{
 		httpClient: &http.Client{
-			Timeout: Timeout,
-			Jar:     jar,
+			Timeout:   opts.RequestTimeout(),
+			Jar:       jar,
+			Transport: transport,
 		},
 		ctx:      ctx,
 		username: username,
 		password: password,
+		saveDir:  opts.SaveDir,
+		pinnedUA: opts.UserAgent,
 	}
 
 	return c, nil
 }
 
-// Login attempts get CSRF Token and do SSO/SAML authentication with random User-Agent per session.
+// ClassLink returns a link switching e-dnevnik site to the active class and showing its subjects, or an empty string
+// if class ID is unknown.
+func ClassLink(classID string) string {
+	if classID == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(ClassActionURL, url.PathEscape(classID))
+}
+
+// RequestTimeout returns per-request timeout, defaulting to Timeout.
+func (o Options) RequestTimeout() time.Duration {
+	if o.Timeout > 0 {
+		return o.Timeout
+	}
+
+	return Timeout
+}
+
+// newTransport creates HTTP transport, using either an explicit proxy URL or proxy settings from the environment, with
+// optional idle connections, minimum TLS version, root CAs and client certificates tuning.
+func newTransport(opts Options) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert
+
+	// all requests go to a single host
+	if opts.MaxIdleConns > 0 {
+		transport.MaxIdleConns = opts.MaxIdleConns
+		transport.MaxIdleConnsPerHost = opts.MaxIdleConns
+	}
+
+	if opts.TLSMinVersion != 0 || opts.RootCAs != nil || len(opts.Certificates) > 0 {
+		transport.TLSClientConfig = &tls.Config{
+			MinVersion:   opts.TLSMinVersion,
+			RootCAs:      opts.RootCAs,
+			Certificates: opts.Certificates,
+		}
+	}
+
+	if opts.Proxy == "" {
+		return transport, nil
+	}
+
+	u, err := url.Parse(opts.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidProxy, err)
+	}
+
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+		transport.Proxy = http.ProxyURL(u)
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProxy, u.Scheme)
+	}
+
+	return transport, nil
+}
+
+// LoadTLS reads an optional PEM CA bundle, added to system root CAs (ie. for a corporate MITM proxy), and an optional
+// PEM client certificate and key pair, returning nil values for those not set.
+func LoadTLS(caFile, certFile, keyFile string) (*x509.CertPool, []tls.Certificate, error) {
+	var (
+		pool  *x509.CertPool
+		certs []tls.Certificate
+	)
+
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		pool, err = x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidCABundle, caFile)
+		}
+	}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %w", ErrInvalidClientCert, err)
+		}
+
+		certs = []tls.Certificate{cert}
+	}
+
+	return pool, certs, nil
+}
+
+// Login attempts get CSRF Token and do SSO/SAML authentication with random (unless pinned) User-Agent per session.
 func (c *Client) Login() error {
 	// generate random User-Agent per fetch dialog
-	c.userAgent = uarand.GetRandom()
+	c.userAgent = c.pinnedUA
+	if c.userAgent == "" {
+		c.userAgent = uarand.GetRandom()
+	}
 
 	// get secret CSRF Token from /
 	if err := c.getCSRFToken(); err != nil {
@@ -76,13 +199,35 @@ func (c *Client) Login() error {
 
 // GetClassEvents attempts to fetch all subjects and their grades, as well as all calendar events for exams in ICS
 // format, returning raw grades listing body, parsed exam events and optional error.
+//
+// If SSO session expires while fetching, it logs in again and repeats the fetch once.
 func (c *Client) GetClassEvents(classID string) (string, Events, error) {
+	var (
+		rawGrades string
+		events    Events
+	)
+
+	err := c.withRelogin(func() error {
+		var err error
+
+		rawGrades, events, err = c.getClassEvents(classID)
+
+		return err
+	})
+
+	return rawGrades, events, err
+}
+
+// getClassEvents switches active class to class ID and fetches all its grades and exam events.
+func (c *Client) getClassEvents(classID string) (string, Events, error) {
 	// do class action to switch active class to class ID
 	err := c.doClassAction(classID)
 	if err != nil {
 		return "", Events{}, err
 	}
 
+	c.classID = classID
+
 	// fetch all grades as raw string/body
 	rawGrades, err := c.getGrades()
 	if err != nil {
@@ -98,11 +243,95 @@ func (c *Client) GetClassEvents(classID string) (string, Events, error) {
 	return rawGrades, events, nil
 }
 
+// GetAbsences attempts to fetch absences of the active class (previously switched to with GetClassEvents), returning
+// raw absences listing body and optional error. Classes or schools without absences have no such page, which results
+// in an empty body.
+func (c *Client) GetAbsences() (string, error) {
+	var rawAbsences string
+
+	err := c.withRelogin(func() error {
+		var err error
+
+		rawAbsences, err = c.getAbsences()
+		if errors.Is(err, ErrPageNotFound) {
+			rawAbsences, err = "", nil
+		}
+
+		return err
+	})
+
+	return rawAbsences, err
+}
+
+// GetNotes attempts to fetch teacher notes of the active class (previously switched to with GetClassEvents),
+// returning raw notes listing body and optional error. Classes or schools without notes have no such page, which
+// results in an empty body.
+func (c *Client) GetNotes() (string, error) {
+	var rawNotes string
+
+	err := c.withRelogin(func() error {
+		var err error
+
+		rawNotes, err = c.getNotes()
+		if errors.Is(err, ErrPageNotFound) {
+			rawNotes, err = "", nil
+		}
+
+		return err
+	})
+
+	return rawNotes, err
+}
+
+// GetSchedule attempts to fetch weekly class timetable of the active class (previously switched to with
+// GetClassEvents), returning raw schedule listing body and optional error.
+func (c *Client) GetSchedule() (string, error) {
+	var rawSchedule string
+
+	err := c.withRelogin(func() error {
+		var err error
+
+		rawSchedule, err = c.getPage(ScheduleURL)
+
+		return err
+	})
+
+	return rawSchedule, err
+}
+
+// GetNationalExams attempts to fetch national exam results of the student, which are the same for all active classes,
+// returning raw national exams listing body and optional error. Classes without national exams have no such page,
+// which results in an empty body.
+func (c *Client) GetNationalExams() (string, error) {
+	var rawNational string
+
+	err := c.withRelogin(func() error {
+		var err error
+
+		rawNational, err = c.getPage(NationalURL)
+		if errors.Is(err, ErrPageNotFound) {
+			rawNational, err = "", nil
+		}
+
+		return err
+	})
+
+	return rawNational, err
+}
+
 // GetClasses attempts to fetch all courses where a student has been previously enlisted or still is (multiple
 // active classes possible).
 func (c *Client) GetClasses() (string, error) {
+	var rawClasses string
+
 	// fetch all active classes
-	rawClasses, err := c.getClasses()
+	err := c.withRelogin(func() error {
+		var err error
+
+		rawClasses, err = c.getClasses()
+
+		return err
+	})
 	if err != nil {
 		return "", err
 	}
@@ -110,6 +339,29 @@ func (c *Client) GetClasses() (string, error) {
 	return rawClasses, nil
 }
 
+// withRelogin runs fetch function and, if it failed due to an expired SSO session, logs in again, switches back to
+// the active class and runs it once more.
+func (c *Client) withRelogin(fn func() error) error {
+	err := fn()
+	if !errors.Is(err, ErrSessionExpired) {
+		return err
+	}
+
+	logger.Info().Msgf("Session expired for user %v, logging in again", c.username)
+
+	if err := c.Login(); err != nil {
+		return err
+	}
+
+	if c.classID != "" {
+		if err := c.doClassAction(c.classID); err != nil {
+			return err
+		}
+	}
+
+	return fn()
+}
+
 // CloseConnections closes all connections on its transport.
 func (c *Client) CloseConnections() {
 	c.httpClient.CloseIdleConnections()
@@ -0,0 +1,356 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package fetch
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// rewriteTransport sends all requests to the test server, keeping the original request URL for redirects and cookies.
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	r.URL.Scheme = t.target.Scheme
+	r.URL.Host = t.target.Host
+
+	resp, err := http.DefaultTransport.RoundTrip(r)
+	if err == nil {
+		resp.Request = req
+	}
+
+	return resp, err
+}
+
+// newSessionServer returns a fake e-dnevnik server which expires the session on the first grades fetch, counting
+// login attempts.
+func newSessionServer(t *testing.T, logins *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	var gradeCalls atomic.Int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /login", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`<html><body><form><input name="csrf_token" value="token"></form></body></html>`))
+	})
+	mux.HandleFunc("POST /login", func(w http.ResponseWriter, _ *http.Request) {
+		logins.Add(1)
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "ok", Path: "/"})
+		_, _ = w.Write([]byte(`<html></html>`))
+	})
+	mux.HandleFunc("GET /class_action/{id}/course", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`<html></html>`))
+	})
+	mux.HandleFunc("GET /grade/all", func(w http.ResponseWriter, r *http.Request) {
+		if gradeCalls.Add(1) == 1 {
+			http.Redirect(w, r, "/login", http.StatusFound)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`<html>grades</html>`))
+	})
+	mux.HandleFunc("GET /absent", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`<html>absences</html>`))
+	})
+	mux.HandleFunc("GET /notes", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`<html>notes</html>`))
+	})
+	mux.HandleFunc("GET /exam/ical", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nEND:VCALENDAR\r\n"))
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestGetClassEventsRelogin(t *testing.T) {
+	var logins atomic.Int32
+
+	srv := newSessionServer(t, &logins)
+
+	target, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewClientWithContext(context.Background(), "korisnik@skole.hr", "lozinka", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.httpClient.Transport = rewriteTransport{target: target}
+
+	if err := c.Login(); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	rawGrades, _, err := c.GetClassEvents("1")
+	if err != nil {
+		t.Fatalf("GetClassEvents() error = %v", err)
+	}
+
+	rawAbsences, err := c.GetAbsences()
+	if err != nil {
+		t.Fatalf("GetAbsences() error = %v", err)
+	}
+
+	if rawGrades != "<html>grades</html>" || rawAbsences != "<html>absences</html>" {
+		t.Errorf("unexpected grades %q or absences %q", rawGrades, rawAbsences)
+	}
+
+	if n := logins.Load(); n != 2 {
+		t.Errorf("expected a single re-login, got %d logins", n)
+	}
+}
+
+func TestGetAbsencesNotesMissingPage(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(srv.Close)
+
+	target, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewClientWithContext(context.Background(), "korisnik@skole.hr", "lozinka", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.httpClient.Transport = rewriteTransport{target: target}
+
+	rawAbsences, err := c.GetAbsences()
+	if err != nil || rawAbsences != "" {
+		t.Errorf("GetAbsences() with missing page = %q, %v, want empty body", rawAbsences, err)
+	}
+
+	rawNotes, err := c.GetNotes()
+	if err != nil || rawNotes != "" {
+		t.Errorf("GetNotes() with missing page = %q, %v, want empty body", rawNotes, err)
+	}
+}
+
+func TestGetClassEventsSessionExpired(t *testing.T) {
+	var logins atomic.Int32
+
+	srv := newSessionServer(t, &logins)
+
+	target, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewClientWithContext(context.Background(), "korisnik@skole.hr", "lozinka", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.httpClient.Transport = rewriteTransport{target: target}
+
+	if _, err := c.getGrades(); !errors.Is(err, ErrSessionExpired) {
+		t.Errorf("expected session expired error, got %v", err)
+	}
+}
+
+func TestLoginUserAgent(t *testing.T) {
+	const ua = "e-dnevnik-test/1.0"
+
+	var got []string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.UserAgent())
+		_, _ = w.Write([]byte(`<html><body><form><input name="csrf_token" value="token"></form></body></html>`))
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	target, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewClientWithContext(context.Background(), "korisnik@skole.hr", "lozinka", Options{UserAgent: ua})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.httpClient.Transport = rewriteTransport{target: target}
+
+	if err := c.Login(); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	if len(got) != 2 || got[0] != ua || got[1] != ua {
+		t.Errorf("expected pinned User-Agent %q in all login requests, got %q", ua, got)
+	}
+}
+
+// writePEM writes a single PEM block to a file in the test directory, returning its path.
+func writePEM(t *testing.T, name, blockType string, der []byte) string {
+	t.Helper()
+
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func TestLoadTLSCABundle(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(srv.Close)
+
+	get := func(opts Options) error {
+		t.Helper()
+
+		transport, err := newTransport(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := (&http.Client{Transport: transport}).Get(srv.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+
+		return err
+	}
+
+	if err := get(Options{}); err == nil {
+		t.Fatalf("request to a server with an untrusted certificate succeeded")
+	}
+
+	pool, certs, err := LoadTLS(writePEM(t, "ca.pem", "CERTIFICATE", srv.Certificate().Raw), "", "")
+	if err != nil {
+		t.Fatalf("LoadTLS() error = %v", err)
+	}
+
+	if certs != nil {
+		t.Errorf("LoadTLS() client certificates = %v, want none", len(certs))
+	}
+
+	if err := get(Options{RootCAs: pool}); err != nil {
+		t.Errorf("request with CA bundle error = %v", err)
+	}
+
+	if _, _, err := LoadTLS(writePEM(t, "bad.pem", "GARBAGE", []byte("x")), "", ""); !errors.Is(err, ErrInvalidCABundle) {
+		t.Errorf("LoadTLS() with invalid CA bundle error = %v, want %v", err, ErrInvalidCABundle)
+	}
+}
+
+func TestLoadTLSClientCert(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := writePEM(t, "client.pem", "CERTIFICATE", der)
+	keyFile := writePEM(t, "client.key", "EC PRIVATE KEY", keyDER)
+
+	pool, certs, err := LoadTLS("", certFile, keyFile)
+	if err != nil {
+		t.Fatalf("LoadTLS() error = %v", err)
+	}
+
+	if pool != nil || len(certs) != 1 {
+		t.Errorf("LoadTLS() = %v, %v certificates, want no pool and 1 certificate", pool, len(certs))
+	}
+
+	if _, _, err := LoadTLS("", certFile, certFile); !errors.Is(err, ErrInvalidClientCert) {
+		t.Errorf("LoadTLS() with invalid key error = %v, want %v", err, ErrInvalidClientCert)
+	}
+}
+
+func TestSiteUnavailable(t *testing.T) {
+	if err := statusError(http.StatusServiceUnavailable); !errors.Is(err, ErrSiteUnavailable) ||
+		!errors.Is(err, ErrUnexpectedStatus) {
+		t.Errorf("statusError(503) = %v, want %v", err, ErrSiteUnavailable)
+	}
+
+	if err := statusError(http.StatusForbidden); errors.Is(err, ErrSiteUnavailable) ||
+		!errors.Is(err, ErrUnexpectedStatus) {
+		t.Errorf("statusError(403) = %v, want %v", err, ErrUnexpectedStatus)
+	}
+
+	srv := httptest.NewServer(http.NotFoundHandler())
+	target, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv.Close()
+
+	c, err := NewClientWithContext(context.Background(), "korisnik@skole.hr", "lozinka", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.httpClient.Transport = rewriteTransport{target: target}
+
+	if _, err := c.getGrades(); !errors.Is(err, ErrSiteUnavailable) {
+		t.Errorf("getGrades() with unreachable site = %v, want %v", err, ErrSiteUnavailable)
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"path"
 	"strings"
 
 	"github.com/PuerkitoBio/goquery"
@@ -35,11 +36,38 @@ import (
 
 var (
 	ErrUnexpectedStatus = errors.New("unexpected status code")
-	ErrCSRFToken        = errors.New("could not find CSRF token")
+	ErrCSRF             = errors.New("could not find CSRF token")
 	ErrNilBody          = errors.New("client body is nil")
-	ErrInvalidLogin     = errors.New("unable to login")
+	ErrLogin            = errors.New("unable to login")
+	ErrSessionExpired   = errors.New("session expired, redirected to login")
+	ErrPageNotFound     = errors.New("page not found")
+	ErrSiteUnavailable  = errors.New("e-dnevnik site unavailable")
 )
 
+// statusError returns an error for an unexpected HTTP status code, marking the site as unavailable for server-side
+// errors and rate limiting.
+func statusError(code int) error {
+	if code >= http.StatusInternalServerError || code == http.StatusTooManyRequests {
+		return fmt.Errorf("%w: %w: %v", ErrSiteUnavailable, ErrUnexpectedStatus, code)
+	}
+
+	return fmt.Errorf("%w: %v", ErrUnexpectedStatus, code)
+}
+
+// isLoginRedirect reports if the response is a login page, ie. after an expired SSO session was redirected to /login.
+func isLoginRedirect(resp *http.Response) bool {
+	if resp.Request == nil || resp.Request.URL == nil {
+		return false
+	}
+
+	u, err := url.Parse(LoginURL)
+	if err != nil {
+		return false
+	}
+
+	return resp.Request.URL.Path == u.Path
+}
+
 // getCSRFToken extracts CSRF Token value hidden in the input form, optionally also getting initial value of cnOcjene
 // security cookie.
 func (c *Client) getCSRFToken() error {
@@ -58,7 +86,7 @@ func (c *Client) getCSRFToken() error {
 		case <-c.ctx.Done():
 			return c.ctx.Err()
 		default:
-			return err
+			return fmt.Errorf("%w: %w", ErrSiteUnavailable, err)
 		}
 	}
 
@@ -68,7 +96,7 @@ func (c *Client) getCSRFToken() error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("%w: %v", ErrUnexpectedStatus, resp.StatusCode)
+		return statusError(resp.StatusCode)
 	}
 
 	doc, err := goquery.NewDocumentFromReader(resp.Body)
@@ -88,7 +116,7 @@ func (c *Client) getCSRFToken() error {
 	io.Copy(io.Discard, resp.Body) //nolint:errcheck
 
 	if !csrfTokenExists {
-		return fmt.Errorf("%w", ErrCSRFToken)
+		return fmt.Errorf("%w", ErrCSRF)
 	}
 
 	return nil
@@ -126,7 +154,7 @@ func (c *Client) doSAMLRequest() error {
 		case <-c.ctx.Done():
 			return c.ctx.Err()
 		default:
-			return err
+			return fmt.Errorf("%w: %w", ErrSiteUnavailable, err)
 		}
 	}
 
@@ -143,7 +171,7 @@ func (c *Client) doSAMLRequest() error {
 	// check if this is a login error
 	alertSel := doc.FindMatcher(goquery.Single("#page-wrapper > div.flash-messages > div.alert > p"))
 	if alertSel.Length() > 0 {
-		return fmt.Errorf("%w: %v", ErrInvalidLogin, alertSel.Text())
+		return fmt.Errorf("%w: %v", ErrLogin, alertSel.Text())
 	}
 
 	// drain rest of the body
@@ -151,7 +179,7 @@ func (c *Client) doSAMLRequest() error {
 
 	// regular SSO response should have HTTP 302 status
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusFound {
-		return fmt.Errorf("%w: %v", ErrUnexpectedStatus, resp.StatusCode)
+		return statusError(resp.StatusCode)
 	}
 
 	return nil
@@ -180,7 +208,7 @@ func (c *Client) getGrades() (string, error) {
 		case <-c.ctx.Done():
 			return "", c.ctx.Err()
 		default:
-			return "", err
+			return "", fmt.Errorf("%w: %w", ErrSiteUnavailable, err)
 		}
 	}
 
@@ -189,8 +217,12 @@ func (c *Client) getGrades() (string, error) {
 	}
 	defer resp.Body.Close()
 
+	if isLoginRedirect(resp) {
+		return "", fmt.Errorf("%w", ErrSessionExpired)
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("%w: %v", ErrUnexpectedStatus, resp.StatusCode)
+		return "", statusError(resp.StatusCode)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -198,6 +230,72 @@ func (c *Client) getGrades() (string, error) {
 		return "", err
 	}
 
+	c.saveSnapshot("grades", ".html", string(body))
+
+	return string(body), nil
+}
+
+// getAbsences fetches all absences for the active class and returns them as raw body string.
+func (c *Client) getAbsences() (string, error) {
+	return c.getPage(AbsentURL)
+}
+
+// getNotes fetches all teacher notes for the active class and returns them as raw body string.
+func (c *Client) getNotes() (string, error) {
+	return c.getPage(NotesURL)
+}
+
+// getPage fetches a page for the active class and returns it as raw body string.
+func (c *Client) getPage(pageURL string) (string, error) {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Cache-Control", "no-cache")
+	req.Header.Set("Pragma", "no-cache")
+	req.Header.Set("Referer", LoginURL)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		select {
+		case <-c.ctx.Done():
+			return "", c.ctx.Err()
+		default:
+			return "", fmt.Errorf("%w: %w", ErrSiteUnavailable, err)
+		}
+	}
+
+	if resp == nil || resp.Body == nil {
+		return "", fmt.Errorf("%w", ErrNilBody)
+	}
+	defer resp.Body.Close()
+
+	if isLoginRedirect(resp) {
+		return "", fmt.Errorf("%w", ErrSessionExpired)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("%w: %v", ErrPageNotFound, pageURL)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", statusError(resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	c.saveSnapshot(path.Base(u.Path), ".html", string(body))
+
 	return string(body), nil
 }
 
@@ -224,7 +322,7 @@ func (c *Client) getCalendar() (Events, error) {
 		case <-c.ctx.Done():
 			return Events{}, c.ctx.Err()
 		default:
-			return Events{}, err
+			return Events{}, fmt.Errorf("%w: %w", ErrSiteUnavailable, err)
 		}
 	}
 
@@ -233,8 +331,12 @@ func (c *Client) getCalendar() (Events, error) {
 	}
 	defer resp.Body.Close()
 
+	if isLoginRedirect(resp) {
+		return Events{}, fmt.Errorf("%w", ErrSessionExpired)
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		return Events{}, fmt.Errorf("%w: %v", ErrUnexpectedStatus, resp.StatusCode)
+		return Events{}, statusError(resp.StatusCode)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -242,6 +344,8 @@ func (c *Client) getCalendar() (Events, error) {
 		return Events{}, err
 	}
 
+	c.saveSnapshot("exams", ".ics", string(body))
+
 	// decode ICS events
 	d := goics.NewDecoder(strings.NewReader(string(body)))
 	evs := Events{}
@@ -276,7 +380,7 @@ func (c *Client) getClasses() (string, error) {
 		case <-c.ctx.Done():
 			return "", c.ctx.Err()
 		default:
-			return "", err
+			return "", fmt.Errorf("%w: %w", ErrSiteUnavailable, err)
 		}
 	}
 
@@ -285,8 +389,12 @@ func (c *Client) getClasses() (string, error) {
 	}
 	defer resp.Body.Close()
 
+	if isLoginRedirect(resp) {
+		return "", fmt.Errorf("%w", ErrSessionExpired)
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("%w: %v", ErrUnexpectedStatus, resp.StatusCode)
+		return "", statusError(resp.StatusCode)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -294,6 +402,8 @@ func (c *Client) getClasses() (string, error) {
 		return "", err
 	}
 
+	c.saveSnapshot("classes", ".html", string(body))
+
 	return string(body), nil
 }
 
@@ -319,7 +429,7 @@ func (c *Client) doClassAction(classID string) error {
 		case <-c.ctx.Done():
 			return c.ctx.Err()
 		default:
-			return err
+			return fmt.Errorf("%w: %w", ErrSiteUnavailable, err)
 		}
 	}
 
@@ -328,9 +438,13 @@ func (c *Client) doClassAction(classID string) error {
 	}
 	defer resp.Body.Close()
 
+	if isLoginRedirect(resp) {
+		return fmt.Errorf("%w", ErrSessionExpired)
+	}
+
 	// regular /class_action responses are HTTP 200 or HTTP 302 with redirect to /course
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusFound {
-		return fmt.Errorf("%w: %v", ErrUnexpectedStatus, resp.StatusCode)
+		return statusError(resp.StatusCode)
 	}
 
 	// drain rest of the body
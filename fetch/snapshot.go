@@ -0,0 +1,84 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package fetch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dkorunic/e-dnevnik-bot/logger"
+)
+
+const (
+	SnapshotDirMode   = 0o700 // snapshot directory permissions, snapshots contain personal data
+	SnapshotFileMode  = 0o600 // snapshot file permissions
+	snapshotTimestamp = "20060102-150405.000"
+)
+
+// saveSnapshot writes raw response body to a timestamped file per user and active class in the snapshot directory,
+// if enabled. Failures are only logged, as snapshots are a debugging aid and should never break scraping.
+func (c *Client) saveSnapshot(name, ext, body string) {
+	if c.saveDir == "" {
+		return
+	}
+
+	class := c.classID
+	if class == "" {
+		class = "all"
+	}
+
+	file := strings.Join([]string{
+		snapshotName(c.username),
+		snapshotName(class),
+		name,
+		time.Now().Format(snapshotTimestamp),
+	}, "_") + ext
+
+	if err := os.MkdirAll(c.saveDir, SnapshotDirMode); err != nil {
+		logger.Warn().Msgf("Unable to create snapshot directory %v: %v", c.saveDir, err)
+
+		return
+	}
+
+	path := filepath.Join(c.saveDir, file)
+	if err := os.WriteFile(path, []byte(body), SnapshotFileMode); err != nil {
+		logger.Warn().Msgf("Unable to save snapshot %v: %v", path, err)
+
+		return
+	}
+
+	logger.Debug().Msgf("Saved snapshot %v", path)
+}
+
+// snapshotName replaces characters not safe for file names.
+func snapshotName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|', ' ':
+			return '-'
+		default:
+			return r
+		}
+	}, s)
+}
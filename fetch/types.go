@@ -23,6 +23,8 @@ package fetch
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"net/http"
 	"net/url"
 	"time"
@@ -39,6 +41,21 @@ type Client struct {
 	password   string
 	csrfToken  string
 	userAgent  string
+	saveDir    string
+	classID    string
+	pinnedUA   string
+}
+
+// Options structure holds optional HTTP client settings.
+type Options struct {
+	Proxy         string            // proxy URL overriding proxy settings from the environment
+	Timeout       time.Duration     // per-request timeout (default is Timeout)
+	MaxIdleConns  int               // maximum idle (keep-alive) connections (default is net/http default)
+	TLSMinVersion uint16            // minimum TLS version (default is net/http default)
+	SaveDir       string            // directory to save raw response bodies to, for debugging (empty is disabled)
+	UserAgent     string            // fixed User-Agent (default is random per session)
+	RootCAs       *x509.CertPool    // trusted root CAs (default is system root CAs)
+	Certificates  []tls.Certificate // client certificates (default is none)
 }
 
 // Event structure holds ICS event-related fields.
@@ -52,10 +69,11 @@ type Events []Event
 
 // Class structure holds all active classes.
 type Class struct {
-	ID     string
-	Name   string
-	Year   string
-	School string
+	ID      string
+	Name    string
+	Year    string
+	School  string
+	Student string
 }
 
 // Classes is a slice of Class structure.
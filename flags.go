@@ -23,29 +23,59 @@ package main
 
 import (
 	"fmt"
+	"math"
 	"os"
+	"runtime"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/dkorunic/e-dnevnik-bot/db"
+	"github.com/dkorunic/e-dnevnik-bot/fetch"
 	"github.com/dkorunic/e-dnevnik-bot/logger"
+	"github.com/dkorunic/e-dnevnik-bot/messenger"
+	"github.com/dustin/go-humanize"
+	"github.com/goccy/go-json"
 	"github.com/peterbourgon/ff/v4"
 	"github.com/peterbourgon/ff/v4/ffhelp"
 )
 
 const (
-	DefaultConfFile      = ".e-dnevnik.toml"     // default configuration filename
-	DefaultCalendarToken = "calendar_token.json" // default Google Calendar token file
-	DefaultTickInterval  = 1 * time.Hour         // default (and minimal permitted value) is 1 tick per 1h
-	DefaultRetries       = 3                     // default retry attempts
+	DefaultConfFile        = ".e-dnevnik.toml"     // default configuration filename
+	DefaultCalendarToken   = "calendar_token.json" // default Google Calendar token file
+	DefaultMailToken       = "mail_token.json"     // default mail OAuth2 token file
+	DefaultTickInterval    = 1 * time.Hour         // default (and minimal permitted value) is 1 tick per 1h
+	DefaultRetries         = 3                     // default retry attempts
+	DefaultConcurrency     = 2                     // default number of concurrently scraped classes per user
+	DefaultUserConcurrency = 4                     // default number of concurrently scraped users
+	DefaultRetryDelay      = 1 * time.Second       // default base delay between scrape retries
+	MinFastTickInterval    = 1 * time.Minute       // minimal permitted poll interval with fast polling enabled
+	DefaultMemRatio        = 0.9                   // default GOMEMLIMIT ratio of available memory
+	MinMemLimit            = 16 * 1024 * 1024      // minimal permitted absolute GOMEMLIMIT (16 MiB)
 )
 
+// versionInfo holds machine-readable program version printed with --version-json.
+type versionInfo struct {
+	GitTag    string `json:"gitTag"`
+	GitCommit string `json:"gitCommit"`
+	GitDirty  string `json:"gitDirty"`
+	BuildTime string `json:"buildTime"`
+	GoVersion string `json:"goVersion"`
+}
+
 var (
-	debug, debugEvents, daemon, help, emulation, colorLogs, version *bool
-	confFile, dbFile, cpuProfile, memProfile, calTokFile            *string
-	tickInterval, relevancePeriod                                   *time.Duration
-	retries                                                         *uint
+	debug, debugEvents, daemon, help, emulation, colorLogs, jsonLogs, version, versionJSON, dbCheck, dbRepair, dryRun, enrollment, schedule, dumpDB, dbStats, encryptConf, fastPoll, checkLogin, seedAndSend *bool
+	confFile, dbFile, cpuProfile, memProfile, calTokFile, metricsAddr, healthAddr, keyFile, profilesDir, exportDB, importDB, saveHTML, memoryLimit, statusFile, triggerAddr, otlpEndpoint                    *string
+	tickInterval, relevancePeriod, renotifyInterval, dbTTL, retryDelay, fetchTimeout, gradeCutoff                                                                                                            *time.Duration
+	memoryRatio                                                                                                                                                                                              *float64
+	retries                                                                                                                                                                                                  *uint
+	classConcurrency, userConcurrency, breakerThreshold, remindDays                                                                                                                                          *int
+	onlyMessengers, skipMessengers                                                                                                                                                                           *[]string
 )
 
+// memLimitBytes holds parsed absolute GOMEMLIMIT (0 is disabled).
+var memLimitBytes uint64
+
 // parseFlags parses the command line flags and sets the corresponding variables.
 func parseFlags() {
 	fs := ff.NewFlagSet("e-dnevnik-bot")
@@ -55,19 +85,55 @@ func parseFlags() {
 	daemon = fs.Bool('d', "daemon", "enable daemon mode (running as a service)")
 	help = fs.Bool('?', "help", "display help")
 	emulation = fs.Bool('t', "test", "send a test event (to check if messaging works)")
+	dryRun = fs.BoolLong("dry-run", "scrape and log alerts that would be sent, without sending or recording them")
 	colorLogs = fs.Bool('l', "colorlogs", "enable colorized console logs")
+	jsonLogs = fs.BoolLong("json-logs", "enable structured JSON logs with caller information (for log aggregation)")
 	version = fs.BoolLong("version", "display program version")
+	versionJSON = fs.BoolLong("version-json", "display program version in JSON (for automation)")
+	dbCheck = fs.BoolLong("db-check", "verify alert database integrity on startup")
+	enrollment = fs.BoolLong("enrollment", "alert on active class (enrollment) changes")
+	schedule = fs.BoolLong("schedule", "alert on weekly class timetable changes")
+	checkLogin = fs.BoolLong("check-login", "verify e-dnevnik login for all users and exit")
+	dumpDB = fs.BoolLong("dump-db", "print alert database contents and exit")
+	dbStats = fs.BoolLong("db-stats", "print alert database size and key counts and exit")
+	dbRepair = fs.BoolLong("db-repair", "back up and recreate alert database if corrupted (implies --db-check)")
+	fastPoll = fs.BoolLong("allow-fast-poll", "permit poll interval below 1h (for testing only)")
+	encryptConf = fs.BoolLong("encrypt-config", "encrypt secrets in configuration file and exit")
+	seedAndSend = fs.BoolLong("seed-and-send", "send alerts for all current events on a newly initialized database")
 
-	confFile = fs.String('f', "conffile", DefaultConfFile, "configuration file (in TOML)")
+	confFile = fs.String('f', "conffile", DefaultConfFile, "configuration file (in TOML, - for standard input)")
 	dbFile = fs.String('b', "database", db.DefaultDBPath, "alert database file")
 	calTokFile = fs.String('g', "calendartoken", DefaultCalendarToken, "Google Calendar token file")
 	cpuProfile = fs.String('c', "cpuprofile", "", "CPU profile output file")
 	memProfile = fs.String('m', "memprofile", "", "memory profile output file")
+	metricsAddr = fs.StringLong("metrics-addr", "", "Prometheus metrics listen address (ie. :9090)")
+	profilesDir = fs.StringLong("profiles", "", "directory with configuration profiles (*.toml), each with its own database and calendar token")
+	exportDB = fs.StringLong("export-db", "", "export alert database to JSON file and exit")
+	importDB = fs.StringLong("import-db", "", "import alert database entries from JSON file and exit")
+	keyFile = fs.StringLong("key-file", "", "configuration secrets key file (overrides "+SecretKeyEnv+" environment variable)")
+	saveHTML = fs.StringLong("save-html", "", "directory to save fetched raw pages to (for debugging parse failures)")
+	healthAddr = fs.StringLong("health-addr", "", "health check listen address for /healthz and /readyz (ie. :8080)")
+	triggerAddr = fs.StringLong("trigger-addr", "", "on-demand scrape listen address for POST /scrape (ie. :8081)")
+	otlpEndpoint = fs.StringLong("otlp-endpoint", "", "OTLP/HTTP endpoint URL to send scrape cycle traces to (ie. http://localhost:4318)")
+	statusFile = fs.StringLong("status-file", "", "JSON file to write the last run status to (for external monitoring)")
+	onlyMessengers = fs.StringSetLong("only-messenger", "send alerts only through this messenger, overriding configuration (repeatable)")
+	skipMessengers = fs.StringSetLong("skip-messenger", "never send alerts through this messenger, overriding configuration (repeatable)")
 
 	tickInterval = fs.Duration('i', "interval", DefaultTickInterval, "interval between polls when in daemon mode")
 	relevancePeriod = fs.Duration('p', "relevance", 0, "maximum relevance period for events (0 = unlimited)")
+	gradeCutoff = fs.DurationLong("grade-cutoff", 0, "skip grades older than this period already when parsing (0 = disabled)")
+	dbTTL = fs.DurationLong("db-ttl", db.DefaultTTL, "retention period of alerts in alert database")
+	renotifyInterval = fs.DurationLong("renotify", 0, "re-notification interval for upcoming exams (0 = disabled)")
+	remindDays = fs.IntLong("remind-days", 0, "send a one-time reminder of upcoming exams this many days before (0 = disabled)")
 
 	retries = fs.Uint('r', "retries", DefaultRetries, "number of retry attempts on error")
+	retryDelay = fs.DurationLong("retry-delay", DefaultRetryDelay, "base delay between scrape retries (exponential backoff with jitter)")
+	fetchTimeout = fs.DurationLong("fetch-timeout", fetch.Timeout, "e-dnevnik HTTP request timeout")
+	classConcurrency = fs.IntLong("class-concurrency", DefaultConcurrency, "number of concurrently scraped classes per user")
+	userConcurrency = fs.IntLong("user-concurrency", DefaultUserConcurrency, "number of concurrently scraped users")
+	memoryRatio = fs.Float64Long("mem-ratio", DefaultMemRatio, "GOMEMLIMIT ratio of available memory (0.0-1.0]")
+	memoryLimit = fs.StringLong("mem-limit", "", "absolute GOMEMLIMIT (ie. 128MiB), overriding --mem-ratio")
+	breakerThreshold = fs.IntLong("breaker-threshold", messenger.DefaultBreakerThreshold, "consecutive send failures after which a messenger skips the rest of a run (0 = disabled)")
 
 	var err error
 
@@ -78,6 +144,11 @@ func parseFlags() {
 		os.Exit(1)
 	}
 
+	// full configuration from the environment, unless configuration file has been explicitly set
+	if f, ok := fs.GetFlag("conffile"); ok && !f.IsSet() && os.Getenv(ConfigEnv) != "" {
+		*confFile = configEnvFile
+	}
+
 	if *help {
 		fmt.Printf("%s\n", ffhelp.Flags(fs))
 
@@ -90,9 +161,136 @@ func parseFlags() {
 		os.Exit(0)
 	}
 
-	if *tickInterval < DefaultTickInterval {
+	if *versionJSON {
+		b, err := json.Marshal(versionInfo{
+			GitTag:    GitTag,
+			GitCommit: GitCommit,
+			GitDirty:  GitDirty,
+			BuildTime: BuildTime,
+			GoVersion: runtime.Version(),
+		})
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+
+			os.Exit(1)
+		}
+
+		fmt.Printf("%s\n", b)
+
+		os.Exit(0)
+	}
+
+	if *colorLogs && *jsonLogs {
+		fmt.Printf("%s\n", ffhelp.Flags(fs))
+		fmt.Printf("Error: colorized console logs and JSON logs are mutually exclusive\n")
+
+		os.Exit(1)
+	}
+
+	if *profilesDir != "" && (*dumpDB || *dbStats || *exportDB != "" || *importDB != "" || *encryptConf) {
+		fmt.Printf("%s\n", ffhelp.Flags(fs))
+		fmt.Printf("Error: --dump-db, --db-stats, --export-db, --import-db and --encrypt-config work on a single " +
+			"database and configuration file, use -b DIR/NAME.db or -f DIR/NAME.toml instead of --profiles\n")
+
+		os.Exit(1)
+	}
+
+	if *classConcurrency < 1 {
+		fmt.Printf("%s\n", ffhelp.Flags(fs))
+		fmt.Printf("Error: class concurrency has to be at least 1, got: %v\n", *classConcurrency)
+
+		os.Exit(1)
+	}
+
+	if *userConcurrency < 1 {
+		fmt.Printf("%s\n", ffhelp.Flags(fs))
+		fmt.Printf("Error: user concurrency has to be at least 1, got: %v\n", *userConcurrency)
+
+		os.Exit(1)
+	}
+
+	if *breakerThreshold < 0 {
+		fmt.Printf("%s\n", ffhelp.Flags(fs))
+		fmt.Printf("Error: circuit breaker threshold cannot be negative, got: %v\n", *breakerThreshold)
+
+		os.Exit(1)
+	}
+
+	if *remindDays < 0 {
+		fmt.Printf("%s\n", ffhelp.Flags(fs))
+		fmt.Printf("Error: exam reminder days cannot be negative, got: %v\n", *remindDays)
+
+		os.Exit(1)
+	}
+
+	for _, name := range slices.Concat(*onlyMessengers, *skipMessengers) {
+		if !slices.Contains(messengerNames, name) {
+			fmt.Printf("%s\n", ffhelp.Flags(fs))
+			fmt.Printf("Error: unknown messenger %v, supported are: %v\n", name, strings.Join(messengerNames, ", "))
+
+			os.Exit(1)
+		}
+	}
+
+	if *memoryRatio <= 0 || *memoryRatio > 1 {
+		fmt.Printf("%s\n", ffhelp.Flags(fs))
+		fmt.Printf("Error: memory ratio has to be in (0.0-1.0] range, got: %v\n", *memoryRatio)
+
+		os.Exit(1)
+	}
+
+	if *memoryLimit != "" {
+		memLimitBytes, err = humanize.ParseBytes(*memoryLimit)
+		if err != nil || memLimitBytes < MinMemLimit || memLimitBytes > math.MaxInt64 {
+			fmt.Printf("%s\n", ffhelp.Flags(fs))
+			fmt.Printf("Error: memory limit has to be at least %v, got: %v\n", humanize.IBytes(MinMemLimit), *memoryLimit)
+
+			os.Exit(1)
+		}
+	}
+
+	if *fetchTimeout <= 0 {
+		fmt.Printf("%s\n", ffhelp.Flags(fs))
+		fmt.Printf("Error: fetch timeout has to be positive, got: %v\n", *fetchTimeout)
+
+		os.Exit(1)
+	}
+
+	if *retryDelay <= 0 {
+		fmt.Printf("%s\n", ffhelp.Flags(fs))
+		fmt.Printf("Error: retry delay has to be positive, got: %v\n", *retryDelay)
+
+		os.Exit(1)
+	}
+
+	if *dbTTL <= 0 {
+		fmt.Printf("%s\n", ffhelp.Flags(fs))
+		fmt.Printf("Error: alert database TTL has to be positive, got: %v\n", *dbTTL)
+
+		os.Exit(1)
+	}
+
+	if *fastPoll && *tickInterval < DefaultTickInterval {
+		if *tickInterval < MinFastTickInterval {
+			logger.Info().Msgf("Poll interval is below %v, so I will default to %v", MinFastTickInterval, MinFastTickInterval)
+
+			*tickInterval = MinFastTickInterval
+		}
+
+		logger.Warn().Msgf("FAST POLLING ENABLED: polling every %v, use only for testing as it puts load on e-Dnevnik",
+			*tickInterval)
+	} else if *tickInterval < DefaultTickInterval {
 		logger.Info().Msgf("Poll interval is below %v, so I will default to %v", DefaultTickInterval, DefaultTickInterval)
 
 		*tickInterval = time.Hour
 	}
 }
+
+// minTickInterval returns the minimal permitted poll interval, which is lower only with fast polling enabled.
+func minTickInterval() time.Duration {
+	if *fastPoll {
+		return MinFastTickInterval
+	}
+
+	return DefaultTickInterval
+}
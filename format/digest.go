@@ -0,0 +1,142 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package format
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+)
+
+// DigestSubject is a subject of digest messages.
+const DigestSubject = "e-Dnevnik"
+
+// FamilyDigest formats messages of all users as a single cleartext digest, grouped by username and then by subject.
+func FamilyDigest(msgs map[string][]msgtypes.Message) string {
+	sb := &strings.Builder{}
+
+	for _, user := range slices.Sorted(maps.Keys(msgs)) {
+		// group user messages by subject, keeping the original order within a subject
+		bySubject := make(map[string][]msgtypes.Message)
+		for _, m := range msgs[user] {
+			bySubject[m.Subject] = append(bySubject[m.Subject], m)
+		}
+
+		if len(bySubject) == 0 {
+			continue
+		}
+
+		sb.WriteString(user)
+		sb.WriteString("\n\n")
+
+		for _, subject := range slices.Sorted(maps.Keys(bySubject)) {
+			sb.WriteString(subject)
+			sb.WriteString("\n")
+
+			for _, m := range bySubject[subject] {
+				sb.WriteString("- ")
+				sb.WriteString(plainPrefix(m.Code))
+				digestFormatFields(sb, m.Descriptions, m.Fields, m.PreviousFields)
+
+				if m.Average > 0 {
+					sb.WriteString(", ")
+					sb.WriteString(AverageLine(m.Average))
+				}
+
+				sb.WriteString("\n")
+			}
+
+			sb.WriteString("\n")
+		}
+	}
+
+	return sb.String()
+}
+
+// DigestMsg combines messages into a single digest message per user (sorted by username), holding one field per
+// message, grouped by subject. Field descriptions are subjects and field values are prefixed single line messages.
+func DigestMsg(msgs []msgtypes.Message) []msgtypes.Message {
+	byUser := make(map[string][]msgtypes.Message)
+	for _, m := range msgs {
+		byUser[m.Username] = append(byUser[m.Username], m)
+	}
+
+	digests := make([]msgtypes.Message, 0, len(byUser))
+
+	for _, user := range slices.Sorted(maps.Keys(byUser)) {
+		userMsgs := byUser[user]
+
+		// group by subject, keeping the original order within a subject
+		slices.SortStableFunc(userMsgs, func(a, b msgtypes.Message) int {
+			return strings.Compare(a.Subject, b.Subject)
+		})
+
+		d := msgtypes.Message{
+			Username: user,
+			Subject:  DigestSubject,
+			Code:     msgtypes.Digest,
+		}
+
+		for _, m := range userMsgs {
+			if d.Student == "" {
+				d.Student, d.School = m.Student, m.School
+			}
+
+			sb := &strings.Builder{}
+			sb.WriteString(plainPrefix(m.Code))
+			digestFormatFields(sb, m.Descriptions, m.Fields, m.PreviousFields)
+
+			if m.Average > 0 {
+				sb.WriteString(", ")
+				sb.WriteString(AverageLine(m.Average))
+			}
+
+			d.Descriptions = append(d.Descriptions, m.Subject)
+			d.Fields = append(d.Fields, sb.String())
+
+			if m.Timestamp.After(d.Timestamp) {
+				d.Timestamp = m.Timestamp
+			}
+		}
+
+		digests = append(digests, d)
+	}
+
+	return digests
+}
+
+// digestFormatFields formats descriptions and values in a single line.
+//
+//nolint:interfacer
+func digestFormatFields(sb *strings.Builder, descriptions, fields, previous []string) {
+	for i := range fields {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+
+		sb.WriteString(descriptions[i])
+		sb.WriteString(": ")
+		sb.WriteString(FieldValue(fields, previous, i))
+	}
+}
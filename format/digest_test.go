@@ -0,0 +1,130 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+)
+
+func TestFamilyDigest(t *testing.T) {
+	msgs := map[string][]msgtypes.Message{
+		"drugi@skole.hr": {
+			{
+				Username:     "drugi@skole.hr",
+				Subject:      "Matematika",
+				Code:         msgtypes.Exam,
+				Descriptions: []string{"Datum ispita", "Napomena"},
+				Fields:       []string{"10.01.2025.", "Pisana provjera"},
+			},
+		},
+		"prvi@skole.hr": {
+			{
+				Username:     "prvi@skole.hr",
+				Subject:      "Matematika",
+				Descriptions: []string{"Datum", "Ocjena"},
+				Fields:       []string{"2.1.", "5"},
+			},
+			{
+				Username:     "prvi@skole.hr",
+				Subject:      "Fizika",
+				Descriptions: []string{"Datum", "Ocjena"},
+				Fields:       []string{"3.1.", "4"},
+			},
+			{
+				Username:     "prvi@skole.hr",
+				Subject:      "Matematika",
+				Descriptions: []string{"Datum", "Ocjena"},
+				Fields:       []string{"4.1.", "3"},
+			},
+		},
+		"treci@skole.hr": {},
+	}
+
+	want := "drugi@skole.hr\n\n" +
+		"Matematika\n" +
+		"- " + EventPrefix + "Datum ispita: 10.01.2025., Napomena: Pisana provjera\n\n" +
+		"prvi@skole.hr\n\n" +
+		"Fizika\n" +
+		"- " + GradePrefix + "Datum: 3.1., Ocjena: 4\n\n" +
+		"Matematika\n" +
+		"- " + GradePrefix + "Datum: 2.1., Ocjena: 5\n" +
+		"- " + GradePrefix + "Datum: 4.1., Ocjena: 3\n\n"
+
+	if got := FamilyDigest(msgs); got != want {
+		t.Errorf("FamilyDigest() = %q, want %q", got, want)
+	}
+}
+
+func TestDigestMsg(t *testing.T) {
+	msgs := []msgtypes.Message{
+		{
+			Username:     "prvi@skole.hr",
+			Subject:      "Matematika",
+			Descriptions: []string{"Datum", "Ocjena"},
+			Fields:       []string{"2.1.", "5"},
+			Average:      4.5,
+		},
+		{
+			Username:     "drugi@skole.hr",
+			Subject:      "Matematika",
+			Code:         msgtypes.Exam,
+			Descriptions: []string{"Datum ispita", "Napomena"},
+			Fields:       []string{"10.01.2025.", "Pisana provjera"},
+		},
+		{
+			Username:     "prvi@skole.hr",
+			Subject:      "Fizika",
+			Descriptions: []string{"Datum", "Ocjena"},
+			Fields:       []string{"3.1.", "4"},
+		},
+	}
+
+	got := DigestMsg(msgs)
+	if len(got) != 2 || got[0].Username != "drugi@skole.hr" || got[1].Username != "prvi@skole.hr" {
+		t.Fatalf("DigestMsg() = %+v, want one digest per user sorted by username", got)
+	}
+
+	d := got[1]
+	if d.Code != msgtypes.Digest || d.Subject != DigestSubject {
+		t.Errorf("DigestMsg() code and subject = %v, %q", d.Code, d.Subject)
+	}
+
+	wantDesc := []string{"Fizika", "Matematika"}
+	wantFields := []string{
+		GradePrefix + "Datum: 3.1., Ocjena: 4",
+		GradePrefix + "Datum: 2.1., Ocjena: 5, " + AveragePrefix + "4.50",
+	}
+
+	for i := range wantDesc {
+		if d.Descriptions[i] != wantDesc[i] || d.Fields[i] != wantFields[i] {
+			t.Errorf("DigestMsg() field %d = %q: %q, want %q: %q", i, d.Descriptions[i], d.Fields[i], wantDesc[i],
+				wantFields[i])
+		}
+	}
+
+	if p := PlainMsg(d); !strings.HasPrefix(p, DigestPrefix+"prvi@skole.hr / "+DigestSubject) {
+		t.Errorf("PlainMsg() of digest = %q", p)
+	}
+}
@@ -22,25 +22,41 @@
 package format
 
 import (
+	"html"
 	"strings"
+
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
 )
 
-// HTMLMsg formats grade report as preformatted HTML block in a string.
-func HTMLMsg(username, subject string, isExam bool, descriptions, grade []string) string {
+// HTMLMsg formats grade report as preformatted HTML block in a string, using custom template (with escaped message
+// fields) if set.
+func HTMLMsg(g msgtypes.Message) string {
+	if s, ok := customMsg(g, true); ok {
+		return s
+	}
+
 	sb := &strings.Builder{}
 
-	htmlAddHeader(sb, username, subject, isExam)
+	htmlAddHeader(sb, g)
 
 	sb.WriteString("<pre>\n")
-	plainFormatGrades(sb, descriptions, grade)
+	plainFormatGrades(sb, g)
 	sb.WriteString("</pre>\n")
 
+	if g.URL != "" {
+		sb.WriteString(`<a href="`)
+		sb.WriteString(html.EscapeString(g.URL))
+		sb.WriteString(`">`)
+		sb.WriteString(strings.TrimSuffix(LinkPrefix, ": "))
+		sb.WriteString("</a>\n")
+	}
+
 	return sb.String()
 }
 
 // htmlAddHeader adds bold header containing username and subject name, and a delimiter.
-func htmlAddHeader(sb *strings.Builder, user, subject string, isExam bool) {
+func htmlAddHeader(sb *strings.Builder, g msgtypes.Message) {
 	sb.WriteString("<b>")
-	PlainFormatSubject(sb, user, subject, isExam)
+	PlainFormatSubject(sb, g)
 	sb.WriteString("</b>\n")
 }
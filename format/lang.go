@@ -0,0 +1,205 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package format
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+)
+
+const (
+	LangCroatian = "hr" // Croatian language (default)
+	LangEnglish  = "en" // English language
+)
+
+// ReminderKey is the prefix override key of reminders of already sent events.
+const ReminderKey = "reminder"
+
+var (
+	ErrUnknownLanguage = errors.New("unknown language, supported are hr and en")
+	ErrUnknownPrefix   = errors.New("unknown prefix event type")
+)
+
+// Strings holds localized message prefixes, subjects and separators.
+type Strings struct {
+	GradePrefix      string                        // grade title prefix
+	EventPrefix      string                        // exam title prefix
+	AbsencePrefix    string                        // absence title prefix
+	EnrollmentPrefix string                        // enrollment change title prefix
+	NotePrefix       string                        // teacher note title prefix
+	DigestPrefix     string                        // digest title prefix
+	SchedulePrefix   string                        // class timetable change title prefix
+	NationalPrefix   string                        // national exam result title prefix
+	ReminderPrefix   string                        // reminder of an already sent event title prefix
+	ChangedWas       string                        // edited field previous value prefix
+	ChangedNow       string                        // edited field current value prefix
+	AveragePrefix    string                        // subject grade average prefix
+	MailSubject      string                        // default mail subject
+	MailDigest       string                        // default family digest mail subject
+	CalendarExamSep  string                        // separator between username and subject in calendar event summary
+	SummaryKinds     map[msgtypes.EventCode]string // lowercase event type names in one-line summaries
+}
+
+var languages = map[string]Strings{
+	LangCroatian: {
+		GradePrefix:      GradePrefix,
+		EventPrefix:      EventPrefix,
+		AbsencePrefix:    AbsencePrefix,
+		EnrollmentPrefix: EnrollmentPrefix,
+		NotePrefix:       NotePrefix,
+		DigestPrefix:     DigestPrefix,
+		SchedulePrefix:   SchedulePrefix,
+		NationalPrefix:   NationalPrefix,
+		ReminderPrefix:   ReminderPrefix,
+		ChangedWas:       ChangedWas,
+		ChangedNow:       ChangedNow,
+		AveragePrefix:    AveragePrefix,
+		MailSubject:      "Nova ocjena iz e-Dnevnika",
+		MailDigest:       "Obiteljski sažetak iz e-Dnevnika",
+		CalendarExamSep:  " - Ispit iz: ",
+		SummaryKinds: map[msgtypes.EventCode]string{
+			msgtypes.Grade:            "ocjena",
+			msgtypes.Exam:             "ispit",
+			msgtypes.Absence:          "izostanak",
+			msgtypes.EnrollmentChange: "promjena upisa",
+			msgtypes.Note:             "bilješka",
+			msgtypes.Digest:           "sažetak",
+			msgtypes.Schedule:         "promjena rasporeda",
+			msgtypes.NationalExam:     "nacionalni ispit",
+		},
+	},
+	LangEnglish: {
+		GradePrefix:      "New grade: ",
+		EventPrefix:      "⚠ EXAM SCHEDULED: ",
+		AbsencePrefix:    "New absence: ",
+		EnrollmentPrefix: "Enrollment change: ",
+		NotePrefix:       "New note: ",
+		DigestPrefix:     "Digest: ",
+		SchedulePrefix:   "Schedule change: ",
+		NationalPrefix:   "National exam: ",
+		ReminderPrefix:   "⏰ REMINDER: ",
+		ChangedWas:       "was ",
+		ChangedNow:       ", now ",
+		AveragePrefix:    "current average: ",
+		MailSubject:      "New grade from e-Dnevnik",
+		MailDigest:       "Family digest from e-Dnevnik",
+		CalendarExamSep:  " - Exam in: ",
+		SummaryKinds: map[msgtypes.EventCode]string{
+			msgtypes.Grade:            "grade",
+			msgtypes.Exam:             "exam",
+			msgtypes.Absence:          "absence",
+			msgtypes.EnrollmentChange: "enrollment change",
+			msgtypes.Note:             "note",
+			msgtypes.Digest:           "digest",
+			msgtypes.Schedule:         "schedule change",
+			msgtypes.NationalExam:     "national exam",
+		},
+	},
+}
+
+var current = languages[LangCroatian]
+
+// SetLanguage sets the language of all formatted messages, defaulting to Croatian if empty.
+func SetLanguage(lang string) error {
+	s, err := Localize(lang, nil)
+	if err != nil {
+		return err
+	}
+
+	current = s
+
+	return nil
+}
+
+// SetPrefixes overrides title prefixes of the current language, keyed by event type name (grade, exam, absence,
+// enrollment, note, digest, schedule or national_exam) or reminder. It has to be called after SetLanguage.
+func SetPrefixes(prefixes map[string]string) error {
+	s := current
+	if err := overridePrefixes(&s, prefixes); err != nil {
+		return err
+	}
+
+	current = s
+
+	return nil
+}
+
+// Localize returns localized strings of the language (defaulting to Croatian if empty) with title prefixes
+// overridden as in SetPrefixes, without changing the language of formatted messages.
+func Localize(lang string, prefixes map[string]string) (Strings, error) {
+	if lang == "" {
+		lang = LangCroatian
+	}
+
+	s, ok := languages[lang]
+	if !ok {
+		return Strings{}, fmt.Errorf("%w: %v", ErrUnknownLanguage, lang)
+	}
+
+	if err := overridePrefixes(&s, prefixes); err != nil {
+		return Strings{}, err
+	}
+
+	return s, nil
+}
+
+// SetStrings sets localized strings of all formatted messages, as returned by Localize.
+func SetStrings(s Strings) {
+	current = s
+}
+
+// overridePrefixes overrides title prefixes of localized strings, keyed by event type name or reminder.
+func overridePrefixes(s *Strings, prefixes map[string]string) error {
+	for k, v := range prefixes {
+		switch k {
+		case msgtypes.Grade.String():
+			s.GradePrefix = v
+		case msgtypes.Exam.String():
+			s.EventPrefix = v
+		case msgtypes.Absence.String():
+			s.AbsencePrefix = v
+		case msgtypes.EnrollmentChange.String():
+			s.EnrollmentPrefix = v
+		case msgtypes.Note.String():
+			s.NotePrefix = v
+		case msgtypes.Digest.String():
+			s.DigestPrefix = v
+		case msgtypes.Schedule.String():
+			s.SchedulePrefix = v
+		case msgtypes.NationalExam.String():
+			s.NationalPrefix = v
+		case ReminderKey:
+			s.ReminderPrefix = v
+		default:
+			return fmt.Errorf("%w: %v", ErrUnknownPrefix, k)
+		}
+	}
+
+	return nil
+}
+
+// Lang returns localized strings for the current language.
+func Lang() Strings {
+	return current
+}
@@ -0,0 +1,103 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package format
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+)
+
+func TestSetLanguage(t *testing.T) {
+	t.Cleanup(func() { _ = SetLanguage(LangCroatian) })
+
+	if err := SetLanguage("de"); !errors.Is(err, ErrUnknownLanguage) {
+		t.Fatalf("SetLanguage() with unknown language = %v, want %v", err, ErrUnknownLanguage)
+	}
+
+	if err := SetLanguage(LangEnglish); err != nil {
+		t.Fatalf("SetLanguage() = %v", err)
+	}
+
+	got := PlainMsg(msgtypes.Message{
+		Username:       "korisnik@skole.hr",
+		Subject:        "Matematika",
+		Code:           msgtypes.Grade,
+		Descriptions:   []string{"Ocjena"},
+		Fields:         []string{"4"},
+		PreviousFields: []string{"5"},
+	})
+	if !strings.HasPrefix(got, "New grade: ") || !strings.Contains(got, "was 5, now 4") {
+		t.Errorf("PlainMsg() in English = %q", got)
+	}
+
+	if err := SetLanguage(""); err != nil || Lang().GradePrefix != GradePrefix {
+		t.Errorf("SetLanguage() with empty language did not default to Croatian: %v", err)
+	}
+}
+
+func TestSetPrefixes(t *testing.T) {
+	t.Cleanup(func() { _ = SetLanguage(LangCroatian) })
+
+	if err := SetPrefixes(map[string]string{"homework": "📓 "}); !errors.Is(err, ErrUnknownPrefix) {
+		t.Fatalf("SetPrefixes() with unknown event type = %v, want %v", err, ErrUnknownPrefix)
+	}
+
+	if err := SetPrefixes(map[string]string{"exam": "Ispit: ", ReminderKey: ""}); err != nil {
+		t.Fatalf("SetPrefixes() = %v", err)
+	}
+
+	g := msgtypes.Message{Username: "korisnik@skole.hr", Subject: "Matematika", Code: msgtypes.Exam, Reminder: true}
+
+	if got, want := MarkupMsg(g), "Ispit: korisnik@skole.hr / Matematika"; !strings.Contains(got, want) {
+		t.Errorf("MarkupMsg() = %q, want %q", got, want)
+	}
+
+	if Lang().GradePrefix != GradePrefix {
+		t.Errorf("SetPrefixes() changed grade prefix to %q", Lang().GradePrefix)
+	}
+
+	if err := SetLanguage(LangCroatian); err != nil || Lang().EventPrefix != EventPrefix {
+		t.Errorf("SetLanguage() did not restore default prefixes: %v", err)
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	if _, err := Localize(LangEnglish, map[string]string{"homework": "📓 "}); !errors.Is(err, ErrUnknownPrefix) {
+		t.Fatalf("Localize() with unknown event type = %v, want %v", err, ErrUnknownPrefix)
+	}
+
+	s, err := Localize(LangEnglish, map[string]string{"grade": "Grade: "})
+	if err != nil {
+		t.Fatalf("Localize() = %v", err)
+	}
+
+	if s.GradePrefix != "Grade: " || s.MailSubject != languages[LangEnglish].MailSubject {
+		t.Errorf("Localize() = %+v", s)
+	}
+
+	if Lang().GradePrefix != GradePrefix {
+		t.Errorf("Localize() changed current grade prefix to %q", Lang().GradePrefix)
+	}
+}
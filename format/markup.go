@@ -23,24 +23,31 @@ package format
 
 import (
 	"strings"
+
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
 )
 
-// MarkupMsg formats grade report as preformatted Markup block in a string.
-func MarkupMsg(username, subject string, isExam bool, descriptions, grade []string) string {
+// MarkupMsg formats grade report as preformatted Markup block in a string, using custom template if set.
+func MarkupMsg(g msgtypes.Message) string {
+	if s, ok := customMsg(g, false); ok {
+		return s
+	}
+
 	sb := &strings.Builder{}
 
-	markupAddHeader(sb, username, subject, isExam)
+	markupAddHeader(sb, g)
 
 	sb.WriteString("```\n")
-	plainFormatGrades(sb, descriptions, grade)
+	plainFormatGrades(sb, g)
 	sb.WriteString("```\n")
+	plainAddLink(sb, g.URL)
 
 	return sb.String()
 }
 
 // markupAddHeader adds Markup bold header containing username and subject name, and a delimiter.
-func markupAddHeader(sb *strings.Builder, user, subject string, isExam bool) {
+func markupAddHeader(sb *strings.Builder, g msgtypes.Message) {
 	sb.WriteString("*")
-	PlainFormatSubject(sb, user, subject, isExam)
+	PlainFormatSubject(sb, g)
 	sb.WriteString("*\n\n")
 }
@@ -22,54 +22,142 @@
 package format
 
 import (
+	"strconv"
 	"strings"
+
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
 )
 
+// Croatian (default) message prefixes.
 const (
-	GradePrefix = "Nova ocjena: "       // grade title prefix
-	EventPrefix = "⚠ NAJAVLJEN ISPIT: " // exam title prefix
+	GradePrefix      = "Nova ocjena: "        // grade title prefix
+	EventPrefix      = "⚠ NAJAVLJEN ISPIT: "  // exam title prefix
+	AbsencePrefix    = "Novi izostanak: "     // absence title prefix
+	EnrollmentPrefix = "Promjena upisa: "     // enrollment change title prefix
+	ChangedWas       = "bilo "                // edited field previous value prefix
+	ChangedNow       = ", sada "              // edited field current value prefix
+	AveragePrefix    = "trenutni prosjek: "   // subject grade average prefix
+	NotePrefix       = "Nova bilješka: "      // teacher note title prefix
+	DigestPrefix     = "Sažetak: "            // digest title prefix
+	SchedulePrefix   = "Promjena rasporeda: " // class timetable change title prefix
+	NationalPrefix   = "Nacionalni ispit: "   // national exam result title prefix
+	ReminderPrefix   = "⏰ PODSJETNIK: "       // reminder of an already sent event title prefix
 )
 
-// PlainMsg formats grade report as cleartext block in a string.
-func PlainMsg(username, subject string, isExam bool, descriptions, grade []string) string {
+// LinkPrefix is e-dnevnik site link prefix.
+const LinkPrefix = "e-Dnevnik: "
+
+// PlainMsg formats grade report as cleartext block in a string, using custom template if set.
+func PlainMsg(g msgtypes.Message) string {
+	if s, ok := customMsg(g, false); ok {
+		return s
+	}
+
 	sb := &strings.Builder{}
 
-	plainAddHeader(sb, username, subject, isExam)
-	plainFormatGrades(sb, descriptions, grade)
+	plainAddHeader(sb, g)
+	plainFormatGrades(sb, g)
+	plainAddLink(sb, g.URL)
 
 	return sb.String()
 }
 
-// plainFormatGrades formats grade descriptions and values.
+// plainFormatGrades formats grade descriptions and values, showing previous values of edited fields and subject
+// grade average if known.
 //
 //nolint:interfacer
-func plainFormatGrades(sb *strings.Builder, descriptions, grade []string) {
-	for i := range grade {
+func plainFormatGrades(sb *strings.Builder, g msgtypes.Message) {
+	for i := range g.Fields {
 		// grade listing will print scraped corresponding descriptions
-		sb.WriteString(descriptions[i])
+		sb.WriteString(g.Descriptions[i])
 		sb.WriteString(": ")
-		sb.WriteString(grade[i])
+		sb.WriteString(FieldValue(g.Fields, g.PreviousFields, i))
+		sb.WriteString("\n")
+	}
+
+	if g.Average > 0 {
+		sb.WriteString(AverageLine(g.Average))
 		sb.WriteString("\n")
 	}
 }
 
-// PlainFormatSubject adds cleartext header containing prefix (event/grade), username and subject.
+// AverageLine formats subject grade average, returning an empty string if the average is unknown.
+func AverageLine(average float64) string {
+	if average <= 0 {
+		return ""
+	}
+
+	return current.AveragePrefix + strconv.FormatFloat(average, 'f', 2, 64)
+}
+
+// FieldValue returns i-th field value, or both previous and current value if the field has been edited.
+func FieldValue(fields, previous []string, i int) string {
+	if i >= len(previous) || previous[i] == fields[i] {
+		return fields[i]
+	}
+
+	return current.ChangedWas + previous[i] + current.ChangedNow + fields[i]
+}
+
+// DisplayName returns student full name (scraped or configured) if known, or username otherwise.
+func DisplayName(g msgtypes.Message) string {
+	if g.Student != "" {
+		return g.Student
+	}
+
+	return g.Username
+}
+
+// PlainFormatSubject adds cleartext header containing prefix (event/grade/absence/enrollment/note/digest/schedule), user name and
+// subject, with reminders of already sent events getting an additional reminder prefix.
 //
 //nolint:interfacer
-func PlainFormatSubject(sb *strings.Builder, user, subject string, isExam bool) {
-	if isExam {
-		sb.WriteString(EventPrefix)
-	} else {
-		sb.WriteString(GradePrefix)
+func PlainFormatSubject(sb *strings.Builder, g msgtypes.Message) {
+	if g.Reminder {
+		sb.WriteString(current.ReminderPrefix)
 	}
 
-	sb.WriteString(user)
+	sb.WriteString(plainPrefix(g.Code))
+	sb.WriteString(DisplayName(g))
 	sb.WriteString(" / ")
-	sb.WriteString(subject)
+	sb.WriteString(g.Subject)
+}
+
+// plainAddLink adds a link to e-dnevnik site, if known.
+func plainAddLink(sb *strings.Builder, link string) {
+	if link == "" {
+		return
+	}
+
+	sb.WriteString(LinkPrefix)
+	sb.WriteString(link)
+	sb.WriteString("\n")
 }
 
 // plainAddHeader adds cleartext header containing username and subject name, and a delimiter.
-func plainAddHeader(sb *strings.Builder, user, subject string, isExam bool) {
-	PlainFormatSubject(sb, user, subject, isExam)
+func plainAddHeader(sb *strings.Builder, g msgtypes.Message) {
+	PlainFormatSubject(sb, g)
 	sb.WriteString("\n\n")
 }
+
+// plainPrefix returns localized title prefix for the event type.
+func plainPrefix(code msgtypes.EventCode) string {
+	switch code {
+	case msgtypes.Exam:
+		return current.EventPrefix
+	case msgtypes.Absence:
+		return current.AbsencePrefix
+	case msgtypes.EnrollmentChange:
+		return current.EnrollmentPrefix
+	case msgtypes.Note:
+		return current.NotePrefix
+	case msgtypes.Digest:
+		return current.DigestPrefix
+	case msgtypes.Schedule:
+		return current.SchedulePrefix
+	case msgtypes.NationalExam:
+		return current.NationalPrefix
+	default:
+		return current.GradePrefix
+	}
+}
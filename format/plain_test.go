@@ -0,0 +1,120 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+)
+
+func TestPlainMsgEdited(t *testing.T) {
+	got := PlainMsg(msgtypes.Message{
+		Username:       "korisnik@skole.hr",
+		Subject:        "Matematika",
+		Code:           msgtypes.Grade,
+		Descriptions:   []string{"Datum", "Ocjena", "Bilješka"},
+		Fields:         []string{"2.1.", "4", "Usmeno"},
+		PreviousFields: []string{"2.1.", "5", "Usmeno"},
+		Average:        4.333,
+	})
+
+	want := "Nova ocjena: korisnik@skole.hr / Matematika\n\n" +
+		"Datum: 2.1.\n" +
+		"Ocjena: bilo 5, sada 4\n" +
+		"Bilješka: Usmeno\n" +
+		"trenutni prosjek: 4.33\n"
+
+	if got != want {
+		t.Errorf("PlainMsg() = %q, want %q", got, want)
+	}
+
+	if got := FieldValue([]string{"2.1.", "4"}, nil, 1); got != "4" {
+		t.Errorf("FieldValue() without previous fields = %q, want %q", got, "4")
+	}
+}
+
+func TestPlainMsgStudent(t *testing.T) {
+	got := PlainMsg(msgtypes.Message{
+		Username:     "korisnik@skole.hr",
+		Student:      "Ana Anić",
+		School:       "Osnovna škola Zagreb",
+		Subject:      "Matematika",
+		Code:         msgtypes.Grade,
+		Descriptions: []string{"Ocjena"},
+		Fields:       []string{"5"},
+	})
+
+	want := "Nova ocjena: Ana Anić / Matematika\n\nOcjena: 5\n"
+
+	if got != want {
+		t.Errorf("PlainMsg() = %q, want %q", got, want)
+	}
+
+	if got := DisplayName(msgtypes.Message{Username: "korisnik@skole.hr"}); got != "korisnik@skole.hr" {
+		t.Errorf("DisplayName() without student = %q, want %q", got, "korisnik@skole.hr")
+	}
+}
+
+func TestPlainMsgReminder(t *testing.T) {
+	got := PlainMsg(msgtypes.Message{
+		Username:     "korisnik@skole.hr",
+		Subject:      "Matematika",
+		Code:         msgtypes.Exam,
+		Descriptions: []string{"Datum", "Opis"},
+		Fields:       []string{"10.01.2025.", "Pisana provjera"},
+		Reminder:     true,
+	})
+
+	want := ReminderPrefix + EventPrefix + "korisnik@skole.hr / Matematika\n\n" +
+		"Datum: 10.01.2025.\n" +
+		"Opis: Pisana provjera\n"
+
+	if got != want {
+		t.Errorf("PlainMsg() = %q, want %q", got, want)
+	}
+}
+
+func TestMsgLink(t *testing.T) {
+	g := msgtypes.Message{
+		Username:     "korisnik@skole.hr",
+		Subject:      "Matematika",
+		Code:         msgtypes.Grade,
+		Descriptions: []string{"Ocjena"},
+		Fields:       []string{"5"},
+		URL:          "https://ocjene.skole.hr/class_action/123/course",
+	}
+
+	want := "Nova ocjena: korisnik@skole.hr / Matematika\n\nOcjena: 5\n" +
+		"e-Dnevnik: https://ocjene.skole.hr/class_action/123/course\n"
+
+	if got := PlainMsg(g); got != want {
+		t.Errorf("PlainMsg() = %q, want %q", got, want)
+	}
+
+	wantHTML := `<a href="https://ocjene.skole.hr/class_action/123/course">e-Dnevnik</a>` + "\n"
+
+	if got := HTMLMsg(g); !strings.HasSuffix(got, wantHTML) {
+		t.Errorf("HTMLMsg() = %q, want suffix %q", got, wantHTML)
+	}
+}
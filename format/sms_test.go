@@ -0,0 +1,52 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package format
+
+import (
+	"testing"
+
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+)
+
+func TestSMSMsg(t *testing.T) {
+	g := msgtypes.Message{
+		Username:       "korisnik@skole.hr",
+		Subject:        "Matematika",
+		Code:           msgtypes.Grade,
+		Descriptions:   []string{"Datum", "Ocjena", "Bilješka"},
+		Fields:         []string{"2.1.", "4", "Usmeno\nodgovaranje"},
+		PreviousFields: []string{"2.1.", "5", "Usmeno\nodgovaranje"},
+		Average:        4.333,
+	}
+
+	want := "korisnik@skole.hr / Matematika: ocjena 4, Usmeno odgovaranje (2.1.)"
+
+	if got := SMSMsg(g, 0); got != want {
+		t.Errorf("SMSMsg() = %q, want %q", got, want)
+	}
+
+	want = "korisnik@skole.hr / Matematika: ocjena 4…"
+
+	if got := SMSMsg(g, 42); got != want {
+		t.Errorf("SMSMsg() truncated = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,91 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package format
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+)
+
+// summaryDate matches e-dnevnik dates (ie. 15.3. or 15.03.2025.) shown in parentheses in one-line summaries.
+var summaryDate = regexp.MustCompile(`^\d{1,2}\.\s*\d{1,2}\.(\s*\d{4}\.)?$`)
+
+// SummaryMsg formats an event as a compact single line of cleartext (ie. "Matematika: ocjena 5 (15.3.)"), with the
+// first date-like field shown in parentheses and the remaining non-empty fields listed after the event type. Username
+// is prepended as in message titles if not empty.
+func SummaryMsg(username, subject string, code msgtypes.EventCode, fields []string) string {
+	sb := &strings.Builder{}
+
+	if username != "" {
+		sb.WriteString(username)
+		sb.WriteString(" / ")
+	}
+
+	sb.WriteString(subject)
+	sb.WriteString(": ")
+	sb.WriteString(current.SummaryKinds[code])
+
+	var date string
+
+	values := make([]string, 0, len(fields))
+
+	for _, f := range fields {
+		// fields could contain line breaks
+		f = strings.Join(strings.Fields(f), " ")
+
+		switch {
+		case f == "" || f == subject:
+			continue
+		case date == "" && summaryDate.MatchString(f):
+			date = f
+		default:
+			values = append(values, f)
+		}
+	}
+
+	if len(values) > 0 {
+		sb.WriteString(" ")
+		sb.WriteString(strings.Join(values, ", "))
+	}
+
+	if date != "" {
+		sb.WriteString(" (")
+		sb.WriteString(date)
+		sb.WriteString(")")
+	}
+
+	return sb.String()
+}
+
+// SummaryTitle formats a message as a one-line summary for notification titles, with reminders of already sent
+// events getting an additional reminder prefix.
+func SummaryTitle(g msgtypes.Message) string {
+	s := SummaryMsg(DisplayName(g), g.Subject, g.Code, g.Fields)
+
+	if g.Reminder {
+		return current.ReminderPrefix + s
+	}
+
+	return s
+}
@@ -0,0 +1,107 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package format
+
+import (
+	"testing"
+
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+)
+
+func TestSummaryMsg(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		subject  string
+		code     msgtypes.EventCode
+		fields   []string
+		want     string
+	}{
+		{
+			name:    "grade",
+			subject: "Matematika",
+			code:    msgtypes.Grade,
+			fields:  []string{"15.3.", "5", ""},
+			want:    "Matematika: ocjena 5 (15.3.)",
+		},
+		{
+			name:     "grade with username and remark",
+			username: "korisnik@skole.hr",
+			subject:  "Matematika",
+			code:     msgtypes.Grade,
+			fields:   []string{"15.3.", "4", "Usmeno\nodgovaranje"},
+			want:     "korisnik@skole.hr / Matematika: ocjena 4, Usmeno odgovaranje (15.3.)",
+		},
+		{
+			name:    "exam repeating subject",
+			subject: "Fizika",
+			code:    msgtypes.Exam,
+			fields:  []string{"Fizika", "20.03.2025.", "Pisana provjera"},
+			want:    "Fizika: ispit Pisana provjera (20.03.2025.)",
+		},
+		{
+			name:    "absence",
+			subject: "Hrvatski jezik",
+			code:    msgtypes.Absence,
+			fields:  []string{"1. 4.", "Opravdano", "3"},
+			want:    "Hrvatski jezik: izostanak Opravdano, 3 (1. 4.)",
+		},
+		{
+			name:    "enrollment without date",
+			subject: "1.a",
+			code:    msgtypes.EnrollmentChange,
+			fields:  []string{"Upisan u razred"},
+			want:    "1.a: promjena upisa Upisan u razred",
+		},
+		{
+			name:    "no fields",
+			subject: "Kemija",
+			code:    msgtypes.Note,
+			want:    "Kemija: bilješka",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SummaryMsg(tt.username, tt.subject, tt.code, tt.fields); got != tt.want {
+				t.Errorf("SummaryMsg() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummaryTitleReminder(t *testing.T) {
+	g := msgtypes.Message{
+		Username: "korisnik@skole.hr",
+		Student:  "Ivan Horvat",
+		Subject:  "Matematika",
+		Code:     msgtypes.Exam,
+		Fields:   []string{"Matematika", "10.01.2025.", "Pisana provjera"},
+		Reminder: true,
+	}
+
+	want := "⏰ PODSJETNIK: Ivan Horvat / Matematika: ispit Pisana provjera (10.01.2025.)"
+
+	if got := SummaryTitle(g); got != want {
+		t.Errorf("SummaryTitle() = %q, want %q", got, want)
+	}
+}
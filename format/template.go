@@ -0,0 +1,159 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package format
+
+import (
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/dkorunic/e-dnevnik-bot/logger"
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+)
+
+var ErrTemplate = errors.New("invalid message template")
+
+// templateFuncs are helper functions available in message templates.
+var templateFuncs = map[string]any{
+	"displayName": DisplayName,
+	"prefix":      plainPrefix,
+	"average":     AverageLine,
+	"fieldValue":  FieldValue,
+	"join":        strings.Join,
+}
+
+// custom message templates (nil means built-in formatting)
+var (
+	textTmpl *texttemplate.Template
+	htmlTmpl *htmltemplate.Template
+)
+
+// Template is a parsed custom message template for cleartext, Markup and HTML messages.
+type Template struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// SetTemplate sets custom message template used for cleartext, Markup and HTML messages instead of built-in
+// formatting, disabling it if empty.
+func SetTemplate(tmpl string) error {
+	t, err := ParseTemplate(tmpl)
+	if err != nil {
+		return err
+	}
+
+	UseTemplate(t)
+
+	return nil
+}
+
+// ParseTemplate parses custom message template without using it, returning nil for an empty template.
+func ParseTemplate(tmpl string) (*Template, error) {
+	if tmpl == "" {
+		return nil, nil
+	}
+
+	t, err := texttemplate.New("message").Funcs(templateFuncs).Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTemplate, err)
+	}
+
+	h, err := htmltemplate.New("message").Funcs(templateFuncs).Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTemplate, err)
+	}
+
+	return &Template{text: t, html: h}, nil
+}
+
+// UseTemplate sets parsed custom message template used instead of built-in formatting, disabling it if nil.
+func UseTemplate(t *Template) {
+	if t == nil {
+		textTmpl, htmlTmpl = nil, nil
+
+		return
+	}
+
+	textTmpl, htmlTmpl = t.text, t.html
+}
+
+// RenderTemplate renders message with cleartext template.
+func RenderTemplate(tmpl string, m msgtypes.Message) (string, error) {
+	t, err := texttemplate.New("message").Funcs(templateFuncs).Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrTemplate, err)
+	}
+
+	return executeTemplate(t, m)
+}
+
+// RenderHTMLTemplate renders message with HTML template, escaping all message fields.
+func RenderHTMLTemplate(tmpl string, m msgtypes.Message) (string, error) {
+	t, err := htmltemplate.New("message").Funcs(templateFuncs).Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrTemplate, err)
+	}
+
+	return executeTemplate(t, m)
+}
+
+// templateExecutor is implemented by both text and HTML templates.
+type templateExecutor interface {
+	Execute(w io.Writer, data any) error
+}
+
+// executeTemplate renders message with a parsed template.
+func executeTemplate(t templateExecutor, m msgtypes.Message) (string, error) {
+	sb := &strings.Builder{}
+
+	if err := t.Execute(sb, m); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrTemplate, err)
+	}
+
+	return sb.String(), nil
+}
+
+// customMsg renders message with custom template if set, reporting if built-in formatting should be used instead.
+func customMsg(m msgtypes.Message, isHTML bool) (string, bool) {
+	var t templateExecutor
+
+	switch {
+	case isHTML && htmlTmpl != nil:
+		t = htmlTmpl
+	case !isHTML && textTmpl != nil:
+		t = textTmpl
+	default:
+		return "", false
+	}
+
+	s, err := executeTemplate(t, m)
+	if err != nil {
+		logger.Warn().Msgf("%v, using built-in formatting", err)
+
+		return "", false
+	}
+
+	return s, true
+}
@@ -0,0 +1,124 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package format
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+)
+
+const testTemplate = `{{prefix .Code}}{{displayName .}}: {{.Subject}}
+{{range $i, $d := .Descriptions}}{{$d}} = {{fieldValue $.Fields $.PreviousFields $i}}
+{{end}}`
+
+func TestRenderTemplate(t *testing.T) {
+	g := msgtypes.Message{
+		Username:       "korisnik@skole.hr",
+		Subject:        "Tehnička <kultura>",
+		Code:           msgtypes.Grade,
+		Descriptions:   []string{"Datum", "Ocjena"},
+		Fields:         []string{"10.01.2025.", "5"},
+		PreviousFields: []string{"10.01.2025.", "4"},
+	}
+
+	got, err := RenderTemplate(testTemplate, g)
+	if err != nil {
+		t.Fatalf("RenderTemplate() error = %v", err)
+	}
+
+	want := GradePrefix + "korisnik@skole.hr: Tehnička <kultura>\nDatum = 10.01.2025.\nOcjena = " + ChangedWas +
+		"4" + ChangedNow + "5\n"
+	if got != want {
+		t.Errorf("RenderTemplate() = %q, want %q", got, want)
+	}
+
+	got, err = RenderHTMLTemplate(`<b>{{.Subject}}</b>`, g)
+	if err != nil {
+		t.Fatalf("RenderHTMLTemplate() error = %v", err)
+	}
+
+	if want := "<b>Tehnička &lt;kultura&gt;</b>"; got != want {
+		t.Errorf("RenderHTMLTemplate() = %q, want %q", got, want)
+	}
+
+	if _, err := RenderTemplate("{{.Unknown", g); !errors.Is(err, ErrTemplate) {
+		t.Errorf("RenderTemplate() error = %v, want %v", err, ErrTemplate)
+	}
+}
+
+func TestSetTemplate(t *testing.T) {
+	g := msgtypes.Message{Username: "korisnik@skole.hr", Subject: "Matematika & fizika"}
+
+	if err := SetTemplate("{{.Subject}}"); err != nil {
+		t.Fatalf("SetTemplate() error = %v", err)
+	}
+
+	defer SetTemplate("") //nolint:errcheck
+
+	if got := PlainMsg(g); got != "Matematika & fizika" {
+		t.Errorf("PlainMsg() = %q", got)
+	}
+
+	if got := HTMLMsg(g); got != "Matematika &amp; fizika" {
+		t.Errorf("HTMLMsg() = %q", got)
+	}
+
+	if err := SetTemplate("{{.Missing}}"); err != nil {
+		t.Fatalf("SetTemplate() error = %v", err)
+	}
+
+	// rendering failure falls back to built-in formatting
+	if got := PlainMsg(g); got == "" {
+		t.Error("PlainMsg() returned empty message, expected built-in formatting")
+	}
+
+	if err := SetTemplate("{{if}}"); !errors.Is(err, ErrTemplate) {
+		t.Errorf("SetTemplate() error = %v, want %v", err, ErrTemplate)
+	}
+}
+
+func TestParseTemplate(t *testing.T) {
+	g := msgtypes.Message{Username: "korisnik@skole.hr", Subject: "Matematika"}
+
+	if _, err := ParseTemplate("{{if}}"); !errors.Is(err, ErrTemplate) {
+		t.Fatalf("ParseTemplate() error = %v, want %v", err, ErrTemplate)
+	}
+
+	tmpl, err := ParseTemplate("{{.Subject}}")
+	if err != nil {
+		t.Fatalf("ParseTemplate() error = %v", err)
+	}
+
+	// parsing alone does not replace built-in formatting
+	if got := PlainMsg(g); got == "Matematika" {
+		t.Errorf("PlainMsg() = %q, expected built-in formatting", got)
+	}
+
+	UseTemplate(tmpl)
+	defer UseTemplate(nil)
+
+	if got := PlainMsg(g); got != "Matematika" {
+		t.Errorf("PlainMsg() = %q", got)
+	}
+}
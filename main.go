@@ -22,8 +22,10 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io/fs"
 	"os"
 	"os/signal"
@@ -37,10 +39,18 @@ import (
 	"time"
 
 	"github.com/KimMachineGun/automemlimit/memlimit"
+	"github.com/dkorunic/e-dnevnik-bot/db"
+	"github.com/dkorunic/e-dnevnik-bot/health"
 	"github.com/dkorunic/e-dnevnik-bot/logger"
 	"github.com/dkorunic/e-dnevnik-bot/messenger"
+	"github.com/dkorunic/e-dnevnik-bot/metrics"
 	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+	"github.com/dkorunic/e-dnevnik-bot/scrape"
+	"github.com/dkorunic/e-dnevnik-bot/tracing"
+	"github.com/dkorunic/e-dnevnik-bot/trigger"
 	"github.com/dustin/go-humanize"
+	"github.com/goccy/go-json"
+	"github.com/google/renameio/v2/maybe"
 	sysdnotify "github.com/iguanesolutions/go-systemd/v6/notify"
 	sysdwatchdog "github.com/iguanesolutions/go-systemd/v6/notify/watchdog"
 	"github.com/mattn/go-isatty"
@@ -56,18 +66,18 @@ const (
 	testSubject     = "Ovo je testni predmet"
 	testDescription = "Testni opis"
 	testField       = "Testna vrijednost"
-	maxMemRatio     = 0.9
 	scheduledActive = "Scheduled run in progress"
 	scheduledSleep  = "Scheduled run completed, will sleep now"
 )
 
 var (
-	exitWithError atomic.Bool
-	ErrMaxProc    = errors.New("failed to set GOMAXPROCS")
-	GitTag        = ""
-	GitCommit     = ""
-	GitDirty      = ""
-	BuildTime     = ""
+	exitWithError    atomic.Bool
+	ErrMaxProc       = errors.New("failed to set GOMAXPROCS")
+	ErrTriggerSecret = errors.New("on-demand scrape trigger requires trigger_secret in configuration, not starting it")
+	GitTag           = ""
+	GitCommit        = ""
+	GitDirty         = ""
+	BuildTime        = ""
 )
 
 // init initializes the GitTag, GitCommit, GitDirty, and BuildTime variables.
@@ -89,6 +99,8 @@ func init() {
 // and exits the program with an exit code of 1. If the exitWithError variable is false, it logs
 // an info message and exits the program with an exit code of 0 (success).
 func fatalIfErrors() {
+	tracing.Shutdown()
+
 	if exitWithError.Load() {
 		logger.Fatal().Msg("Exiting, during run some errors were encountered.")
 	}
@@ -121,28 +133,44 @@ func main() {
 
 	zerolog.SetGlobalLevel(logLevel)
 
+	switch {
 	// enable slow colored console logging
-	if *colorLogs {
+	case *colorLogs:
 		logger.Logger = zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339}).
 			Level(logLevel).
 			With().
 			Timestamp().
 			Caller().
 			Logger()
+	// enable structured JSON logging with sub-second timestamps and caller information
+	case *jsonLogs:
+		zerolog.TimeFieldFormat = time.RFC3339Nano
+		logger.Logger = zerolog.New(os.Stdout).
+			Level(logLevel).
+			With().
+			Timestamp().
+			Caller().
+			Logger()
 	}
 
 	logger.Info().Msgf("e-dnevnik-bot %v %v%v, built on %v, with %v", GitTag, GitCommit, GitDirty,
 		BuildTime, runtime.Version())
 
-	// configure GOMEMLIMIT to 90% of available memory (Cgroups v2/v1 or system)
+	// configure per-messenger circuit breaker
+	messenger.SetBreakerThreshold(*breakerThreshold)
+
+	// configure GOMEMLIMIT to a ratio of available memory (Cgroups v2/v1 or system) or to an absolute limit
+	memProvider := memlimit.ApplyFallback(memlimit.FromCgroup, memlimit.FromSystem)
+	memRatio := *memoryRatio
+
+	if memLimitBytes > 0 {
+		memProvider = memlimit.Limit(memLimitBytes)
+		memRatio = 1
+	}
+
 	limit, err := memlimit.SetGoMemLimitWithOpts(
-		memlimit.WithRatio(maxMemRatio),
-		memlimit.WithProvider(
-			memlimit.ApplyFallback(
-				memlimit.FromCgroup,
-				memlimit.FromSystem,
-			),
-		),
+		memlimit.WithRatio(memRatio),
+		memlimit.WithProvider(memProvider),
 	)
 
 	if err != nil {
@@ -169,12 +197,80 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	// load TOML config
-	config, err := loadConfig()
+	// dump alert database and exit
+	if *dumpDB {
+		if err := dumpDatabase(); err != nil {
+			logger.Fatal().Msgf("Error dumping database: %v", err)
+		}
+
+		return
+	}
+
+	// print alert database statistics and exit
+	if *dbStats {
+		if err := printDatabaseStats(ctx); err != nil {
+			logger.Fatal().Msgf("Error reading database statistics: %v", err)
+		}
+
+		return
+	}
+
+	// export alert database to JSON and exit
+	if *exportDB != "" {
+		if err := exportDatabase(ctx); err != nil {
+			logger.Fatal().Msgf("Error exporting database: %v", err)
+		}
+
+		return
+	}
+
+	// import alert database from JSON and exit
+	if *importDB != "" {
+		n, err := importDatabase(ctx)
+		if err != nil {
+			logger.Fatal().Msgf("Error importing database: %v", err)
+		}
+
+		logger.Info().Msgf("Imported %v entries from %v to database %v", n, *importDB, *dbFile)
+
+		return
+	}
+
+	// encrypt configuration secrets and exit
+	if *encryptConf {
+		if err := encryptConfig(); err != nil {
+			logger.Fatal().Msgf("Error encrypting configuration: %v", err)
+		}
+
+		logger.Info().Msgf("Encrypted secrets in configuration file %v", *confFile)
+
+		return
+	}
+
+	// load TOML config, or every profile configuration
+	profiles, err := loadProfiles()
 	if err != nil {
 		logger.Fatal().Msgf("Error loading configuration: %v", err)
 	}
 
+	// alert database integrity check
+	if *dbCheck || *dbRepair {
+		for _, p := range profiles {
+			checkDatabase(p.dbFile)
+		}
+	}
+
+	// verify e-dnevnik credentials and exit
+	if *checkLogin {
+		if !checkLogins(ctx, profiles) {
+			logger.Fatal().Msg("Login check failed for some users")
+		}
+
+		logger.Info().Msg("Login check passed for all users")
+
+		return
+	}
+
 	// enable CPU profiling dump on exit
 	if *cpuProfile != "" {
 		f, err := os.Create(*cpuProfile)
@@ -207,8 +303,12 @@ func main() {
 	}
 
 	// Google Calendar API initial setup
-	if config.calendarEnabled {
-		checkCalendar(ctx, &config)
+	for _, p := range profiles {
+		if p.config.calendarEnabled {
+			checkCalendar(ctx, &p.config, p.calTokFile)
+		}
+
+		checkMailOAuth(ctx, &p.config)
 	}
 
 	// test mode: send messages and exit
@@ -216,23 +316,40 @@ func main() {
 		logger.Info().Msg("Emulation/testing mode enabled, will try to send a test message")
 		signal.Reset()
 
-		gradesMsg := make(chan msgtypes.Message, chanBufLen)
-		gradesMsg <- msgtypes.Message{
-			Username: testUsername,
-			Subject:  testSubject,
-			Descriptions: []string{
-				testDescription,
-			},
-			Fields: []string{
-				testField,
-			},
-		}
-		close(gradesMsg)
+		passed := true
+
+		for _, p := range profiles {
+			gradesMsg := make(chan msgtypes.Message, chanBufLen)
+			gradesMsg <- msgtypes.Message{
+				Username: testUsername,
+				Subject:  testSubject,
+				Descriptions: []string{
+					testDescription,
+				},
+				Fields: []string{
+					testField,
+				},
+			}
+			close(gradesMsg)
+
+			var wgMsg sync.WaitGroup
+
+			// test message is always sent immediately
+			p.config.digestEnabled = false
+			p.config.quietEnabled = false
+			p.results = newRunResults()
 
-		var wgMsg sync.WaitGroup
+			msgSend(ctx, &wgMsg, gradesMsg, p, nil)
+			wgMsg.Wait()
+
+			if !p.testSummary() {
+				passed = false
+			}
+		}
 
-		msgSend(ctx, &wgMsg, gradesMsg, config)
-		wgMsg.Wait()
+		if !passed {
+			logger.Fatal().Msg("Exiting with a failure from the emulation, some messengers could not send a test message.")
+		}
 
 		logger.Info().Msg("Exiting with a success from the emulation.")
 
@@ -243,8 +360,11 @@ func main() {
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
 
+	// per-user poll intervals shorter than the global one make polls more frequent
+	interval := pollInterval(profiles)
+
 	if *daemon {
-		logger.Info().Msgf("Service started, will collect information every %v", tickInterval)
+		logger.Info().Msgf("Service started, will collect information every %v", interval)
 	} else {
 		logger.Info().Msg("Service is not enabled, doing just a single run")
 	}
@@ -271,6 +391,42 @@ func main() {
 		}()
 	}
 
+	// Prometheus metrics
+	if *metricsAddr != "" {
+		go metrics.Serve(ctx, *metricsAddr)
+	}
+
+	// health checks
+	if *healthAddr != "" {
+		go health.Serve(ctx, *healthAddr)
+	}
+
+	// OpenTelemetry tracing
+	if *otlpEndpoint != "" {
+		if err := tracing.Init(ctx, *otlpEndpoint, GitTag); err != nil {
+			logger.Fatal().Msgf("Error initializing tracing: %v", err)
+		}
+
+		logger.Info().Msgf("Sending traces to %v", *otlpEndpoint)
+	}
+
+	// on-demand scrape trigger
+	scrapeNow := make(chan struct{}, 1)
+
+	if *triggerAddr != "" {
+		if secret := profiles[0].config.TriggerSecret; secret != "" {
+			go trigger.Serve(ctx, *triggerAddr, secret, scrapeNow)
+		} else {
+			logger.Error().Msgf("%v", ErrTriggerSecret)
+		}
+	}
+
+	// configuration reload on SIGHUP, applied from the next scheduled run
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	defer signal.Stop(hup)
+
 	for {
 		select {
 		// in case of context cancellation, try to propagate and exit
@@ -290,39 +446,64 @@ func main() {
 			fatalIfErrors()
 
 			return
+		case <-hup:
+			_ = sysdnotify.Reloading()
+
+			reloadProfiles(ctx, profiles)
+
+			interval = pollInterval(profiles)
+
+			_ = sysdnotify.Ready()
+		case <-scrapeNow:
+			logger.Info().Msg("Received on-demand scrape request")
+
+			// scrape all users regardless of their poll intervals
+			for _, p := range profiles {
+				p.lastScrape = nil
+			}
+
+			ticker.Reset(time.Millisecond)
 		case <-ticker.C:
 			logger.Info().Msg(scheduledActive)
-			ticker.Reset(*tickInterval)
+			ticker.Reset(interval)
 
 			_ = sysdnotify.Status(scheduledActive)
 
 			// reset exit error status
 			exitWithError.Store(false)
 
-			gradesScraped := make(chan msgtypes.Message, chanBufLen)
-			gradesMsg := make(chan msgtypes.Message, chanBufLen)
-
-			var wgVersion, wgScrape, wgFilter, wgMsg sync.WaitGroup
+			var wgVersion, wgProfiles sync.WaitGroup
 
 			// self-check
 			versionCheck(ctx, &wgVersion)
 
-			// subjects/grades/exams scraper routines
-			scrapers(ctx, &wgScrape, gradesScraped, config)
-
-			// message/alert database checking routine
-			msgDedup(ctx, &wgFilter, gradesScraped, gradesMsg)
+			// profiles run independently, a failing one does not stop the others
+			for _, p := range profiles {
+				wgProfiles.Add(1)
 
-			// messenger routines
-			msgSend(ctx, &wgMsg, gradesMsg, config)
+				go func() {
+					defer wgProfiles.Done()
 
-			wgScrape.Wait()
-			close(gradesScraped)
+					if !p.run(ctx) {
+						exitWithError.Store(true)
+					}
+				}()
+			}
 
-			wgFilter.Wait()
-			wgMsg.Wait()
+			wgProfiles.Wait()
 			wgVersion.Wait()
 
+			if *statusFile != "" {
+				if err := writeStatus(profiles); err != nil {
+					logger.Warn().Msgf("%v: %v", ErrStatusFile, err)
+				}
+			}
+
+			// ready after the first successful run
+			if !exitWithError.Load() {
+				health.Ready.Store(true)
+			}
+
 			if !*daemon {
 				fatalIfErrors()
 
@@ -341,21 +522,21 @@ func main() {
 // Parameters:
 // - config: a pointer to the tomlConfig struct containing the configuration settings.
 // - ctx: the context object for cancellation and timeout.
-func checkCalendar(ctx context.Context, config *tomlConfig) {
+// - tokFile: the Google Calendar token file.
+func checkCalendar(ctx context.Context, config *tomlConfig, tokFile string) {
 	if config == nil {
 		return
 	}
 
-	if _, err := os.Stat(*calTokFile); errors.Is(err, fs.ErrNotExist) {
+	if _, err := os.Stat(tokFile); errors.Is(err, fs.ErrNotExist) {
 		// check if we are running under a terminal
-		fd := os.Stdout.Fd()
-		if os.Getenv("TERM") == "dumb" || (!isatty.IsTerminal(fd) && !isatty.IsCygwinTerminal(fd)) {
+		if !interactive() {
 			logger.Error().Msgf("Google Calendar API token file not found and first run requires running under a terminal. Disabling calendar integration.")
 
 			config.calendarEnabled = false
 		} else {
 			// early Google Calendar API initialization and token refresh
-			_, _, err := messenger.InitCalendar(ctx, *calTokFile, config.Calendar.Name)
+			_, _, err := messenger.InitCalendar(ctx, tokFile, config.Calendar.Name)
 			if err != nil {
 				logger.Error().Msgf("Error initializing Google Calendar API: %v. Disabling calendar integration.", err)
 
@@ -364,3 +545,198 @@ func checkCalendar(ctx context.Context, config *tomlConfig) {
 		}
 	}
 }
+
+// checkLogins attempts to login as every configured user and reports the result per user, returning true if all
+// logins have succeeded.
+func checkLogins(ctx context.Context, profiles []*profile) bool {
+	ok := true
+
+	for _, p := range profiles {
+		for _, u := range p.config.User {
+			if err := scrape.CheckLogin(ctx, u.Username, u.Password, p.config.fetchOptions(), *retries, *retryDelay); err != nil {
+				logger.Error().Msgf("Login check for user %v failed: %v", u.Username, err)
+
+				ok = false
+
+				continue
+			}
+
+			logger.Info().Msgf("Login check for user %v passed", u.Username)
+		}
+	}
+
+	return ok
+}
+
+// checkMailOAuth initializes OAuth2 token source for XOAUTH2 mail authentication, disabling e-mail messenger and
+// family digest if that is not possible. First run requires running under a terminal to obtain the token.
+func checkMailOAuth(ctx context.Context, config *tomlConfig) {
+	if config == nil || config.Mail.Auth != messenger.MailAuthXOAUTH2 || (!config.mailEnabled && !config.familyEnabled) {
+		return
+	}
+
+	if _, err := os.Stat(config.Mail.OAuthToken); errors.Is(err, fs.ErrNotExist) && !interactive() {
+		logger.Error().Msgf("Mail OAuth2 token file not found and first run requires running under a terminal. Disabling e-mail.")
+
+		config.mailEnabled, config.familyEnabled = false, false
+
+		return
+	}
+
+	tokens, err := messenger.InitMailOAuth(ctx, config.Mail.OAuthCredentials, config.Mail.OAuthToken,
+		config.Mail.OAuthScopes)
+	if err != nil {
+		logger.Error().Msgf("Error initializing mail OAuth2: %v. Disabling e-mail.", err)
+
+		config.mailEnabled, config.familyEnabled = false, false
+
+		return
+	}
+
+	config.mailTokens = tokens
+}
+
+// interactive reports if we are running under a terminal.
+func interactive() bool {
+	fd := os.Stdout.Fd()
+
+	return os.Getenv("TERM") != "dumb" && (isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd))
+}
+
+// checkDatabase verifies alert database integrity, exiting on corruption or, if repair has been requested, moving the
+// corrupted database out of the way so that it gets recreated from scratch.
+func checkDatabase(dbFile string) {
+	err := db.Verify(dbFile)
+	if err == nil {
+		logger.Info().Msg("Database integrity check passed")
+
+		return
+	}
+
+	if !*dbRepair {
+		logger.Fatal().Msgf("Database integrity check failed, consider running with --db-repair: %v", err)
+	}
+
+	backupPath, bErr := db.Backup(dbFile)
+	if bErr != nil {
+		logger.Fatal().Msgf("Database integrity check failed and unable to repair: %v", bErr)
+	}
+
+	logger.Warn().Msgf("Database integrity check failed: %v. Corrupted database moved to %v and will be recreated.",
+		err, backupPath)
+}
+
+// dumpDatabase prints all alert database entries with their expiry times, decoding stored sets and re-notification
+// timestamps where applicable.
+func dumpDatabase() error {
+	if !db.Exists(*dbFile) {
+		return fmt.Errorf("%w: %v", os.ErrNotExist, *dbFile)
+	}
+
+	eDB, err := db.New(*dbFile)
+	if err != nil {
+		return err
+	}
+	defer eDB.Close()
+
+	return eDB.Iterate(func(key, value []byte, expiresAt time.Time) error {
+		expiry := "never"
+		if !expiresAt.IsZero() {
+			expiry = expiresAt.Format(time.RFC3339)
+		}
+
+		var decoded string
+
+		switch {
+		case bytes.HasPrefix(key, []byte(db.SetPrefix)):
+			var set []string
+			if err := json.Unmarshal(value, &set); err == nil {
+				decoded = strings.Join(set, ", ")
+			}
+		case bytes.Equal(key, []byte(db.SchemaKey)):
+			decoded = "schema version " + string(value)
+		case bytes.HasPrefix(key, []byte(db.RenotifyPrefix)):
+			var last time.Time
+			if err := last.UnmarshalBinary(value); err == nil {
+				decoded = "last notified " + last.Format(time.RFC3339)
+			}
+		case bytes.HasPrefix(key, []byte(db.RemindPrefix)):
+			var last time.Time
+			if err := last.UnmarshalBinary(value); err == nil {
+				decoded = "reminded " + last.Format(time.RFC3339)
+			}
+		}
+
+		fmt.Printf("%x\texpires: %v\t%v\n", key, expiry, decoded)
+
+		return nil
+	})
+}
+
+// printDatabaseStats prints alert database size and key counts.
+func printDatabaseStats(ctx context.Context) error {
+	if !db.Exists(*dbFile) {
+		return fmt.Errorf("%w: %v", os.ErrNotExist, *dbFile)
+	}
+
+	eDB, err := db.New(*dbFile)
+	if err != nil {
+		return err
+	}
+	defer eDB.Close()
+
+	stats, err := eDB.Stats(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("lsm size:\t%v\n", humanize.IBytes(uint64(stats.LSMSize)))   //nolint:gosec
+	fmt.Printf("vlog size:\t%v\n", humanize.IBytes(uint64(stats.VlogSize))) //nolint:gosec
+	fmt.Printf("keys:\t%v\n", stats.Keys)
+	fmt.Printf("alerts:\t%v\n", stats.Alerts)
+	fmt.Printf("renotify:\t%v\n", stats.Renotify)
+	fmt.Printf("remind:\t%v\n", stats.Remind)
+	fmt.Printf("sets:\t%v\n", stats.Sets)
+	fmt.Printf("queues:\t%v\n", stats.Queues)
+	fmt.Printf("expired:\t%v\n", stats.Expired)
+
+	return nil
+}
+
+// exportDatabase writes all alert database entries to a JSON file.
+func exportDatabase(ctx context.Context) error {
+	if !db.Exists(*dbFile) {
+		return fmt.Errorf("%w: %v", os.ErrNotExist, *dbFile)
+	}
+
+	eDB, err := db.New(*dbFile)
+	if err != nil {
+		return err
+	}
+	defer eDB.Close()
+
+	var buf bytes.Buffer
+	if err := eDB.ExportJSON(ctx, &buf); err != nil {
+		return err
+	}
+
+	return maybe.WriteFile(*exportDB, buf.Bytes(), ConfigPerms)
+}
+
+// importDatabase reads alert database entries from a JSON file, creating the database if needed and returning the
+// number of imported entries.
+func importDatabase(ctx context.Context) (int, error) {
+	f, err := os.Open(*importDB)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	eDB, err := db.New(*dbFile)
+	if err != nil {
+		return 0, err
+	}
+	defer eDB.Close()
+
+	return eDB.ImportJSON(ctx, f)
+}
@@ -0,0 +1,236 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dkorunic/e-dnevnik-bot/logger"
+)
+
+const (
+	AppriseTelegram = "tgram"   // tgram://{bot_token}/{chat_id}/...
+	AppriseDiscord  = "discord" // discord://{bot_token}/{user_id}/...
+	AppriseSlack    = "slack"   // slack://{oauth_token}/{channel}/...
+	AppriseMail     = "mailto"  // mailto://{user}:{password}@{server}:{port}?to={address},...&from={address}
+)
+
+var (
+	ErrAppriseEmptyURLs      = errors.New("empty list of Apprise URLs")
+	ErrAppriseInvalidURL     = errors.New("invalid Apprise URL")
+	ErrAppriseUnknownScheme  = errors.New("unsupported Apprise URL scheme")
+	ErrAppriseSendingMessage = errors.New("error sending Apprise message")
+)
+
+// AppriseTarget holds a parsed Apprise-style notification URL, mapped onto one of the built-in messengers.
+type AppriseTarget struct {
+	Scheme     string
+	Token      string   // Telegram, Discord or Slack token
+	Recipients []string // chat IDs, user IDs, channels or e-mail addresses
+	Server     string   // SMTP server
+	Port       string   // SMTP port
+	Username   string   // SMTP username
+	Password   string   // SMTP password
+	From       string   // e-mail sender address
+	Subject    string   // e-mail subject
+}
+
+// ParseAppriseURL parses an Apprise-style notification URL. Telegram, Discord and Slack URLs have a token followed
+// by recipients as path elements, while mail URLs carry SMTP credentials and server, with recipients, sender and
+// subject in query parameters (both sender and recipient default to the username).
+func ParseAppriseURL(raw string) (AppriseTarget, error) {
+	scheme, rest, ok := strings.Cut(strings.TrimSpace(raw), "://")
+	if !ok {
+		return AppriseTarget{}, fmt.Errorf("%w: missing scheme", ErrAppriseInvalidURL)
+	}
+
+	t := AppriseTarget{Scheme: strings.ToLower(scheme)}
+
+	switch t.Scheme {
+	case AppriseTelegram, AppriseDiscord, AppriseSlack:
+		// tokens are not valid URL hosts (ie. Telegram tokens contain a colon), so split the path by hand
+		parts := strings.FieldsFunc(rest, func(r rune) bool { return r == '/' })
+		if len(parts) < 2 {
+			return AppriseTarget{}, fmt.Errorf("%w: %v URL needs a token and at least one recipient",
+				ErrAppriseInvalidURL, t.Scheme)
+		}
+
+		for i := range parts {
+			p, err := url.PathUnescape(parts[i])
+			if err != nil {
+				return AppriseTarget{}, fmt.Errorf("%w: %w", ErrAppriseInvalidURL, err)
+			}
+
+			parts[i] = p
+		}
+
+		t.Token, t.Recipients = parts[0], parts[1:]
+	case AppriseMail:
+		u, err := url.Parse(raw)
+		if err != nil {
+			return AppriseTarget{}, fmt.Errorf("%w: %w", ErrAppriseInvalidURL, err)
+		}
+
+		if u.Hostname() == "" {
+			return AppriseTarget{}, fmt.Errorf("%w: %v URL needs a server", ErrAppriseInvalidURL, t.Scheme)
+		}
+
+		t.Server, t.Port = u.Hostname(), u.Port()
+		if t.Port == "" {
+			t.Port = strconv.Itoa(MailPort)
+		}
+
+		t.Username = u.User.Username()
+		t.Password, _ = u.User.Password()
+
+		q := u.Query()
+
+		t.From = q.Get("from")
+		if t.From == "" {
+			t.From = t.Username
+		}
+
+		t.Subject = q.Get("subject")
+
+		for _, a := range strings.Split(q.Get("to"), ",") {
+			if a = strings.TrimSpace(a); a != "" {
+				t.Recipients = append(t.Recipients, a)
+			}
+		}
+
+		if len(t.Recipients) == 0 && t.Username != "" {
+			t.Recipients = []string{t.Username}
+		}
+
+		if t.From == "" || len(t.Recipients) == 0 {
+			return AppriseTarget{}, fmt.Errorf("%w: %v URL needs sender and recipient addresses",
+				ErrAppriseInvalidURL, t.Scheme)
+		}
+	default:
+		return AppriseTarget{}, fmt.Errorf("%w: %v", ErrAppriseUnknownScheme, scheme)
+	}
+
+	return t, nil
+}
+
+// send relays messages to the built-in messenger matching the URL scheme, with messenger default rate limits.
+func (t AppriseTarget) send(ctx context.Context, ch <-chan interface{}, retries uint) error {
+	switch t.Scheme {
+	case AppriseTelegram:
+		return Telegram(ctx, ch, t.Token, t.Recipients, nil, 0, false, nil, 0, 0, retries)
+	case AppriseDiscord:
+		return Discord(ctx, ch, t.Token, t.Recipients, 0, 0, retries)
+	case AppriseSlack:
+		return Slack(ctx, ch, t.Token, t.Recipients, false, 0, 0, retries)
+	case AppriseMail:
+		return Mail(ctx, ch, t.Server, t.Port, t.Username, t.Password, MailAuthPlain, "", false, nil, t.From, t.Subject,
+			t.Recipients, false, 0, 0, retries)
+	default:
+		return fmt.Errorf("%w: %v", ErrAppriseUnknownScheme, t.Scheme)
+	}
+}
+
+// Apprise sends messages to all Apprise-style notification URLs, dispatching each one to the matching built-in
+// messenger.
+//
+// ctx: the context in which the function is executed.
+// ch: the channel from which messages are received.
+// urls: the Apprise-style notification URLs.
+// retries: the number of retries in case of failure.
+// error: an error if there was a problem sending the message.
+func Apprise(ctx context.Context, ch <-chan interface{}, urls []string, retries uint) error {
+	if len(urls) == 0 {
+		return fmt.Errorf("%w", ErrAppriseEmptyURLs)
+	}
+
+	targets := make([]AppriseTarget, 0, len(urls))
+
+	for _, u := range urls {
+		t, err := ParseAppriseURL(u)
+		if err != nil {
+			return err
+		}
+
+		targets = append(targets, t)
+	}
+
+	logger.Debug().Msg("Started Apprise messenger")
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+
+	// every URL gets its own messenger routine
+	chs := make([]chan interface{}, len(targets))
+
+	for i, t := range targets {
+		chs[i] = make(chan interface{})
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if err := t.send(ctx, chs[i], retries); err != nil {
+				logger.Error().Msgf("%v: %v: %v", ErrAppriseSendingMessage, t.Scheme, err)
+
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+
+			// drain messages left after an early messenger exit so that relaying never blocks
+			for range chs[i] { //nolint:revive
+			}
+		}()
+	}
+
+	// relay all messages to every messenger
+	for o := range ch {
+		select {
+		case <-ctx.Done():
+		default:
+			for _, c := range chs {
+				select {
+				case c <- o:
+				case <-ctx.Done():
+				}
+			}
+		}
+	}
+
+	for _, c := range chs {
+		close(c)
+	}
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,152 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/dkorunic/e-dnevnik-bot/logger"
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+)
+
+// DefaultBreakerThreshold is the default number of consecutive send failures after which a messenger skips the rest
+// of the messages in a run, keeping them to be sent in the next run.
+const DefaultBreakerThreshold = 3
+
+var (
+	breakerThreshold atomic.Int64
+	defaultBreakers  Breakers // circuit breaker state used without one set in the context
+)
+
+// Breakers holds circuit breaker state of messengers between runs, so that independent callers (ie. configuration
+// profiles) sending with the same messenger do not share it.
+type Breakers struct {
+	tripped sync.Map // messengers with a tripped circuit breaker in the previous run
+	mu      sync.Mutex
+	skipped map[string][]msgtypes.Message // messages skipped by tripped circuit breakers, keyed by messenger name
+}
+
+// Skipped returns and forgets messages skipped by tripped circuit breakers since the last call, keyed by messenger
+// name, so that they can be sent again in the next run.
+func (b *Breakers) Skipped() map[string][]msgtypes.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	skipped := b.skipped
+	b.skipped = nil
+
+	return skipped
+}
+
+// breakersKey is the context key of circuit breaker state.
+type breakersKey struct{}
+
+// WithBreakers returns a context carrying circuit breaker state used by all messengers sending with it.
+func WithBreakers(ctx context.Context, b *Breakers) context.Context {
+	return context.WithValue(ctx, breakersKey{}, b)
+}
+
+// breakersFrom returns circuit breaker state carried by the context, or the default one if unset.
+func breakersFrom(ctx context.Context) *Breakers {
+	if b, ok := ctx.Value(breakersKey{}).(*Breakers); ok && b != nil {
+		return b
+	}
+
+	return &defaultBreakers
+}
+
+//nolint:gochecknoinits
+func init() {
+	breakerThreshold.Store(DefaultBreakerThreshold)
+}
+
+// SetBreakerThreshold sets the number of consecutive send failures after which a messenger skips the rest of the
+// messages in a run (0 disables the circuit breaker).
+func SetBreakerThreshold(n int) {
+	breakerThreshold.Store(int64(max(n, 0)))
+}
+
+// breaker is a per-run messenger circuit breaker, which trips after a number of consecutive send failures so that an
+// unavailable service does not use up the whole run with retries, keeping skipped messages for the next run. It is
+// reset with the next run.
+type breaker struct {
+	name      string
+	threshold int64
+	failures  int64
+	state     *Breakers
+}
+
+// newBreaker creates a new circuit breaker for the messenger with state carried by the context, logging if the
+// breaker has tripped in the previous run.
+func newBreaker(ctx context.Context, name string) *breaker {
+	state := breakersFrom(ctx)
+
+	if _, ok := state.tripped.LoadAndDelete(name); ok {
+		logger.Info().Msgf("%v messenger circuit breaker reset, trying to send again", name)
+	}
+
+	return &breaker{name: name, threshold: breakerThreshold.Load(), state: state}
+}
+
+// open reports if the breaker has tripped and sending should be skipped.
+func (b *breaker) open() bool {
+	return b.threshold > 0 && b.failures >= b.threshold
+}
+
+// record records a send result, resetting the failure count on success and tripping the breaker after threshold
+// consecutive failures.
+func (b *breaker) record(err error) {
+	if err == nil {
+		b.failures = 0
+
+		return
+	}
+
+	b.failures++
+
+	if b.threshold > 0 && b.failures == b.threshold {
+		logger.Warn().Msgf("%v messenger circuit breaker tripped after %v consecutive failures, skipping the rest of the messages in this run",
+			b.name, b.failures)
+		b.state.tripped.Store(b.name, struct{}{})
+	}
+}
+
+// skip records a message skipped due to a tripped breaker, so that it is sent again in the next run. A message skipped
+// for several recipients is recorded only once and sent to all of them again.
+func (b *breaker) skip(g msgtypes.Message) {
+	b.state.mu.Lock()
+	defer b.state.mu.Unlock()
+
+	msgs := b.state.skipped[b.name]
+	if n := len(msgs); n > 0 && reflect.DeepEqual(msgs[n-1], g) {
+		return
+	}
+
+	if b.state.skipped == nil {
+		b.state.skipped = make(map[string][]msgtypes.Message)
+	}
+
+	b.state.skipped[b.name] = append(msgs, g)
+}
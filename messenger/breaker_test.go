@@ -0,0 +1,137 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+)
+
+func TestBreaker(t *testing.T) {
+	errSend := errors.New("send failed")
+
+	defer SetBreakerThreshold(DefaultBreakerThreshold)
+
+	SetBreakerThreshold(2)
+
+	var breakers Breakers
+
+	ctx := WithBreakers(context.Background(), &breakers)
+
+	b := newBreaker(ctx, "Test")
+
+	b.record(errSend)
+	b.record(nil)
+	b.record(errSend)
+
+	if b.open() {
+		t.Fatal("breaker open after non-consecutive failures")
+	}
+
+	b.record(errSend)
+
+	if !b.open() {
+		t.Fatal("breaker closed after consecutive failures reached threshold")
+	}
+
+	if _, ok := breakers.tripped.Load("Test"); !ok {
+		t.Error("tripped breaker not recorded")
+	}
+
+	// next run starts with a closed breaker
+	b = newBreaker(ctx, "Test")
+	if b.open() {
+		t.Error("breaker open in the next run")
+	}
+
+	if _, ok := breakers.tripped.Load("Test"); ok {
+		t.Error("tripped breaker not reset in the next run")
+	}
+
+	SetBreakerThreshold(0)
+
+	b = newBreaker(ctx, "Test")
+	for range 10 {
+		b.record(errSend)
+	}
+
+	if b.open() {
+		t.Error("disabled breaker open")
+	}
+}
+
+func TestBreakersSeparate(t *testing.T) {
+	errSend := errors.New("send failed")
+
+	defer SetBreakerThreshold(DefaultBreakerThreshold)
+
+	SetBreakerThreshold(1)
+
+	var first, second Breakers
+
+	newBreaker(WithBreakers(context.Background(), &first), "Test").record(errSend)
+
+	if _, ok := first.tripped.Load("Test"); !ok {
+		t.Fatal("tripped breaker not recorded")
+	}
+
+	if _, ok := second.tripped.Load("Test"); ok {
+		t.Error("tripped breaker recorded in unrelated state")
+	}
+
+	if _, ok := defaultBreakers.tripped.Load("Test"); ok {
+		t.Error("tripped breaker recorded in default state")
+	}
+}
+
+func TestBreakerSkipped(t *testing.T) {
+	errSend := errors.New("send failed")
+
+	defer SetBreakerThreshold(DefaultBreakerThreshold)
+
+	SetBreakerThreshold(1)
+
+	var breakers Breakers
+
+	b := newBreaker(WithBreakers(context.Background(), &breakers), "test")
+	b.record(errSend)
+
+	first := msgtypes.Message{Username: "korisnik@test.domena", Subject: "Matematika"}
+	second := msgtypes.Message{Username: "korisnik@test.domena", Subject: "Fizika"}
+
+	// first message is skipped for two recipients
+	b.skip(first)
+	b.skip(first)
+	b.skip(second)
+
+	skipped := breakers.Skipped()
+	if msgs := skipped["test"]; len(msgs) != 2 || msgs[0].Subject != first.Subject || msgs[1].Subject != second.Subject {
+		t.Fatalf("Skipped() = %+v, want both messages once", skipped)
+	}
+
+	if skipped := breakers.Skipped(); len(skipped) != 0 {
+		t.Errorf("Skipped() after fetching = %+v, want none", skipped)
+	}
+}
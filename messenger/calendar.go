@@ -23,20 +23,25 @@ package messenger
 
 import (
 	"context"
+	"crypto/sha256"
 	"embed"
+	"encoding/hex"
 	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/avast/retry-go/v4"
+	"github.com/dkorunic/e-dnevnik-bot/format"
 	"github.com/dkorunic/e-dnevnik-bot/logger"
+	"github.com/dkorunic/e-dnevnik-bot/metrics"
 	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
 	"github.com/dkorunic/e-dnevnik-bot/oauth"
-	"go.uber.org/ratelimit"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 )
 
@@ -47,6 +52,12 @@ const (
 	CalendarMaxResults  = 100
 	CalendarCredentials = "assets/calendar_credentials.json" // embedded Google Calendar credentials file
 
+	CalendarReminderPopup = "popup"      // popup reminder method
+	CalendarReminderEmail = "email"      // e-mail reminder method
+	CalendarMaxReminders  = 5            // maximum number of reminders per event
+	CalendarMaxReminder   = 4 * 7 * 1440 // maximum reminder time (minutes before event)
+	CalendarMaxColorID    = 11           // highest Google Calendar event color ID
+	CalendarConfirmed     = "confirmed"  // status of a confirmed (not deleted) event
 )
 
 var (
@@ -65,10 +76,19 @@ var credentialFS embed.FS
 // - ch: a channel for receiving messages
 // - name: the name of the calendar
 // - tokFile: the path to the token file
+// - reminders: reminder times in minutes before the event (empty means calendar default reminders)
+// - method: the reminder method (popup or email)
+// - duration: the duration of timed events for exams with a known time (zero means all-day events only)
+// - prefix: the optional prefix prepended to event summaries
+// - colorID: the optional Google Calendar event color ID (1-11, zero means calendar default color)
+// - limit: optional rate limit override (requests per window)
+// - window: optional rate limit window override
 // - retries: the number of retry attempts for inserting a Google Calendar event
 //
 // It returns an error indicating any issues encountered during the execution of the function.
-func Calendar(ctx context.Context, ch <-chan interface{}, name, tokFile string, retries uint) error {
+func Calendar(ctx context.Context, ch <-chan interface{}, name, tokFile string, reminders []int, method string,
+	duration time.Duration, prefix string, colorID int, limit int, window time.Duration, retries uint,
+) error {
 	srv, calID, err := InitCalendar(ctx, tokFile, name)
 	if err != nil {
 		return err
@@ -77,7 +97,8 @@ func Calendar(ctx context.Context, ch <-chan interface{}, name, tokFile string,
 	logger.Debug().Msg("Started Google Calendar API messenger")
 
 	now := time.Now()
-	rl := ratelimit.New(CalendarAPILimit, ratelimit.Per(CalendarWindow))
+	rl, minDelay := newRateLimiter("Calendar", limit, window, CalendarAPILimit, CalendarWindow)
+	cb := newBreaker(ctx, "calendar")
 
 	// process all messages
 	for o := range ch {
@@ -92,8 +113,8 @@ func Calendar(ctx context.Context, ch <-chan interface{}, name, tokFile string,
 				continue
 			}
 
-			// skip non-exam events
-			if !g.IsExam {
+			// skip non-exam events and re-notifications of already inserted exams
+			if g.Code != msgtypes.Exam || g.Reminder {
 				continue
 			}
 
@@ -104,16 +125,14 @@ func Calendar(ctx context.Context, ch <-chan interface{}, name, tokFile string,
 				continue
 			}
 
-			// create an all day event
-			newEvent := &calendar.Event{
-				Summary: strings.Join([]string{g.Username, g.Subject}, " - Ispit iz: "),
-				Start: &calendar.EventDateTime{
-					Date: g.Timestamp.Format(time.DateOnly),
-				},
-				End: &calendar.EventDateTime{
-					Date: g.Timestamp.AddDate(0, 0, 1).Format(time.DateOnly),
-				},
-				Description: g.Fields[len(g.Fields)-1],
+			newEvent := newCalendarEvent(g, reminders, method, duration, prefix, colorID)
+
+			// circuit breaker: service is unavailable in this run
+			if cb.open() {
+				metrics.MessagesFailed.WithLabelValues("calendar").Inc()
+				cb.skip(g)
+
+				continue
 			}
 
 			rl.Take()
@@ -121,23 +140,102 @@ func Calendar(ctx context.Context, ch <-chan interface{}, name, tokFile string,
 			// retryable and cancellable attempt
 			err = retry.Do(
 				func() error {
-					_, err := srv.Events.Insert(calID, newEvent).Do()
-
-					return err
+					return calendarUpsert(ctx, srv, calID, newEvent)
 				},
 				retry.Attempts(retries),
 				retry.Context(ctx),
-				retry.Delay(CalendarMinDelay),
+				retry.Delay(minDelay),
 			)
+			cb.record(err)
+
 			if err != nil {
+				metrics.MessagesFailed.WithLabelValues("calendar").Inc()
 				logger.Error().Msgf("Unable to insert Google Calendar event: %v", err)
+
+				continue
 			}
+
+			metrics.MessagesSent.WithLabelValues("calendar").Inc()
 		}
 	}
 
 	return err
 }
 
+// newCalendarEvent creates an all-day exam event, or a timed event of the given duration if the exam time is known,
+// with optional reminders overriding calendar defaults, summary prefix and event color.
+func newCalendarEvent(g msgtypes.Message, reminders []int, method string, duration time.Duration, prefix string,
+	colorID int,
+) *calendar.Event {
+	// create an all day event
+	ev := &calendar.Event{
+		Id:      calendarEventID(g),
+		Summary: prefix + strings.Join([]string{format.DisplayName(g), g.Subject}, format.Lang().CalendarExamSep),
+		Start: &calendar.EventDateTime{
+			Date: g.Timestamp.Format(time.DateOnly),
+		},
+		End: &calendar.EventDateTime{
+			Date: g.Timestamp.AddDate(0, 0, 1).Format(time.DateOnly),
+		},
+		Description: g.Fields[len(g.Fields)-1],
+	}
+
+	// exams without time of day start at midnight
+	if h, m, s := g.Timestamp.Clock(); duration > 0 && h+m+s > 0 {
+		ev.Start = &calendar.EventDateTime{DateTime: g.Timestamp.Format(time.RFC3339)}
+		ev.End = &calendar.EventDateTime{DateTime: g.Timestamp.Add(duration).Format(time.RFC3339)}
+	}
+
+	if colorID > 0 {
+		ev.ColorId = strconv.Itoa(colorID)
+	}
+
+	if len(reminders) > 0 {
+		if method == "" {
+			method = CalendarReminderPopup
+		}
+
+		ev.Reminders = &calendar.EventReminders{
+			UseDefault:      false,
+			ForceSendFields: []string{"UseDefault"},
+		}
+
+		for _, r := range reminders {
+			ev.Reminders.Overrides = append(ev.Reminders.Overrides, &calendar.EventReminder{
+				Method:          method,
+				Minutes:         int64(r),
+				ForceSendFields: []string{"Minutes"},
+			})
+		}
+	}
+
+	return ev
+}
+
+// calendarEventID returns a deterministic event ID derived from the user, subject and exam date, so that a re-sent or
+// edited exam updates the existing event instead of creating a duplicate. Hex digits are valid event ID characters.
+func calendarEventID(g msgtypes.Message) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{g.Username, g.Subject, g.Timestamp.Format(time.DateOnly)},
+		"\x00")))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// calendarUpsert inserts the event or, if an event with the same ID already exists (even if deleted), updates it.
+func calendarUpsert(ctx context.Context, srv *calendar.Service, calID string, ev *calendar.Event) error {
+	_, err := srv.Events.Insert(calID, ev).Context(ctx).Do()
+
+	var gErr *googleapi.Error
+	if errors.As(err, &gErr) && gErr.Code == http.StatusConflict {
+		logger.Debug().Msgf("Google Calendar event %v already exists, updating it", ev.Id)
+
+		ev.Status = CalendarConfirmed
+		_, err = srv.Events.Update(calID, ev.Id, ev).Context(ctx).Do()
+	}
+
+	return err
+}
+
 // InitCalendar initializes a Google Calendar service and retrieves the calendar ID.
 //
 // ctx: The context.Context for the function.
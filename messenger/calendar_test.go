@@ -0,0 +1,188 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+	"github.com/goccy/go-json"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+)
+
+func TestNewCalendarEvent(t *testing.T) {
+	g := msgtypes.Message{
+		Username:     "korisnik@skole.hr",
+		Subject:      "Matematika",
+		Code:         msgtypes.Exam,
+		Descriptions: []string{"Datum ispita", "Napomena"},
+		Fields:       []string{"10.01.2025.", "Pisana provjera"},
+		Timestamp:    time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC),
+	}
+
+	ev := newCalendarEvent(g, nil, "", 45*time.Minute, "", 0)
+	if ev.Start.Date != "2025-01-10" || ev.End.Date != "2025-01-11" || ev.Start.DateTime != "" {
+		t.Errorf("expected all-day event, got start %+v, end %+v", ev.Start, ev.End)
+	}
+
+	if ev.Reminders != nil {
+		t.Errorf("expected default reminders, got %+v", ev.Reminders)
+	}
+
+	g.Timestamp = time.Date(2025, 1, 10, 8, 0, 0, 0, time.UTC)
+
+	ev = newCalendarEvent(g, []int{1440, 0}, "", 45*time.Minute, "", 0)
+	if ev.Start.DateTime != "2025-01-10T08:00:00Z" || ev.End.DateTime != "2025-01-10T08:45:00Z" || ev.Start.Date != "" {
+		t.Errorf("expected timed event, got start %+v, end %+v", ev.Start, ev.End)
+	}
+
+	if ev.Reminders == nil || ev.Reminders.UseDefault || len(ev.Reminders.Overrides) != 2 {
+		t.Fatalf("expected reminder overrides, got %+v", ev.Reminders)
+	}
+
+	if r := ev.Reminders.Overrides[0]; r.Method != CalendarReminderPopup || r.Minutes != 1440 {
+		t.Errorf("unexpected reminder: %+v", r)
+	}
+
+	if ev.Summary != "korisnik@skole.hr - Ispit iz: Matematika" || ev.Description != "Pisana provjera" {
+		t.Errorf("unexpected summary %q or description %q", ev.Summary, ev.Description)
+	}
+
+	if ev.ColorId != "" {
+		t.Errorf("expected default color, got %q", ev.ColorId)
+	}
+
+	ev = newCalendarEvent(g, nil, "", 0, "[Škola] ", 11)
+	if ev.Start.Date != "2025-01-10" {
+		t.Errorf("expected all-day event without duration, got start %+v", ev.Start)
+	}
+
+	if ev.Summary != "[Škola] korisnik@skole.hr - Ispit iz: Matematika" || ev.ColorId != "11" {
+		t.Errorf("unexpected summary %q or color %q", ev.Summary, ev.ColorId)
+	}
+}
+
+func TestCalendarEventID(t *testing.T) {
+	g := msgtypes.Message{
+		Username:  "korisnik@skole.hr",
+		Subject:   "Matematika",
+		Fields:    []string{"10.01.2025.", "Pisana provjera"},
+		Timestamp: time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC),
+	}
+
+	edited := g
+	edited.Fields = []string{"10.01.2025.", "Usmeni ispit"}
+	edited.Timestamp = time.Date(2025, 1, 10, 8, 0, 0, 0, time.UTC)
+
+	if calendarEventID(g) != calendarEventID(edited) {
+		t.Errorf("calendarEventID() differs for an edited exam on the same date")
+	}
+
+	moved := g
+	moved.Timestamp = g.Timestamp.AddDate(0, 0, 1)
+
+	if calendarEventID(g) == calendarEventID(moved) {
+		t.Errorf("calendarEventID() matches for an exam on a different date")
+	}
+}
+
+func TestCalendarUpsert(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		existing = map[string]bool{}
+		inserts  int
+		updates  int
+	)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /calendars/{cal}/events", func(w http.ResponseWriter, r *http.Request) {
+		var ev calendar.Event
+		if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+			t.Errorf("unable to decode body: %v", err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if existing[ev.Id] {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"error":{"code":409,"message":"The requested identifier already exists."}}`))
+
+			return
+		}
+
+		existing[ev.Id] = true
+		inserts++
+
+		_ = json.NewEncoder(w).Encode(ev)
+	})
+	mux.HandleFunc("PUT /calendars/{cal}/events/{id}", func(w http.ResponseWriter, r *http.Request) {
+		var ev calendar.Event
+		if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+			t.Errorf("unable to decode body: %v", err)
+		}
+
+		if ev.Status != CalendarConfirmed || ev.Id != r.PathValue("id") {
+			t.Errorf("unexpected update of %v: %+v", r.PathValue("id"), ev)
+		}
+
+		mu.Lock()
+		updates++
+		mu.Unlock()
+
+		_ = json.NewEncoder(w).Encode(ev)
+	})
+
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	srv, err := calendar.NewService(context.Background(), option.WithEndpoint(ts.URL+"/"),
+		option.WithHTTPClient(ts.Client()))
+	if err != nil {
+		t.Fatalf("unable to create calendar service: %v", err)
+	}
+
+	g := msgtypes.Message{
+		Username:  "korisnik@skole.hr",
+		Subject:   "Matematika",
+		Code:      msgtypes.Exam,
+		Fields:    []string{"10.01.2025.", "Pisana provjera"},
+		Timestamp: time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC),
+	}
+
+	for range 2 {
+		if err := calendarUpsert(context.Background(), srv, "cal", newCalendarEvent(g, nil, "", 0, "", 0)); err != nil {
+			t.Fatalf("calendarUpsert() = %v", err)
+		}
+	}
+
+	if inserts != 1 || updates != 1 {
+		t.Errorf("got %v inserts and %v updates, want 1 insert and 1 update", inserts, updates)
+	}
+}
@@ -32,14 +32,16 @@ import (
 	"github.com/bwmarrin/discordgo"
 	"github.com/dkorunic/e-dnevnik-bot/format"
 	"github.com/dkorunic/e-dnevnik-bot/logger"
+	"github.com/dkorunic/e-dnevnik-bot/metrics"
 	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
-	"go.uber.org/ratelimit"
 )
 
 const (
 	DiscordAPILimit = 50 // 50 API req/s per user/IP
 	DiscordWindow   = 1 * time.Second
 	DiscordMinDelay = DiscordWindow / DiscordAPILimit
+
+	DiscordEmptyField = "-" // placeholder for missing embed field name or value
 )
 
 var (
@@ -56,9 +58,11 @@ var (
 // ch: The channel from which to receive messages.
 // token: The Discord API token.
 // userIDs: The list of user IDs to send the messages to.
+// limit: The optional rate limit override (messages per window).
+// window: The optional rate limit window override.
 // retries: The number of attempts to send the message before giving up.
 // Returns an error if there was a problem sending the message.
-func Discord(ctx context.Context, ch <-chan interface{}, token string, userIDs []string, retries uint) error {
+func Discord(ctx context.Context, ch <-chan interface{}, token string, userIDs []string, limit int, window time.Duration, retries uint) error {
 	if token == "" {
 		return fmt.Errorf("%w", ErrDiscordEmptyAPIKey)
 	}
@@ -84,7 +88,8 @@ func Discord(ctx context.Context, ch <-chan interface{}, token string, userIDs [
 
 	logger.Debug().Msg("Started Discord messenger")
 
-	rl := ratelimit.New(DiscordAPILimit, ratelimit.Per(DiscordWindow))
+	rl, minDelay := newRateLimiter("Discord", limit, window, DiscordAPILimit, DiscordWindow)
+	cb := newBreaker(ctx, "discord")
 
 	// process all messages
 	for o := range ch {
@@ -100,30 +105,25 @@ func Discord(ctx context.Context, ch <-chan interface{}, token string, userIDs [
 			}
 
 			// format message as rich message with embedded data
-			fields := make([]*discordgo.MessageEmbedField, 0)
-			for ii := range g.Fields {
-				fields = append(fields, &discordgo.MessageEmbedField{
-					Name:   g.Descriptions[ii],
-					Value:  g.Fields[ii],
-					Inline: true,
-				})
-			}
-
-			sb := &strings.Builder{}
-			format.PlainFormatSubject(sb, g.Username, g.Subject, g.IsExam)
-
-			msg := &discordgo.MessageEmbed{
-				Title:  sb.String(),
-				Fields: fields,
-			}
+			msg := newDiscordEmbed(g)
 
 			// send to all recipients
 			for _, u := range userIDs {
+				// circuit breaker: service is unavailable in this run
+				if cb.open() {
+					metrics.MessagesFailed.WithLabelValues("discord").Inc()
+					cb.skip(g)
+
+					continue
+				}
+
 				rl.Take()
 
 				// create a new user/private channel if needed
 				c, err := dg.UserChannelCreate(u)
 				if err != nil {
+					cb.record(err)
+					metrics.MessagesFailed.WithLabelValues("discord").Inc()
 					logger.Error().Msgf("%v: %v", ErrDiscordCreatingChannel, err)
 
 					break
@@ -138,16 +138,55 @@ func Discord(ctx context.Context, ch <-chan interface{}, token string, userIDs [
 					},
 					retry.Attempts(retries),
 					retry.Context(ctx),
-					retry.Delay(DiscordMinDelay),
+					retry.Delay(minDelay),
 				)
+				cb.record(err)
+
 				if err != nil {
+					metrics.MessagesFailed.WithLabelValues("discord").Inc()
 					logger.Error().Msgf("%v: %v", ErrDiscordSendingMessage, err)
 
 					break
 				}
+
+				metrics.MessagesSent.WithLabelValues("discord").Inc()
 			}
 		}
 	}
 
 	return err
 }
+
+// newDiscordEmbed formats message as Discord rich message with message subject as a title, subject grade average as a
+// description and embedded fields.
+func newDiscordEmbed(g msgtypes.Message) *discordgo.MessageEmbed {
+	// pair descriptions with values in order, padding the shorter side as Discord rejects empty field names/values
+	fields := make([]*discordgo.MessageEmbedField, 0, max(len(g.Fields), len(g.Descriptions)))
+	for ii := range max(len(g.Fields), len(g.Descriptions)) {
+		name, value := DiscordEmptyField, DiscordEmptyField
+
+		if ii < len(g.Descriptions) && g.Descriptions[ii] != "" {
+			name = g.Descriptions[ii]
+		}
+
+		if ii < len(g.Fields) && g.Fields[ii] != "" {
+			value = format.FieldValue(g.Fields, g.PreviousFields, ii)
+		}
+
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   name,
+			Value:  value,
+			Inline: true,
+		})
+	}
+
+	sb := &strings.Builder{}
+	format.PlainFormatSubject(sb, g)
+
+	return &discordgo.MessageEmbed{
+		Title:       sb.String(),
+		Description: format.AverageLine(g.Average),
+		URL:         g.URL,
+		Fields:      fields,
+	}
+}
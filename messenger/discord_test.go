@@ -0,0 +1,81 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"testing"
+
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+)
+
+func TestNewDiscordEmbedMismatchedFields(t *testing.T) {
+	tests := []struct {
+		name         string
+		descriptions []string
+		fields       []string
+		want         [][2]string
+	}{
+		{
+			name:         "matched",
+			descriptions: []string{"Datum", "Ocjena"},
+			fields:       []string{"1.2.2025", "5"},
+			want:         [][2]string{{"Datum", "1.2.2025"}, {"Ocjena", "5"}},
+		},
+		{
+			name:         "missing field values",
+			descriptions: []string{"Datum", "Ocjena", "Bilješka"},
+			fields:       []string{"1.2.2025"},
+			want:         [][2]string{{"Datum", "1.2.2025"}, {"Ocjena", DiscordEmptyField}, {"Bilješka", DiscordEmptyField}},
+		},
+		{
+			name:         "missing descriptions",
+			descriptions: []string{"Datum"},
+			fields:       []string{"1.2.2025", "5"},
+			want:         [][2]string{{"Datum", "1.2.2025"}, {DiscordEmptyField, "5"}},
+		},
+		{
+			name: "empty",
+			want: [][2]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			embed := newDiscordEmbed(msgtypes.Message{
+				Username:     "korisnik@skole.hr",
+				Subject:      "Matematika",
+				Descriptions: tt.descriptions,
+				Fields:       tt.fields,
+			})
+
+			if len(embed.Fields) != len(tt.want) {
+				t.Fatalf("got %d fields, want %d", len(embed.Fields), len(tt.want))
+			}
+
+			for i, f := range embed.Fields {
+				if f.Name != tt.want[i][0] || f.Value != tt.want[i][1] {
+					t.Errorf("field %d = (%q, %q), want (%q, %q)", i, f.Name, f.Value, tt.want[i][0], tt.want[i][1])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/avast/retry-go/v4"
+	"github.com/bwmarrin/discordgo"
+	"github.com/dkorunic/e-dnevnik-bot/logger"
+	"github.com/dkorunic/e-dnevnik-bot/metrics"
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+	"github.com/goccy/go-json"
+)
+
+const (
+	DiscordWebhookAPILimit = 5 // 5 webhook req per 2s
+	DiscordWebhookWindow   = 2 * time.Second
+	DiscordWebhookMinDelay = DiscordWebhookWindow / DiscordWebhookAPILimit
+	DiscordWebhookTimeout  = 30 * time.Second
+)
+
+var (
+	ErrDiscordEmptyWebhooks = errors.New("empty list of Discord webhook URLs")
+	ErrDiscordWebhookStatus = errors.New("unexpected Discord webhook response")
+)
+
+// discordWebhookMessage is a Discord execute webhook API request body.
+type discordWebhookMessage struct {
+	Embeds []*discordgo.MessageEmbed `json:"embeds"`
+}
+
+// DiscordWebhook sends messages as rich messages to Discord channel webhooks, without a bot token.
+//
+// ctx: The context.Context that can be used to cancel the operation.
+// ch: The channel from which to receive messages.
+// webhooks: The list of Discord channel webhook URLs to send the messages to.
+// limit: The optional rate limit override (messages per window).
+// window: The optional rate limit window override.
+// retries: The number of attempts to send the message before giving up.
+// Returns an error if there was a problem sending the message.
+func DiscordWebhook(ctx context.Context, ch <-chan interface{}, webhooks []string, limit int, window time.Duration,
+	retries uint,
+) error {
+	if len(webhooks) == 0 {
+		return fmt.Errorf("%w", ErrDiscordEmptyWebhooks)
+	}
+
+	client := &http.Client{Timeout: DiscordWebhookTimeout}
+
+	logger.Debug().Msg("Started Discord webhook messenger")
+
+	rl, minDelay := newRateLimiter("Discord", limit, window, DiscordWebhookAPILimit, DiscordWebhookWindow)
+	cb := newBreaker(ctx, "discord")
+
+	var err error
+
+	// process all messages
+	for o := range ch {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			g, ok := o.(msgtypes.Message)
+			if !ok {
+				logger.Warn().Msg("Received invalid type from channel, trying to continue")
+
+				continue
+			}
+
+			var b []byte
+
+			// format message as rich message with embedded data
+			b, err = json.Marshal(discordWebhookMessage{Embeds: []*discordgo.MessageEmbed{newDiscordEmbed(g)}})
+			if err != nil {
+				logger.Error().Msgf("%v: %v", ErrDiscordSendingMessage, err)
+
+				continue
+			}
+
+			// send to all webhooks
+			for _, w := range webhooks {
+				// circuit breaker: service is unavailable in this run
+				if cb.open() {
+					metrics.MessagesFailed.WithLabelValues("discord").Inc()
+					cb.skip(g)
+
+					continue
+				}
+
+				rl.Take()
+
+				// retryable and cancellable attempt to send a message
+				err = retry.Do(
+					func() error {
+						return discordWebhookPost(ctx, client, w, b)
+					},
+					retry.Attempts(retries),
+					retry.Context(ctx),
+					retry.Delay(minDelay),
+				)
+				cb.record(err)
+
+				if err != nil {
+					metrics.MessagesFailed.WithLabelValues("discord").Inc()
+					logger.Error().Msgf("%v: %v", ErrDiscordSendingMessage, err)
+
+					break
+				}
+
+				metrics.MessagesSent.WithLabelValues("discord").Inc()
+			}
+		}
+	}
+
+	return err
+}
+
+// discordWebhookPost posts JSON message to Discord webhook URL, returning an error on non-2xx response.
+func discordWebhookPost(ctx context.Context, client *http.Client, webhook string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("%w: %v", ErrDiscordWebhookStatus, resp.Status)
+	}
+
+	return nil
+}
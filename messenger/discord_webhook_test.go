@@ -0,0 +1,93 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+	"github.com/goccy/go-json"
+)
+
+func TestDiscordWebhookPost(t *testing.T) {
+	type request struct {
+		path string
+		msg  discordWebhookMessage
+	}
+
+	reqs := make(chan request, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/webhooks/404/token" {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		var m discordWebhookMessage
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			t.Errorf("unable to decode body: %v", err)
+		}
+
+		reqs <- request{r.URL.Path, m}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	b, err := json.Marshal(discordWebhookMessage{Embeds: []*discordgo.MessageEmbed{newDiscordEmbed(msgtypes.Message{
+		Username:     "korisnik@skole.hr",
+		Subject:      "Matematika",
+		Code:         msgtypes.Grade,
+		Descriptions: []string{"Datum", "Ocjena"},
+		Fields:       []string{"2.1.", "5"},
+		Average:      4.5,
+	})}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := discordWebhookPost(context.Background(), srv.Client(), srv.URL+"/api/webhooks/123/token", b); err != nil {
+		t.Fatalf("discordWebhookPost() = %v", err)
+	}
+
+	r := <-reqs
+	if r.path != "/api/webhooks/123/token" || len(r.msg.Embeds) != 1 {
+		t.Fatalf("unexpected request: %+v", r)
+	}
+
+	e := r.msg.Embeds[0]
+	if e.Title != "Nova ocjena: korisnik@skole.hr / Matematika" || e.Description != "trenutni prosjek: 4.50" ||
+		len(e.Fields) != 2 || e.Fields[1].Name != "Ocjena" || e.Fields[1].Value != "5" {
+		t.Errorf("unexpected embed: %+v", e)
+	}
+
+	err = discordWebhookPost(context.Background(), srv.Client(), srv.URL+"/api/webhooks/404/token", b)
+	if !errors.Is(err, ErrDiscordWebhookStatus) {
+		t.Errorf("discordWebhookPost() = %v, want %v", err, ErrDiscordWebhookStatus)
+	}
+}
@@ -0,0 +1,196 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/avast/retry-go/v4"
+	"github.com/dkorunic/e-dnevnik-bot/format"
+	"github.com/dkorunic/e-dnevnik-bot/logger"
+	"github.com/dkorunic/e-dnevnik-bot/metrics"
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+	"github.com/goccy/go-json"
+)
+
+const (
+	GotifyAPILimit = 5 // self-hosted, but be gentle anyway
+	GotifyWindow   = 1 * time.Second
+	GotifyMinDelay = GotifyWindow / GotifyAPILimit
+	GotifyTimeout  = 30 * time.Second
+)
+
+var (
+	ErrGotifyEmptyServer    = errors.New("empty Gotify server URL")
+	ErrGotifyEmptyAPIKey    = errors.New("empty Gotify application token")
+	ErrGotifySendingMessage = errors.New("error sending Gotify message")
+	ErrGotifyStatus         = errors.New("unexpected Gotify API response")
+)
+
+// gotifyMessage is a Gotify message API request body.
+type gotifyMessage struct {
+	Title    string `json:"title"`
+	Message  string `json:"message"`
+	Priority int    `json:"priority"`
+}
+
+// Gotify sends messages through a self-hosted Gotify server.
+//
+// ctx: the context in which the function is executed.
+// ch: the channel from which messages are received.
+// serverURL: the base URL of the Gotify server.
+// appToken: the Gotify application token.
+// priority: the message priority.
+// insecure: whether to skip TLS certificate verification of the server.
+// limit: the optional rate limit override (messages per window).
+// window: the optional rate limit window override.
+// retries: the number of retries in case of failure.
+// error: an error if there was a problem sending the message.
+func Gotify(ctx context.Context, ch <-chan interface{}, serverURL, appToken string, priority int, insecure bool,
+	limit int, window time.Duration, retries uint,
+) error {
+	if serverURL == "" {
+		return fmt.Errorf("%w", ErrGotifyEmptyServer)
+	}
+
+	if appToken == "" {
+		return fmt.Errorf("%w", ErrGotifyEmptyAPIKey)
+	}
+
+	apiURL, err := gotifyURL(serverURL, appToken)
+	if err != nil {
+		return err
+	}
+
+	client := newHTTPClient("Gotify", GotifyTimeout, insecure)
+
+	logger.Debug().Msg("Started Gotify messenger")
+
+	rl, minDelay := newRateLimiter("Gotify", limit, window, GotifyAPILimit, GotifyWindow)
+	cb := newBreaker(ctx, "gotify")
+
+	// process all messages
+	for o := range ch {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			g, ok := o.(msgtypes.Message)
+			if !ok {
+				logger.Warn().Msg("Received invalid type from channel, trying to continue")
+
+				continue
+			}
+
+			var b []byte
+
+			b, err = json.Marshal(newGotifyMessage(g, priority))
+			if err != nil {
+				logger.Error().Msgf("%v: %v", ErrGotifySendingMessage, err)
+
+				continue
+			}
+
+			// circuit breaker: service is unavailable in this run
+			if cb.open() {
+				metrics.MessagesFailed.WithLabelValues("gotify").Inc()
+				cb.skip(g)
+
+				continue
+			}
+
+			rl.Take()
+
+			// retryable and cancellable attempt to send a message
+			err = retry.Do(
+				func() error {
+					return gotifyPost(ctx, client, apiURL, b)
+				},
+				retry.Attempts(retries),
+				retry.Context(ctx),
+				retry.Delay(minDelay),
+			)
+			cb.record(err)
+
+			if err != nil {
+				metrics.MessagesFailed.WithLabelValues("gotify").Inc()
+				logger.Error().Msgf("%v: %v", ErrGotifySendingMessage, err)
+
+				continue
+			}
+
+			metrics.MessagesSent.WithLabelValues("gotify").Inc()
+		}
+	}
+
+	return err
+}
+
+// gotifyURL returns Gotify message API URL for the server, with the application token as a query parameter.
+func gotifyURL(serverURL, appToken string) (string, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return "", err
+	}
+
+	u = u.JoinPath("message")
+	u.RawQuery = url.Values{"token": {appToken}}.Encode()
+
+	return u.String(), nil
+}
+
+// newGotifyMessage builds Gotify message with one-line message summary as a title and cleartext message as a body.
+func newGotifyMessage(g msgtypes.Message, priority int) gotifyMessage {
+	return gotifyMessage{
+		Title:    format.SummaryTitle(g),
+		Message:  format.PlainMsg(g),
+		Priority: priority,
+	}
+}
+
+// gotifyPost posts JSON message to Gotify message API URL, returning an error on non-2xx response.
+func gotifyPost(ctx context.Context, client *http.Client, apiURL string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("%w: %v", ErrGotifyStatus, resp.Status)
+	}
+
+	return nil
+}
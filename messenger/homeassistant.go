@@ -0,0 +1,208 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/avast/retry-go/v4"
+	"github.com/dkorunic/e-dnevnik-bot/format"
+	"github.com/dkorunic/e-dnevnik-bot/logger"
+	"github.com/dkorunic/e-dnevnik-bot/metrics"
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+	"github.com/goccy/go-json"
+)
+
+const (
+	HomeAssistantAPILimit = 5 // self-hosted, but be gentle anyway
+	HomeAssistantWindow   = 1 * time.Second
+	HomeAssistantMinDelay = HomeAssistantWindow / HomeAssistantAPILimit
+	HomeAssistantTimeout  = 30 * time.Second
+)
+
+var (
+	ErrHomeAssistantEmptyServer    = errors.New("empty Home Assistant server URL")
+	ErrHomeAssistantEmptyAPIKey    = errors.New("empty Home Assistant access token")
+	ErrHomeAssistantEmptyServices  = errors.New("empty list of Home Assistant notify services")
+	ErrHomeAssistantSendingMessage = errors.New("error sending Home Assistant message")
+	ErrHomeAssistantStatus         = errors.New("unexpected Home Assistant API response")
+)
+
+// homeAssistantMessage is a Home Assistant notify service call request body.
+type homeAssistantMessage struct {
+	Title   string `json:"title"`
+	Message string `json:"message"`
+}
+
+// HomeAssistant sends messages through Home Assistant notify services.
+//
+// ctx: the context in which the function is executed.
+// ch: the channel from which messages are received.
+// serverURL: the base URL of the Home Assistant server.
+// accessToken: the Home Assistant long-lived access token.
+// services: the names of the notify services (ie. mobile_app_phone).
+// insecure: whether to skip TLS certificate verification of the server.
+// limit: the optional rate limit override (messages per window).
+// window: the optional rate limit window override.
+// retries: the number of retries in case of failure.
+// error: an error if there was a problem sending the message.
+func HomeAssistant(ctx context.Context, ch <-chan interface{}, serverURL, accessToken string, services []string,
+	insecure bool, limit int, window time.Duration, retries uint,
+) error {
+	if serverURL == "" {
+		return fmt.Errorf("%w", ErrHomeAssistantEmptyServer)
+	}
+
+	if accessToken == "" {
+		return fmt.Errorf("%w", ErrHomeAssistantEmptyAPIKey)
+	}
+
+	if len(services) == 0 {
+		return fmt.Errorf("%w", ErrHomeAssistantEmptyServices)
+	}
+
+	client := newHTTPClient("Home Assistant", HomeAssistantTimeout, insecure)
+
+	logger.Debug().Msg("Started Home Assistant messenger")
+
+	rl, minDelay := newRateLimiter("Home Assistant", limit, window, HomeAssistantAPILimit, HomeAssistantWindow)
+	cb := newBreaker(ctx, "homeassistant")
+
+	var err error
+
+	// process all messages
+	for o := range ch {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			g, ok := o.(msgtypes.Message)
+			if !ok {
+				logger.Warn().Msg("Received invalid type from channel, trying to continue")
+
+				continue
+			}
+
+			var b []byte
+
+			b, err = json.Marshal(newHomeAssistantMessage(g))
+			if err != nil {
+				logger.Error().Msgf("%v: %v", ErrHomeAssistantSendingMessage, err)
+
+				continue
+			}
+
+			// send to all notify services
+			for _, s := range services {
+				var apiURL string
+
+				apiURL, err = homeAssistantURL(serverURL, s)
+				if err != nil {
+					logger.Error().Msgf("%v: %v", ErrHomeAssistantSendingMessage, err)
+
+					return err
+				}
+
+				// circuit breaker: service is unavailable in this run
+				if cb.open() {
+					metrics.MessagesFailed.WithLabelValues("homeassistant").Inc()
+					cb.skip(g)
+
+					continue
+				}
+
+				rl.Take()
+
+				// retryable and cancellable attempt to send a message
+				err = retry.Do(
+					func() error {
+						return homeAssistantPost(ctx, client, apiURL, accessToken, b)
+					},
+					retry.Attempts(retries),
+					retry.Context(ctx),
+					retry.Delay(minDelay),
+				)
+				cb.record(err)
+
+				if err != nil {
+					metrics.MessagesFailed.WithLabelValues("homeassistant").Inc()
+					logger.Error().Msgf("%v: %v", ErrHomeAssistantSendingMessage, err)
+
+					break
+				}
+
+				metrics.MessagesSent.WithLabelValues("homeassistant").Inc()
+			}
+		}
+	}
+
+	return err
+}
+
+// homeAssistantURL returns Home Assistant notify service call API URL for the server and the service name.
+func homeAssistantURL(serverURL, service string) (string, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return "", err
+	}
+
+	return u.JoinPath("api", "services", "notify", service).String(), nil
+}
+
+// newHomeAssistantMessage builds Home Assistant notification with one-line message summary as a title and cleartext message
+// as a body.
+func newHomeAssistantMessage(g msgtypes.Message) homeAssistantMessage {
+	return homeAssistantMessage{
+		Title:   format.SummaryTitle(g),
+		Message: format.PlainMsg(g),
+	}
+}
+
+// homeAssistantPost posts JSON notification to Home Assistant notify service with a bearer access token, returning an
+// error on non-2xx response.
+func homeAssistantPost(ctx context.Context, client *http.Client, apiURL, accessToken string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("%w: %v", ErrHomeAssistantStatus, resp.Status)
+	}
+
+	return nil
+}
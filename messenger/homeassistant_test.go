@@ -0,0 +1,80 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+	"github.com/goccy/go-json"
+)
+
+func TestHomeAssistantPost(t *testing.T) {
+	type request struct {
+		path, auth string
+		msg        homeAssistantMessage
+	}
+
+	reqs := make(chan request, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m homeAssistantMessage
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			t.Errorf("unable to decode body: %v", err)
+		}
+
+		reqs <- request{r.URL.Path, r.Header.Get("Authorization"), m}
+	}))
+	defer srv.Close()
+
+	apiURL, err := homeAssistantURL(srv.URL+"/", "mobile_app_phone")
+	if err != nil {
+		t.Fatalf("homeAssistantURL() = %v", err)
+	}
+
+	b, err := json.Marshal(newHomeAssistantMessage(msgtypes.Message{
+		Username:     "korisnik@skole.hr",
+		Subject:      "Matematika",
+		Code:         msgtypes.Exam,
+		Descriptions: []string{"Datum ispita", "Napomena"},
+		Fields:       []string{"10.01.2025.", "Pisana provjera"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := homeAssistantPost(context.Background(), srv.Client(), apiURL, "token", b); err != nil {
+		t.Fatalf("homeAssistantPost() = %v", err)
+	}
+
+	r := <-reqs
+	if r.path != "/api/services/notify/mobile_app_phone" || r.auth != "Bearer token" {
+		t.Errorf("unexpected request: %+v", r)
+	}
+
+	if r.msg.Title != "korisnik@skole.hr / Matematika: ispit Pisana provjera (10.01.2025.)" {
+		t.Errorf("unexpected title: %q", r.msg.Title)
+	}
+}
@@ -0,0 +1,84 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dkorunic/e-dnevnik-bot/fetch"
+	"github.com/dkorunic/e-dnevnik-bot/format"
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+	"github.com/jordic/goics"
+)
+
+const (
+	ICSFilename = "ispit.ics"
+	ICSProdID   = "-//dkorunic//e-dnevnik-bot//HR"
+)
+
+// examEvent is an ICS emitter for a single all-day exam event.
+type examEvent msgtypes.Message
+
+// EmitICal builds VCALENDAR with a single all-day VEVENT for an exam.
+func (e examEvent) EmitICal() goics.Componenter {
+	c := goics.NewComponent()
+	c.SetType("VCALENDAR")
+	c.AddProperty("VERSION", "2.0")
+	c.AddProperty("PRODID", ICSProdID)
+	c.AddProperty("METHOD", "PUBLISH")
+
+	s := goics.NewComponent()
+	s.SetType("VEVENT")
+
+	k, v := goics.FormatDateTime("DTSTAMP", time.Now())
+	s.AddProperty(k, v)
+
+	k, v = goics.FormatDateField(fetch.EventDateStart, e.Timestamp)
+	s.AddProperty(k, v)
+
+	k, v = goics.FormatDateField("DTEND", e.Timestamp.AddDate(0, 0, 1))
+	s.AddProperty(k, v)
+
+	s.AddProperty("UID", fmt.Sprintf("%x@e-dnevnik-bot",
+		sha256.Sum256([]byte(strings.Join([]string{e.Username, e.Subject, e.Timestamp.Format(time.DateOnly)}, "/")))))
+	s.AddProperty(fetch.EventSummary, strings.Join([]string{format.DisplayName(msgtypes.Message(e)), e.Subject}, format.Lang().CalendarExamSep))
+
+	if len(e.Fields) > 0 {
+		s.AddProperty(fetch.EventDescription, e.Fields[len(e.Fields)-1])
+	}
+
+	c.AddComponent(s)
+
+	return c
+}
+
+// examICS encodes an exam message as an ICS calendar with a single all-day event.
+func examICS(g msgtypes.Message) []byte {
+	b := &bytes.Buffer{}
+	goics.NewICalEncode(b).Encode(examEvent(g))
+
+	return b.Bytes()
+}
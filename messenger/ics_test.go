@@ -0,0 +1,55 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+)
+
+func TestExamICS(t *testing.T) {
+	ics := string(examICS(msgtypes.Message{
+		Timestamp:    time.Date(2025, time.January, 10, 0, 0, 0, 0, time.Local),
+		Username:     "korisnik@skole.hr",
+		Subject:      "Matematika",
+		Code:         msgtypes.Exam,
+		Descriptions: []string{"Datum ispita", "Napomena"},
+		Fields:       []string{"10.01.2025.", "Pisana provjera"},
+	}))
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"BEGIN:VEVENT\r\n",
+		"DTSTART;VALUE=DATE:20250110\r\n",
+		"DTEND;VALUE=DATE:20250111\r\n",
+		"SUMMARY:korisnik@skole.hr - Ispit iz: Matematika\r\n",
+		"DESCRIPTION:Pisana provjera\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("examICS() missing %q in:\n%s", want, ics)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/avast/retry-go/v4"
+	"github.com/dkorunic/e-dnevnik-bot/format"
+	"github.com/dkorunic/e-dnevnik-bot/logger"
+	"github.com/dkorunic/e-dnevnik-bot/metrics"
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+	"github.com/ergochat/irc-go/ircevent"
+	"github.com/ergochat/irc-go/ircmsg"
+)
+
+const (
+	IRCAPILimit       = 1 // typical ircd flood protection permits about one line per second
+	IRCWindow         = 1 * time.Second
+	IRCMinDelay       = IRCWindow / IRCAPILimit
+	IRCTimeout        = 30 * time.Second
+	IRCReconnectDelay = 10 * time.Second
+	IRCMaxLen         = 350 // single line length limit, to fit in 512 byte IRC line with prefix and target
+	IRCRealName       = "e-Dnevnik bot"
+	IRCQuitMessage    = "e-Dnevnik bot done"
+)
+
+var (
+	ErrIRCEmptyServer    = errors.New("empty IRC server")
+	ErrIRCEmptyNick      = errors.New("empty IRC nick")
+	ErrIRCEmptyChannels  = errors.New("empty list of IRC channels")
+	ErrIRCConnect        = errors.New("error connecting to IRC server")
+	ErrIRCDisconnected   = errors.New("disconnected from IRC server")
+	ErrIRCSendingMessage = errors.New("error sending IRC message")
+)
+
+// IRC sends single-line messages to IRC channels or nicks. It joins all channels on every (re)connection and, when
+// disconnected, reconnects in the background while sending attempts are retried. IRC has no persistent queue, so
+// delivery is at-most-once: messages sent while the connection is dropping can be lost without an error.
+//
+// ctx: the context in which the function is executed.
+// ch: the channel from which messages are received.
+// server: the IRC server hostname.
+// port: the IRC server port.
+// useTLS: whether to connect with TLS.
+// nick: the IRC nick of the bot.
+// channels: the channels (#channel) or nicks to send messages to.
+// saslUser: the optional SASL PLAIN username (SASL is not used if empty).
+// saslPass: the optional SASL PLAIN password.
+// limit: the optional rate limit override (messages per window).
+// window: the optional rate limit window override.
+// retries: the number of retries in case of failure.
+// error: an error if there was a problem sending the message.
+func IRC(ctx context.Context, ch <-chan interface{}, server string, port int, useTLS bool, nick string,
+	channels []string, saslUser, saslPass string, limit int, window time.Duration, retries uint,
+) error {
+	if server == "" {
+		return fmt.Errorf("%w", ErrIRCEmptyServer)
+	}
+
+	if nick == "" {
+		return fmt.Errorf("%w", ErrIRCEmptyNick)
+	}
+
+	if len(channels) == 0 {
+		return fmt.Errorf("%w", ErrIRCEmptyChannels)
+	}
+
+	conn := &ircevent.Connection{
+		Server:          net.JoinHostPort(server, strconv.Itoa(port)),
+		Nick:            nick,
+		User:            nick,
+		RealName:        IRCRealName,
+		QuitMessage:     IRCQuitMessage,
+		UseTLS:          useTLS,
+		UseSASL:         saslUser != "",
+		SASLLogin:       saslUser,
+		SASLPassword:    saslPass,
+		Timeout:         IRCTimeout,
+		ReconnectFreq:   IRCReconnectDelay,
+		AllowTruncation: true,
+		Log:             log.New(ircLogWriter{}, "", 0),
+	}
+
+	// (re)join all channels on every registration
+	joined := make(chan struct{}, 1)
+
+	conn.AddConnectCallback(func(ircmsg.Message) {
+		for _, c := range channels {
+			if strings.HasPrefix(c, "#") || strings.HasPrefix(c, "&") {
+				_ = conn.Join(c)
+			}
+		}
+
+		select {
+		case joined <- struct{}{}:
+		default:
+		}
+	})
+
+	var quitting atomic.Bool
+
+	conn.AddDisconnectCallback(func(ircmsg.Message) {
+		if quitting.Load() {
+			return
+		}
+
+		logger.Warn().Msgf("%v %v, reconnecting", ErrIRCDisconnected, conn.Server)
+	})
+
+	if err := conn.Connect(); err != nil {
+		return fmt.Errorf("%w: %w", ErrIRCConnect, err)
+	}
+
+	// reconnect loop, exits after Quit
+	done := make(chan struct{})
+
+	go func() {
+		conn.Loop()
+		close(done)
+	}()
+
+	defer func() {
+		quitting.Store(true)
+		conn.Quit()
+
+		select {
+		case <-done:
+		case <-time.After(IRCTimeout):
+		}
+	}()
+
+	// wait for end of MOTD and joins before sending
+	select {
+	case <-joined:
+	case <-time.After(IRCTimeout):
+		return fmt.Errorf("%w: registration timeout", ErrIRCConnect)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	logger.Debug().Msg("Started IRC messenger")
+
+	rl, minDelay := newRateLimiter("IRC", limit, window, IRCAPILimit, IRCWindow)
+	cb := newBreaker(ctx, "irc")
+
+	var err error
+
+	// process all messages
+	for o := range ch {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			g, ok := o.(msgtypes.Message)
+			if !ok {
+				logger.Warn().Msg("Received invalid type from channel, trying to continue")
+
+				continue
+			}
+
+			m := format.SMSMsg(g, IRCMaxLen)
+
+			// send to all recipients: channels and nicks are permitted
+			for _, c := range channels {
+				// circuit breaker: service is unavailable in this run
+				if cb.open() {
+					metrics.MessagesFailed.WithLabelValues("irc").Inc()
+					cb.skip(g)
+
+					continue
+				}
+
+				rl.Take()
+
+				// retryable and cancellable attempt to send a message, waiting for a reconnection if needed
+				err = retry.Do(
+					func() error {
+						if !conn.Connected() {
+							return ErrIRCDisconnected
+						}
+
+						return conn.Privmsg(c, m)
+					},
+					retry.Attempts(retries),
+					retry.Context(ctx),
+					retry.Delay(max(minDelay, IRCReconnectDelay)),
+				)
+				cb.record(err)
+
+				if err != nil {
+					metrics.MessagesFailed.WithLabelValues("irc").Inc()
+					logger.Error().Msgf("%v: %v", ErrIRCSendingMessage, err)
+
+					continue
+				}
+
+				metrics.MessagesSent.WithLabelValues("irc").Inc()
+			}
+		}
+	}
+
+	return err
+}
+
+// ircLogWriter forwards IRC client library log output to debug log.
+type ircLogWriter struct{}
+
+// Write logs a single IRC client library log line.
+func (ircLogWriter) Write(p []byte) (int, error) {
+	logger.Debug().Msgf("IRC: %v", strings.TrimSpace(string(p)))
+
+	return len(p), nil
+}
@@ -0,0 +1,134 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+)
+
+// fakeIRCServer accepts a single IRC client, completes registration and records all received lines.
+func fakeIRCServer(t *testing.T) (string, int, func() []string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = ln.Close() })
+
+	var (
+		mu    sync.Mutex
+		lines []string
+		done  = make(chan struct{})
+	)
+
+	go func() {
+		defer close(done)
+
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+
+		r := bufio.NewScanner(c)
+		for r.Scan() {
+			l := strings.TrimSpace(r.Text())
+
+			mu.Lock()
+			lines = append(lines, l)
+			mu.Unlock()
+
+			switch {
+			case strings.HasPrefix(l, "USER "):
+				_, _ = c.Write([]byte(":irc.test 001 bot :Welcome\r\n:irc.test 376 bot :End of MOTD\r\n"))
+			case strings.HasPrefix(l, "QUIT"):
+				return
+			}
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	p, _ := strconv.Atoi(port)
+
+	return host, p, func() []string {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		return lines
+	}
+}
+
+func TestIRC(t *testing.T) {
+	host, port, received := fakeIRCServer(t)
+
+	ch := make(chan interface{}, 1)
+	ch <- msgtypes.Message{
+		Username:     "korisnik@skole.hr",
+		Subject:      "Matematika",
+		Code:         msgtypes.Grade,
+		Descriptions: []string{"Ocjena"},
+		Fields:       []string{"5"},
+	}
+	close(ch)
+
+	err := IRC(context.Background(), ch, host, port, false, "bot", []string{"#razred", "roditelj"}, "", "", 100,
+		time.Second, 1)
+	if err != nil {
+		t.Fatalf("IRC() error = %v", err)
+	}
+
+	var joins, msgs []string
+
+	for _, l := range received() {
+		switch {
+		case strings.HasPrefix(l, "JOIN "):
+			joins = append(joins, l)
+		case strings.HasPrefix(l, "PRIVMSG "):
+			msgs = append(msgs, l)
+		}
+	}
+
+	if len(joins) != 1 || joins[0] != "JOIN #razred" {
+		t.Errorf("expected a single channel join, got %q", joins)
+	}
+
+	if len(msgs) != 2 || !strings.HasPrefix(msgs[0], "PRIVMSG #razred :") ||
+		!strings.HasPrefix(msgs[1], "PRIVMSG roditelj :") || !strings.HasSuffix(msgs[0], "Matematika: ocjena 5") {
+		t.Errorf("unexpected messages: %q", msgs)
+	}
+}
@@ -0,0 +1,102 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/dkorunic/e-dnevnik-bot/logger"
+	"github.com/dkorunic/e-dnevnik-bot/metrics"
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+	"github.com/goccy/go-json"
+)
+
+const JSONLinesFileMode = 0o600
+
+var (
+	ErrJSONLinesEmptyPath    = errors.New("empty JSON Lines file path")
+	ErrJSONLinesWriteMessage = errors.New("error writing JSON Lines message")
+)
+
+// JSONLines appends every message as a single line of JSON to a local file.
+//
+// ctx: the context in which the function is executed.
+// ch: the channel from which messages are received.
+// filePath: the path of the file to append messages to, created if missing.
+// error: an error if there was a problem opening the file or writing a message.
+func JSONLines(ctx context.Context, ch <-chan interface{}, filePath string) error {
+	if filePath == "" {
+		return fmt.Errorf("%w", ErrJSONLinesEmptyPath)
+	}
+
+	f, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, JSONLinesFileMode)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	logger.Debug().Msg("Started JSON Lines messenger")
+
+	// process all messages
+	for o := range ch {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			g, ok := o.(msgtypes.Message)
+			if !ok {
+				logger.Warn().Msg("Received invalid type from channel, trying to continue")
+
+				continue
+			}
+
+			err = jsonLinesWrite(f, g)
+			if err != nil {
+				metrics.MessagesFailed.WithLabelValues("jsonlines").Inc()
+				logger.Error().Msgf("%v: %v", ErrJSONLinesWriteMessage, err)
+
+				continue
+			}
+
+			metrics.MessagesSent.WithLabelValues("jsonlines").Inc()
+		}
+	}
+
+	return err
+}
+
+// jsonLinesWrite marshals a message and writes it followed by a newline in a single write call, so that
+// concurrent appenders never interleave partial lines.
+func jsonLinesWrite(f *os.File, g msgtypes.Message) error {
+	b, err := json.Marshal(g)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(append(b, '\n'))
+
+	return err
+}
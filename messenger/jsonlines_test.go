@@ -0,0 +1,98 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+	"github.com/goccy/go-json"
+)
+
+func TestJSONLines(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "alerts.jsonl")
+
+	// pre-existing content must be preserved
+	if err := os.WriteFile(path, []byte("{}\n"), JSONLinesFileMode); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs := []msgtypes.Message{
+		{Username: "user", Subject: "Matematika", Descriptions: []string{"Ocjena"}, Fields: []string{"5"}},
+		{Username: "user", Subject: "Fizika", Code: msgtypes.Exam},
+	}
+
+	ch := make(chan interface{}, len(msgs)+1)
+	ch <- "invalid"
+
+	for _, m := range msgs {
+		ch <- m
+	}
+
+	close(ch)
+
+	if err := JSONLines(context.Background(), ch, path); err != nil {
+		t.Fatalf("JSONLines() error = %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var lines []string
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+
+	if len(lines) != len(msgs)+1 {
+		t.Fatalf("got %d lines, want %d", len(lines), len(msgs)+1)
+	}
+
+	for i, m := range msgs {
+		var got msgtypes.Message
+		if err := json.Unmarshal([]byte(lines[i+1]), &got); err != nil {
+			t.Fatalf("line %d: %v", i+1, err)
+		}
+
+		if got.Subject != m.Subject || got.Code != m.Code {
+			t.Errorf("line %d = %+v, want %+v", i+1, got, m)
+		}
+	}
+}
+
+func TestJSONLinesEmptyPath(t *testing.T) {
+	t.Parallel()
+
+	if err := JSONLines(context.Background(), make(chan interface{}), ""); err == nil {
+		t.Error("JSONLines() expected error for empty path")
+	}
+}
@@ -22,30 +22,57 @@
 package messenger
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
 	"errors"
+	"fmt"
+	netmail "net/mail"
+	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/avast/retry-go/v4"
 	"github.com/dkorunic/e-dnevnik-bot/format"
 	"github.com/dkorunic/e-dnevnik-bot/logger"
+	"github.com/dkorunic/e-dnevnik-bot/metrics"
 	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+	"github.com/dkorunic/e-dnevnik-bot/oauth"
 	mail "github.com/wneessen/go-mail"
-	"go.uber.org/ratelimit"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
 )
 
 const (
 	MailSendLimit = 20 // 20 emails per 1 hour
 	MailWindow    = 1 * time.Hour
 	MailMinDelay  = MailWindow / MailSendLimit
-	MailSubject   = "Nova ocjena iz e-Dnevnika"
+	MailPort      = 587
+	MailSSLPort   = 465 // implicit TLS (SMTPS) port
+
+	TypeTextCalendar mail.ContentType = "text/calendar" // ICS attachment content type
+
+	MailAuthPlain   = "plain"   // SMTP PLAIN authentication
+	MailAuthLogin   = "login"   // SMTP LOGIN authentication
+	MailAuthXOAUTH2 = "xoauth2" // SMTP XOAUTH2 authentication with OAuth2 access token
+	MailOAuthScope  = "https://mail.google.com/"
+
+	MailTLSNone          = "none"          // plaintext SMTP
+	MailTLSOpportunistic = "opportunistic" // STARTTLS if offered by the server
+	MailTLSMandatory     = "mandatory"     // STARTTLS required
+	MailTLSImplicit      = "implicit"      // implicit TLS (SMTPS)
 )
 
 var (
-	ErrMailInvalidPort     = errors.New("invalid or missing SMTP port, will try with default 587/tcp")
+	ErrMailInvalidPort     = errors.New("invalid or missing SMTP port")
 	ErrMailDialer          = errors.New("failed to create mail delivery client")
 	ErrMailSendingMessages = errors.New("error sending mail messages")
+	ErrMailAuth            = errors.New("unknown SMTP authentication mechanism")
+	ErrMailTLS             = errors.New("unknown SMTP TLS mode")
+	ErrMailOAuthCreds      = errors.New("unable to read mail OAuth2 credentials file")
+	ErrMailOAuthToken      = errors.New("unable to get mail OAuth2 access token")
+	ErrMailInvalidFrom     = errors.New("invalid sender address")
 )
 
 // Mail sends a message through the mail service.
@@ -57,23 +84,32 @@ var (
 // - port: the port number for the mail server.
 // - username: the username for authentication.
 // - password: the password for authentication.
+// - auth: the SMTP authentication mechanism (plain, login or xoauth2, default is plain).
+// - tlsMode: the SMTP TLS mode (none, opportunistic, mandatory or implicit, default is based on the port).
+// - insecure: whether to skip TLS certificate verification of the mail server.
+// - tokens: the OAuth2 token source for xoauth2 authentication.
 // - from: the email address of the sender.
 // - subject: the subject of the email.
 // - to: a slice of email addresses of the recipients.
+// - attachICS: whether to attach an all-day ICS event to exam messages.
+// - limit: optional rate limit override (messages per window).
+// - window: optional rate limit window override.
 // - retries: the number of retry attempts to send the message.
 //
 // The function returns an error.
-func Mail(ctx context.Context, ch <-chan interface{}, server, port, username, password, from, subject string, to []string, retries uint) error {
+func Mail(ctx context.Context, ch <-chan interface{}, server, port, username, password, auth, tlsMode string, insecure bool, tokens oauth2.TokenSource, from, subject string, to []string, attachICS bool, limit int, window time.Duration, retries uint) error {
 	logger.Debug().Msg("Started e-mail messenger")
 
-	portInt, err := strconv.Atoi(port)
-	if err != nil {
-		logger.Warn().Msgf("%v: %v", ErrMailInvalidPort, port)
-
-		portInt = 587
+	if insecure {
+		warnInsecure("E-mail")
 	}
 
-	rl := ratelimit.New(MailSendLimit, ratelimit.Per(MailWindow))
+	portInt := mailPort(port, tlsMode)
+
+	rl, minDelay := newRateLimiter("Mail", limit, window, MailSendLimit, MailWindow)
+	cb := newBreaker(ctx, "mail")
+
+	var err error
 
 	// process all messages
 	for o := range ch {
@@ -89,18 +125,19 @@ func Mail(ctx context.Context, ch <-chan interface{}, server, port, username, pa
 			}
 
 			// format message, have both text/plain and text/html alternative
-			plainContent := format.PlainMsg(g.Username, g.Subject, g.IsExam, g.Descriptions, g.Fields)
-			htmlContent := format.HTMLMsg(g.Username, g.Subject, g.IsExam, g.Descriptions, g.Fields)
+			plainContent := format.PlainMsg(g)
+			htmlContent := format.HTMLMsg(g)
+
+			// optional ICS calendar event for exams
+			var ics []byte
+			if attachICS && g.Code == msgtypes.Exam {
+				ics = examICS(g)
+			}
 
 			// establish dialer
-			d, err := mail.NewClient(server,
-				mail.WithPort(portInt),
-				mail.WithSMTPAuth(mail.SMTPAuthPlain),
-				mail.WithTLSPolicy(mail.TLSOpportunistic),
-				mail.WithUsername(username),
-				mail.WithPassword(password),
-			)
+			d, err := newMailClient(server, portInt, username, password, auth, tlsMode, insecure, tokens)
 			if err != nil {
+				metrics.MessagesFailed.WithLabelValues("mail").Add(float64(len(to)))
 				logger.Error().Msgf("%v: %v", ErrMailDialer, err)
 
 				break
@@ -122,15 +159,30 @@ func Mail(ctx context.Context, ch <-chan interface{}, server, port, username, pa
 				if subject != "" {
 					m.Subject(subject)
 				} else {
-					m.Subject(MailSubject)
+					m.Subject(format.Lang().MailSubject)
 				}
 
 				m.SetBodyString(mail.TypeTextPlain, plainContent)
 				m.AddAlternativeString(mail.TypeTextHTML, htmlContent)
 
+				if ics != nil {
+					if err := m.AttachReader(ICSFilename, bytes.NewReader(ics),
+						mail.WithFileContentType(TypeTextCalendar)); err != nil {
+						logger.Warn().Msgf("Unable to attach ICS event: %v", err)
+					}
+				}
+
 				messages = append(messages, m)
 			}
 
+			// circuit breaker: service is unavailable in this run
+			if cb.open() {
+				metrics.MessagesFailed.WithLabelValues("mail").Add(float64(len(messages)))
+				cb.skip(g)
+
+				continue
+			}
+
 			rl.Take()
 
 			// retryable and cancellable attempt to send a message
@@ -140,15 +192,217 @@ func Mail(ctx context.Context, ch <-chan interface{}, server, port, username, pa
 				},
 				retry.Attempts(retries),
 				retry.Context(ctx),
-				retry.Delay(MailMinDelay),
+				retry.Delay(minDelay),
 			)
+			cb.record(err)
+
 			if err != nil {
+				metrics.MessagesFailed.WithLabelValues("mail").Add(float64(len(messages)))
 				logger.Error().Msgf("%v: %v", ErrMailSendingMessages, err)
 
 				break
 			}
+
+			metrics.MessagesSent.WithLabelValues("mail").Add(float64(len(messages)))
 		}
 	}
 
 	return err
 }
+
+// SendMailDigest sends a single cleartext digest message through the mail service to a single recipient.
+func SendMailDigest(ctx context.Context, server, port, username, password, auth, tlsMode string, insecure bool, tokens oauth2.TokenSource, from, subject, to, content string, retries uint) error {
+	if insecure {
+		warnInsecure("E-mail")
+	}
+
+	d, err := newMailClient(server, mailPort(port, tlsMode), username, password, auth, tlsMode, insecure, tokens)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrMailDialer, err)
+	}
+
+	m := mail.NewMsg()
+
+	_ = m.From(from)
+	_ = m.To(to)
+
+	m.SetMessageID()
+	m.SetDate()
+
+	if subject != "" {
+		m.Subject(subject)
+	} else {
+		m.Subject(format.Lang().MailDigest)
+	}
+
+	m.SetBodyString(mail.TypeTextPlain, content)
+
+	// retryable and cancellable attempt to send a message
+	err = retry.Do(
+		func() error {
+			return d.DialAndSend(m)
+		},
+		retry.Attempts(retries),
+		retry.Context(ctx),
+		retry.Delay(MailMinDelay),
+	)
+	if err != nil {
+		metrics.MessagesFailed.WithLabelValues("mail").Inc()
+
+		return fmt.Errorf("%w: %w", ErrMailSendingMessages, err)
+	}
+
+	metrics.MessagesSent.WithLabelValues("mail").Inc()
+
+	return nil
+}
+
+// mailPort parses SMTP port, falling back to the default SMTPS port for implicit TLS and to the default submission port
+// otherwise.
+func mailPort(port, tlsMode string) int {
+	portInt, err := strconv.Atoi(port)
+	if err != nil {
+		fallback := MailPort
+		if strings.EqualFold(tlsMode, MailTLSImplicit) {
+			fallback = MailSSLPort
+		}
+
+		logger.Warn().Msgf("%v: %q, will try with default %v/tcp", ErrMailInvalidPort, port, fallback)
+
+		return fallback
+	}
+
+	return portInt
+}
+
+// newMailClient creates a new mail delivery client with the given TLS mode and SMTP authentication, using a current
+// OAuth2 access token as password for XOAUTH2 authentication and optionally skipping TLS certificate verification.
+func newMailClient(server string, port int, username, password, auth, tlsMode string, insecure bool, tokens oauth2.TokenSource) (*mail.Client, error) {
+	authType, err := mailAuthType(auth)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsOpt, err := mailTLSOption(MailTLSMode(tlsMode, strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+
+	if authType == mail.SMTPAuthXOAUTH2 {
+		if tokens == nil {
+			return nil, fmt.Errorf("%w: no token source", ErrMailOAuthToken)
+		}
+
+		tok, err := tokens.Token()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrMailOAuthToken, err)
+		}
+
+		password = tok.AccessToken
+	}
+
+	opts := []mail.Option{
+		mail.WithPort(port),
+		mail.WithSMTPAuth(authType),
+		tlsOpt,
+		mail.WithUsername(username),
+		mail.WithPassword(password),
+	}
+
+	if insecure {
+		opts = append(opts, mail.WithTLSConfig(&tls.Config{
+			ServerName:         server,
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: true, //nolint:gosec
+		}))
+	}
+
+	return mail.NewClient(server, opts...)
+}
+
+// mailAuthType maps SMTP authentication mechanism name to go-mail authentication type, defaulting to PLAIN.
+func mailAuthType(auth string) (mail.SMTPAuthType, error) {
+	switch strings.ToLower(auth) {
+	case "", MailAuthPlain:
+		return mail.SMTPAuthPlain, nil
+	case MailAuthLogin:
+		return mail.SMTPAuthLogin, nil
+	case MailAuthXOAUTH2:
+		return mail.SMTPAuthXOAUTH2, nil
+	default:
+		return "", fmt.Errorf("%w: %v", ErrMailAuth, auth)
+	}
+}
+
+// MailFrom validates the sender address, given either as a plain address or in "Name <address>" form, returning it
+// with the display name replaced by the given name if set.
+func MailFrom(from, name string) (string, error) {
+	addr, err := netmail.ParseAddress(strings.TrimSpace(from))
+	if err != nil {
+		return "", fmt.Errorf("%w: %q: %w", ErrMailInvalidFrom, from, err)
+	}
+
+	if name = strings.TrimSpace(name); name != "" {
+		addr.Name = name
+	}
+
+	if addr.Name == "" {
+		return addr.Address, nil
+	}
+
+	return addr.String(), nil
+}
+
+// MailTLSMode returns the SMTP TLS mode, defaulting to implicit TLS on port 465 and opportunistic STARTTLS otherwise.
+func MailTLSMode(tlsMode, port string) string {
+	if tlsMode != "" {
+		return strings.ToLower(tlsMode)
+	}
+
+	if port == strconv.Itoa(MailSSLPort) {
+		return MailTLSImplicit
+	}
+
+	return MailTLSOpportunistic
+}
+
+// mailTLSOption maps SMTP TLS mode to go-mail TLS policy or implicit TLS option.
+func mailTLSOption(tlsMode string) (mail.Option, error) {
+	switch strings.ToLower(tlsMode) {
+	case MailTLSNone:
+		return mail.WithTLSPolicy(mail.NoTLS), nil
+	case "", MailTLSOpportunistic:
+		return mail.WithTLSPolicy(mail.TLSOpportunistic), nil
+	case MailTLSMandatory:
+		return mail.WithTLSPolicy(mail.TLSMandatory), nil
+	case MailTLSImplicit:
+		return mail.WithSSL(), nil
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrMailTLS, tlsMode)
+	}
+}
+
+// InitMailOAuth initializes an OAuth2 token source for XOAUTH2 mail authentication from OAuth2 client credentials
+// file (in Google JSON format) and token file, obtaining the token interactively on the first run.
+func InitMailOAuth(ctx context.Context, credFile, tokFile string, scopes []string) (oauth2.TokenSource, error) {
+	b, err := os.ReadFile(credFile)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMailOAuthCreds, err)
+	}
+
+	if len(scopes) == 0 {
+		scopes = []string{MailOAuthScope}
+	}
+
+	config, err := google.ConfigFromJSON(b, scopes...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMailOAuthCreds, err)
+	}
+
+	tok, err := oauth.GetToken(ctx, config, tokFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return config.TokenSource(ctx, tok), nil
+}
@@ -0,0 +1,133 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"errors"
+	"testing"
+
+	mail "github.com/wneessen/go-mail"
+)
+
+func TestMailAuthType(t *testing.T) {
+	tests := []struct {
+		auth string
+		want mail.SMTPAuthType
+		err  error
+	}{
+		{"", mail.SMTPAuthPlain, nil},
+		{"plain", mail.SMTPAuthPlain, nil},
+		{"LOGIN", mail.SMTPAuthLogin, nil},
+		{"xoauth2", mail.SMTPAuthXOAUTH2, nil},
+		{"cram-md5", "", ErrMailAuth},
+	}
+
+	for _, tt := range tests {
+		got, err := mailAuthType(tt.auth)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("mailAuthType(%q) = %v, %v, want %v, %v", tt.auth, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestNewMailClientXOAUTH2WithoutTokens(t *testing.T) {
+	if _, err := newMailClient("smtp.example.com", MailPort, "user", "", MailAuthXOAUTH2, "", false, nil); !errors.Is(err,
+		ErrMailOAuthToken) {
+		t.Errorf("newMailClient() error = %v, want %v", err, ErrMailOAuthToken)
+	}
+}
+
+func TestMailPort(t *testing.T) {
+	tests := []struct {
+		port, tlsMode string
+		want          int
+	}{
+		{"2525", "", 2525},
+		{"2525", MailTLSImplicit, 2525},
+		{"", "", MailPort},
+		{"", MailTLSNone, MailPort},
+		{"", MailTLSOpportunistic, MailPort},
+		{"", MailTLSMandatory, MailPort},
+		{"", MailTLSImplicit, MailSSLPort},
+		{"smtps", "Implicit", MailSSLPort},
+	}
+
+	for _, tt := range tests {
+		if got := mailPort(tt.port, tt.tlsMode); got != tt.want {
+			t.Errorf("mailPort(%q, %q) = %v, want %v", tt.port, tt.tlsMode, got, tt.want)
+		}
+	}
+}
+
+func TestMailFrom(t *testing.T) {
+	tests := []struct {
+		from, name, want string
+	}{
+		{"bot@skole.hr", "", "bot@skole.hr"},
+		{" bot@skole.hr ", "", "bot@skole.hr"},
+		{"e-Dnevnik Bot <bot@skole.hr>", "", `"e-Dnevnik Bot" <bot@skole.hr>`},
+		{"bot@skole.hr", "e-Dnevnik Bot", `"e-Dnevnik Bot" <bot@skole.hr>`},
+		{"Old Name <bot@skole.hr>", "New Name", `"New Name" <bot@skole.hr>`},
+		{"bot@skole.hr", "Školski bot", "=?utf-8?q?=C5=A0kolski_bot?= <bot@skole.hr>"},
+	}
+
+	for _, tt := range tests {
+		got, err := MailFrom(tt.from, tt.name)
+		if err != nil {
+			t.Errorf("MailFrom(%q, %q) unexpected error: %v", tt.from, tt.name, err)
+
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("MailFrom(%q, %q) = %q, want %q", tt.from, tt.name, got, tt.want)
+		}
+	}
+
+	for _, from := range []string{"", "bot", "bot@", "Bot <bot@skole.hr"} {
+		if _, err := MailFrom(from, "e-Dnevnik Bot"); !errors.Is(err, ErrMailInvalidFrom) {
+			t.Errorf("MailFrom(%q) error = %v, want %v", from, err, ErrMailInvalidFrom)
+		}
+	}
+}
+
+func TestMailTLSMode(t *testing.T) {
+	tests := []struct {
+		tlsMode, port, want string
+	}{
+		{"", "465", MailTLSImplicit},
+		{"", "587", MailTLSOpportunistic},
+		{"", "25", MailTLSOpportunistic},
+		{"Mandatory", "587", MailTLSMandatory},
+		{"none", "465", MailTLSNone},
+	}
+
+	for _, tt := range tests {
+		if got := MailTLSMode(tt.tlsMode, tt.port); got != tt.want {
+			t.Errorf("MailTLSMode(%q, %q) = %v, want %v", tt.tlsMode, tt.port, got, tt.want)
+		}
+	}
+
+	if _, err := mailTLSOption("ssl"); !errors.Is(err, ErrMailTLS) {
+		t.Errorf("mailTLSOption() error = %v, want %v", err, ErrMailTLS)
+	}
+}
@@ -0,0 +1,195 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/avast/retry-go/v4"
+	"github.com/dkorunic/e-dnevnik-bot/format"
+	"github.com/dkorunic/e-dnevnik-bot/logger"
+	"github.com/dkorunic/e-dnevnik-bot/metrics"
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+)
+
+const (
+	MastodonAPILimit          = 1 // be gentle, instances limit status posting
+	MastodonWindow            = 1 * time.Second
+	MastodonMinDelay          = MastodonWindow / MastodonAPILimit
+	MastodonTimeout           = 30 * time.Second
+	MastodonStatusLimit       = 500 // default status length limit in characters
+	MastodonDefaultVisibility = "direct"
+	mastodonEllipsis          = "…"
+)
+
+var (
+	ErrMastodonEmptyInstance  = errors.New("empty Mastodon instance URL")
+	ErrMastodonEmptyAPIKey    = errors.New("empty Mastodon access token")
+	ErrMastodonSendingMessage = errors.New("error sending Mastodon message")
+	ErrMastodonStatus         = errors.New("unexpected Mastodon API response")
+)
+
+// Mastodon posts messages as statuses through the Mastodon API, mentioning all the accounts.
+//
+// ctx: the context in which the function is executed.
+// ch: the channel from which messages are received.
+// instanceURL: the base URL of the Mastodon instance.
+// accessToken: the Mastodon application access token.
+// visibility: the status visibility (direct by default).
+// accounts: the accounts to mention (recipients of direct statuses).
+// insecure: whether to skip TLS certificate verification of the instance.
+// limit: the optional rate limit override (messages per window).
+// window: the optional rate limit window override.
+// retries: the number of retries in case of failure.
+// error: an error if there was a problem sending the message.
+func Mastodon(ctx context.Context, ch <-chan interface{}, instanceURL, accessToken, visibility string, accounts []string,
+	insecure bool, limit int, window time.Duration, retries uint,
+) error {
+	if instanceURL == "" {
+		return fmt.Errorf("%w", ErrMastodonEmptyInstance)
+	}
+
+	if accessToken == "" {
+		return fmt.Errorf("%w", ErrMastodonEmptyAPIKey)
+	}
+
+	if visibility == "" {
+		visibility = MastodonDefaultVisibility
+	}
+
+	apiURL, err := url.JoinPath(instanceURL, "api", "v1", "statuses")
+	if err != nil {
+		return err
+	}
+
+	client := newHTTPClient("Mastodon", MastodonTimeout, insecure)
+
+	logger.Debug().Msg("Started Mastodon messenger")
+
+	rl, minDelay := newRateLimiter("Mastodon", limit, window, MastodonAPILimit, MastodonWindow)
+	cb := newBreaker(ctx, "mastodon")
+
+	// process all messages
+	for o := range ch {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			g, ok := o.(msgtypes.Message)
+			if !ok {
+				logger.Warn().Msg("Received invalid type from channel, trying to continue")
+
+				continue
+			}
+
+			v := url.Values{
+				"status":     {mastodonStatus(g, accounts, MastodonStatusLimit)},
+				"visibility": {visibility},
+			}
+
+			// circuit breaker: service is unavailable in this run
+			if cb.open() {
+				metrics.MessagesFailed.WithLabelValues("mastodon").Inc()
+				cb.skip(g)
+
+				continue
+			}
+
+			rl.Take()
+
+			// retryable and cancellable attempt to send a message
+			err = retry.Do(
+				func() error {
+					return mastodonPost(ctx, client, apiURL, accessToken, v)
+				},
+				retry.Attempts(retries),
+				retry.Context(ctx),
+				retry.Delay(minDelay),
+			)
+			cb.record(err)
+
+			if err != nil {
+				metrics.MessagesFailed.WithLabelValues("mastodon").Inc()
+				logger.Error().Msgf("%v: %v", ErrMastodonSendingMessage, err)
+
+				continue
+			}
+
+			metrics.MessagesSent.WithLabelValues("mastodon").Inc()
+		}
+	}
+
+	return err
+}
+
+// mastodonStatus builds status text from mentioned accounts and cleartext message, truncating it with an ellipsis to
+// fit the status length limit.
+func mastodonStatus(g msgtypes.Message, accounts []string, maxLen int) string {
+	sb := &strings.Builder{}
+
+	for _, a := range accounts {
+		if !strings.HasPrefix(a, "@") {
+			sb.WriteString("@")
+		}
+
+		sb.WriteString(a)
+		sb.WriteString(" ")
+	}
+
+	sb.WriteString(strings.TrimRight(format.PlainMsg(g), "\n"))
+
+	r := []rune(sb.String())
+	if len(r) <= maxLen {
+		return string(r)
+	}
+
+	return strings.TrimRight(string(r[:maxLen-len([]rune(mastodonEllipsis))]), " \n") + mastodonEllipsis
+}
+
+// mastodonPost posts form values to Mastodon statuses API URL, returning an error on non-2xx response.
+func mastodonPost(ctx context.Context, client *http.Client, apiURL, accessToken string, v url.Values) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, strings.NewReader(v.Encode()))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("%w: %v", ErrMastodonStatus, resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+)
+
+func TestMastodonStatus(t *testing.T) {
+	g := msgtypes.Message{
+		Username:     "korisnik@skole.hr",
+		Subject:      "Matematika",
+		Descriptions: []string{"Napomena"},
+		Fields:       []string{strings.Repeat("dugačka bilješka ", 50)},
+	}
+
+	s := mastodonStatus(g, []string{"roditelj@mastodon.social"}, MastodonStatusLimit)
+
+	if n := utf8.RuneCountInString(s); n > MastodonStatusLimit {
+		t.Errorf("status length %d exceeds limit %d", n, MastodonStatusLimit)
+	}
+
+	if !strings.HasPrefix(s, "@roditelj@mastodon.social Nova ocjena: ") {
+		t.Errorf("unexpected status prefix: %q", s)
+	}
+
+	if !strings.HasSuffix(s, mastodonEllipsis) {
+		t.Errorf("expected truncated status with ellipsis: %q", s)
+	}
+
+	g.Fields = []string{"5"}
+	if s := mastodonStatus(g, nil, MastodonStatusLimit); strings.HasSuffix(s, mastodonEllipsis) {
+		t.Errorf("unexpected truncation of short status: %q", s)
+	}
+}
+
+func TestMastodonPost(t *testing.T) {
+	type request struct {
+		auth string
+		form url.Values
+	}
+
+	reqs := make(chan request, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("unable to parse form: %v", err)
+		}
+
+		reqs <- request{r.Header.Get("Authorization"), r.PostForm}
+	}))
+	defer srv.Close()
+
+	v := url.Values{"status": {"test"}, "visibility": {MastodonDefaultVisibility}}
+
+	if err := mastodonPost(context.Background(), srv.Client(), srv.URL, "token", v); err != nil {
+		t.Fatalf("mastodonPost() = %v", err)
+	}
+
+	r := <-reqs
+	if r.auth != "Bearer token" || r.form.Get("status") != "test" || r.form.Get("visibility") != "direct" {
+		t.Errorf("unexpected request: %+v", r)
+	}
+}
@@ -0,0 +1,188 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/avast/retry-go/v4"
+	"github.com/dkorunic/e-dnevnik-bot/logger"
+	"github.com/dkorunic/e-dnevnik-bot/metrics"
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/goccy/go-json"
+)
+
+const (
+	MQTTAPILimit         = 10 // self-hosted, but be gentle anyway
+	MQTTWindow           = 1 * time.Second
+	MQTTMinDelay         = MQTTWindow / MQTTAPILimit
+	MQTTTimeout          = 30 * time.Second
+	MQTTDisconnectQuiesc = 250 // milliseconds to wait for pending work on disconnect
+	MQTTDefaultClientID  = "e-dnevnik-bot"
+	MQTTDefaultPrefix    = "e-dnevnik"
+)
+
+var (
+	ErrMQTTEmptyBroker    = errors.New("empty MQTT broker URL")
+	ErrMQTTConnect        = errors.New("error connecting to MQTT broker")
+	ErrMQTTSendingMessage = errors.New("error publishing MQTT message")
+	ErrMQTTTimeout        = errors.New("MQTT broker timeout")
+)
+
+// mqttTopicReplacer replaces MQTT topic level separator and wildcards in topic levels.
+var mqttTopicReplacer = strings.NewReplacer(" / ", "-", "/", "-", "+", "_", "#", "_")
+
+// MQTT publishes messages as JSON to an MQTT broker, to {topicPrefix}/{username}/{subject} topic. Delivery is handled
+// by the MQTT QoS level and messages are not queued between runs.
+//
+// ctx: the context in which the function is executed.
+// ch: the channel from which messages are received.
+// brokerURL: the MQTT broker URL (ie. tcp://host:1883 or ssl://host:8883).
+// clientID: the MQTT client ID (default is MQTTDefaultClientID).
+// topicPrefix: the topic prefix (default is MQTTDefaultPrefix).
+// username: the optional MQTT username.
+// password: the optional MQTT password.
+// qos: the MQTT QoS level (0, 1 or 2).
+// retained: whether messages are published with the retained flag.
+// limit: the optional rate limit override (messages per window).
+// window: the optional rate limit window override.
+// retries: the number of retries in case of failure.
+// error: an error if there was a problem sending the message.
+func MQTT(ctx context.Context, ch <-chan interface{}, brokerURL, clientID, topicPrefix, username, password string,
+	qos byte, retained bool, limit int, window time.Duration, retries uint,
+) error {
+	if brokerURL == "" {
+		return fmt.Errorf("%w", ErrMQTTEmptyBroker)
+	}
+
+	if clientID == "" {
+		clientID = MQTTDefaultClientID
+	}
+
+	if topicPrefix == "" {
+		topicPrefix = MQTTDefaultPrefix
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(brokerURL).
+		SetClientID(clientID).
+		SetUsername(username).
+		SetPassword(password).
+		SetConnectTimeout(MQTTTimeout).
+		SetWriteTimeout(MQTTTimeout)
+
+	client := mqtt.NewClient(opts)
+
+	if err := mqttWait(client.Connect()); err != nil {
+		return fmt.Errorf("%w: %w", ErrMQTTConnect, err)
+	}
+	defer client.Disconnect(MQTTDisconnectQuiesc)
+
+	logger.Debug().Msg("Started MQTT messenger")
+
+	rl, minDelay := newRateLimiter("MQTT", limit, window, MQTTAPILimit, MQTTWindow)
+	cb := newBreaker(ctx, "mqtt")
+
+	var err error
+
+	// process all messages
+	for o := range ch {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			g, ok := o.(msgtypes.Message)
+			if !ok {
+				logger.Warn().Msg("Received invalid type from channel, trying to continue")
+
+				continue
+			}
+
+			var b []byte
+
+			b, err = json.Marshal(g)
+			if err != nil {
+				logger.Error().Msgf("%v: %v", ErrMQTTSendingMessage, err)
+
+				continue
+			}
+
+			topic := mqttTopic(topicPrefix, g)
+
+			// circuit breaker: service is unavailable in this run
+			if cb.open() {
+				metrics.MessagesFailed.WithLabelValues("mqtt").Inc()
+				cb.skip(g)
+
+				continue
+			}
+
+			rl.Take()
+
+			// retryable and cancellable attempt to publish a message
+			err = retry.Do(
+				func() error {
+					return mqttWait(client.Publish(topic, qos, retained, b))
+				},
+				retry.Attempts(retries),
+				retry.Context(ctx),
+				retry.Delay(minDelay),
+			)
+			cb.record(err)
+
+			if err != nil {
+				metrics.MessagesFailed.WithLabelValues("mqtt").Inc()
+				logger.Error().Msgf("%v: %v", ErrMQTTSendingMessage, err)
+
+				continue
+			}
+
+			metrics.MessagesSent.WithLabelValues("mqtt").Inc()
+		}
+	}
+
+	return err
+}
+
+// mqttTopic returns {topicPrefix}/{username}/{subject} topic for the message, with topic level separators and
+// wildcards in username and subject replaced.
+func mqttTopic(topicPrefix string, g msgtypes.Message) string {
+	return strings.Join([]string{
+		strings.TrimRight(topicPrefix, "/"),
+		mqttTopicReplacer.Replace(g.Username),
+		mqttTopicReplacer.Replace(g.Subject),
+	}, "/")
+}
+
+// mqttWait waits for MQTT operation to complete, returning an error on failure or timeout.
+func mqttWait(t mqtt.Token) error {
+	if !t.WaitTimeout(MQTTTimeout) {
+		return fmt.Errorf("%w", ErrMQTTTimeout)
+	}
+
+	return t.Error()
+}
@@ -0,0 +1,45 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"testing"
+
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+)
+
+func TestMQTTTopic(t *testing.T) {
+	tests := []struct {
+		prefix, username, subject, want string
+	}{
+		{"e-dnevnik", "korisnik@skole.hr", "Matematika", "e-dnevnik/korisnik@skole.hr/Matematika"},
+		{"home/school/", "korisnik@skole.hr", "Matematika / 8.a", "home/school/korisnik@skole.hr/Matematika-8.a"},
+		{"e-dnevnik", "korisnik+1@skole.hr", "C#/.NET", "e-dnevnik/korisnik_1@skole.hr/C_-.NET"},
+	}
+
+	for _, tt := range tests {
+		got := mqttTopic(tt.prefix, msgtypes.Message{Username: tt.username, Subject: tt.subject})
+		if got != tt.want {
+			t.Errorf("mqttTopic(%q, %q, %q) = %q, want %q", tt.prefix, tt.username, tt.subject, got, tt.want)
+		}
+	}
+}
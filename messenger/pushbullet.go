@@ -0,0 +1,186 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/avast/retry-go/v4"
+	"github.com/dkorunic/e-dnevnik-bot/format"
+	"github.com/dkorunic/e-dnevnik-bot/logger"
+	"github.com/dkorunic/e-dnevnik-bot/metrics"
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+	"github.com/goccy/go-json"
+)
+
+const (
+	PushbulletAPILimit = 1 // be gentle, monthly push quota is limited
+	PushbulletWindow   = 1 * time.Second
+	PushbulletMinDelay = PushbulletWindow / PushbulletAPILimit
+	PushbulletTimeout  = 30 * time.Second
+	PushbulletURL      = "https://api.pushbullet.com/v2/pushes"
+)
+
+var (
+	ErrPushbulletEmptyToken     = errors.New("empty Pushbullet access token")
+	ErrPushbulletSendingMessage = errors.New("error sending Pushbullet message")
+	ErrPushbulletStatus         = errors.New("unexpected Pushbullet API response")
+)
+
+// pushbulletPush is a Pushbullet push API request body.
+type pushbulletPush struct {
+	Type       string `json:"type"`
+	Title      string `json:"title"`
+	Body       string `json:"body"`
+	DeviceIden string `json:"device_iden,omitempty"`
+}
+
+// Pushbullet sends messages as notes through the Pushbullet API.
+//
+// ctx: the context in which the function is executed.
+// ch: the channel from which messages are received.
+// accessToken: the Pushbullet access token.
+// deviceIDs: the device idens of the recipients (empty means all devices of the account).
+// limit: the optional rate limit override (messages per window).
+// window: the optional rate limit window override.
+// retries: the number of retries in case of failure.
+// error: an error if there was a problem sending the message.
+func Pushbullet(ctx context.Context, ch <-chan interface{}, accessToken string, deviceIDs []string, limit int,
+	window time.Duration, retries uint,
+) error {
+	if accessToken == "" {
+		return fmt.Errorf("%w", ErrPushbulletEmptyToken)
+	}
+
+	// push without a device iden is broadcasted to all devices
+	if len(deviceIDs) == 0 {
+		deviceIDs = []string{""}
+	}
+
+	client := &http.Client{Timeout: PushbulletTimeout}
+
+	logger.Debug().Msg("Started Pushbullet messenger")
+
+	rl, minDelay := newRateLimiter("Pushbullet", limit, window, PushbulletAPILimit, PushbulletWindow)
+	cb := newBreaker(ctx, "pushbullet")
+
+	var err error
+
+	// process all messages
+	for o := range ch {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			g, ok := o.(msgtypes.Message)
+			if !ok {
+				logger.Warn().Msg("Received invalid type from channel, trying to continue")
+
+				continue
+			}
+
+			// send to all devices
+			for _, d := range deviceIDs {
+				var b []byte
+
+				b, err = json.Marshal(newPushbulletPush(g, d))
+				if err != nil {
+					logger.Error().Msgf("%v: %v", ErrPushbulletSendingMessage, err)
+
+					break
+				}
+
+				// circuit breaker: service is unavailable in this run
+				if cb.open() {
+					metrics.MessagesFailed.WithLabelValues("pushbullet").Inc()
+					cb.skip(g)
+
+					continue
+				}
+
+				rl.Take()
+
+				// retryable and cancellable attempt to send a message
+				err = retry.Do(
+					func() error {
+						return pushbulletPost(ctx, client, PushbulletURL, accessToken, b)
+					},
+					retry.Attempts(retries),
+					retry.Context(ctx),
+					retry.Delay(minDelay),
+				)
+				cb.record(err)
+
+				if err != nil {
+					metrics.MessagesFailed.WithLabelValues("pushbullet").Inc()
+					logger.Error().Msgf("%v: %v", ErrPushbulletSendingMessage, err)
+
+					break
+				}
+
+				metrics.MessagesSent.WithLabelValues("pushbullet").Inc()
+			}
+		}
+	}
+
+	return err
+}
+
+// newPushbulletPush builds Pushbullet note push with one-line message summary as a title and cleartext message as a body,
+// targeting a single device or all devices if device iden is empty.
+func newPushbulletPush(g msgtypes.Message, deviceIden string) pushbulletPush {
+	return pushbulletPush{
+		Type:       "note",
+		Title:      format.SummaryTitle(g),
+		Body:       format.PlainMsg(g),
+		DeviceIden: deviceIden,
+	}
+}
+
+// pushbulletPost posts JSON push to Pushbullet API URL authenticated with the access token, returning an error on
+// non-2xx response.
+func pushbulletPost(ctx context.Context, client *http.Client, apiURL, accessToken string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Access-Token", accessToken)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("%w: %v", ErrPushbulletStatus, resp.Status)
+	}
+
+	return nil
+}
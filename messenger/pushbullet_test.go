@@ -0,0 +1,105 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+	"github.com/goccy/go-json"
+)
+
+func TestPushbulletPost(t *testing.T) {
+	type request struct {
+		token string
+		push  pushbulletPush
+		raw   map[string]any
+	}
+
+	reqs := make(chan request, 2)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var raw map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+			t.Errorf("unable to decode body: %v", err)
+		}
+
+		b, _ := json.Marshal(raw)
+
+		var p pushbulletPush
+		_ = json.Unmarshal(b, &p)
+
+		reqs <- request{r.Header.Get("Access-Token"), p, raw}
+
+		if p.DeviceIden == "bad" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	g := msgtypes.Message{
+		Username:     "korisnik@skole.hr",
+		Subject:      "Matematika",
+		Code:         msgtypes.Exam,
+		Descriptions: []string{"Datum ispita", "Napomena"},
+		Fields:       []string{"10.01.2025.", "Pisana provjera"},
+	}
+
+	b, err := json.Marshal(newPushbulletPush(g, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pushbulletPost(context.Background(), srv.Client(), srv.URL, "token", b); err != nil {
+		t.Fatalf("pushbulletPost() = %v", err)
+	}
+
+	r := <-reqs
+	if r.token != "token" || r.push.Type != "note" {
+		t.Errorf("unexpected request: %+v", r)
+	}
+
+	if r.push.Title != "korisnik@skole.hr / Matematika: ispit Pisana provjera (10.01.2025.)" {
+		t.Errorf("unexpected title: %q", r.push.Title)
+	}
+
+	if _, found := r.raw["device_iden"]; found {
+		t.Errorf("broadcast push has a device iden: %+v", r.raw)
+	}
+
+	b, err = json.Marshal(newPushbulletPush(g, "bad"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pushbulletPost(context.Background(), srv.Client(), srv.URL, "token", b); !errors.Is(err, ErrPushbulletStatus) {
+		t.Errorf("pushbulletPost() = %v, want %v", err, ErrPushbulletStatus)
+	}
+
+	if r = <-reqs; r.push.DeviceIden != "bad" {
+		t.Errorf("unexpected device iden: %q", r.push.DeviceIden)
+	}
+}
@@ -0,0 +1,177 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/avast/retry-go/v4"
+	"github.com/dkorunic/e-dnevnik-bot/format"
+	"github.com/dkorunic/e-dnevnik-bot/logger"
+	"github.com/dkorunic/e-dnevnik-bot/metrics"
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+)
+
+const (
+	PushoverAPILimit = 2 // be gentle, monthly message quota is limited
+	PushoverWindow   = 1 * time.Second
+	PushoverMinDelay = PushoverWindow / PushoverAPILimit
+	PushoverTimeout  = 30 * time.Second
+	PushoverURL      = "https://api.pushover.net/1/messages.json"
+)
+
+var (
+	ErrPushoverEmptyAPIKey    = errors.New("empty Pushover application token")
+	ErrPushoverEmptyUserKeys  = errors.New("empty list of Pushover user keys")
+	ErrPushoverSendingMessage = errors.New("error sending Pushover message")
+	ErrPushoverStatus         = errors.New("unexpected Pushover API response")
+)
+
+// Pushover sends messages through the Pushover API.
+//
+// ctx: the context in which the function is executed.
+// ch: the channel from which messages are received.
+// appToken: the Pushover application token.
+// userKeys: the user (or group) keys of the recipients.
+// priority: the message priority for grades and other events.
+// examPriority: the message priority for exams.
+// limit: the optional rate limit override (messages per window).
+// window: the optional rate limit window override.
+// retries: the number of retries in case of failure.
+// error: an error if there was a problem sending the message.
+func Pushover(ctx context.Context, ch <-chan interface{}, appToken string, userKeys []string, priority, examPriority int,
+	limit int, window time.Duration, retries uint,
+) error {
+	if appToken == "" {
+		return fmt.Errorf("%w", ErrPushoverEmptyAPIKey)
+	}
+
+	if len(userKeys) == 0 {
+		return fmt.Errorf("%w", ErrPushoverEmptyUserKeys)
+	}
+
+	client := &http.Client{Timeout: PushoverTimeout}
+
+	logger.Debug().Msg("Started Pushover messenger")
+
+	rl, minDelay := newRateLimiter("Pushover", limit, window, PushoverAPILimit, PushoverWindow)
+	cb := newBreaker(ctx, "pushover")
+
+	var err error
+
+	// process all messages
+	for o := range ch {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			g, ok := o.(msgtypes.Message)
+			if !ok {
+				logger.Warn().Msg("Received invalid type from channel, trying to continue")
+
+				continue
+			}
+
+			p := priority
+			if g.Code == msgtypes.Exam {
+				p = examPriority
+			}
+
+			// send to all recipients
+			for _, u := range userKeys {
+				// circuit breaker: service is unavailable in this run
+				if cb.open() {
+					metrics.MessagesFailed.WithLabelValues("pushover").Inc()
+					cb.skip(g)
+
+					continue
+				}
+
+				rl.Take()
+
+				v := pushoverMessage(g, appToken, u, p)
+
+				// retryable and cancellable attempt to send a message
+				err = retry.Do(
+					func() error {
+						return pushoverPost(ctx, client, PushoverURL, v)
+					},
+					retry.Attempts(retries),
+					retry.Context(ctx),
+					retry.Delay(minDelay),
+				)
+				cb.record(err)
+
+				if err != nil {
+					metrics.MessagesFailed.WithLabelValues("pushover").Inc()
+					logger.Error().Msgf("%v: %v", ErrPushoverSendingMessage, err)
+
+					break
+				}
+
+				metrics.MessagesSent.WithLabelValues("pushover").Inc()
+			}
+		}
+	}
+
+	return err
+}
+
+// pushoverMessage builds Pushover API form values with one-line message summary as a title and cleartext message as a body.
+func pushoverMessage(g msgtypes.Message, appToken, userKey string, priority int) url.Values {
+	return url.Values{
+		"token":    {appToken},
+		"user":     {userKey},
+		"title":    {format.SummaryTitle(g)},
+		"message":  {format.PlainMsg(g)},
+		"priority": {strconv.Itoa(priority)},
+	}
+}
+
+// pushoverPost posts form values to Pushover API URL, returning an error on non-2xx response.
+func pushoverPost(ctx context.Context, client *http.Client, apiURL string, v url.Values) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, strings.NewReader(v.Encode()))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("%w: %v", ErrPushoverStatus, resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"time"
+
+	"github.com/dkorunic/e-dnevnik-bot/logger"
+	"go.uber.org/ratelimit"
+)
+
+// newRateLimiter creates a rate limiter from optional limit and window overrides, falling back to messenger defaults
+// for non-positive values, and returns it together with the minimal delay between retries.
+func newRateLimiter(name string, limit int, window time.Duration, defLimit int, defWindow time.Duration) (ratelimit.Limiter, time.Duration) {
+	if limit <= 0 {
+		limit = defLimit
+	}
+
+	if window <= 0 {
+		window = defWindow
+	}
+
+	logger.Debug().Msgf("%v messenger rate limit: %v per %v", name, limit, window)
+
+	return ratelimit.New(limit, ratelimit.Per(window)), window / time.Duration(limit)
+}
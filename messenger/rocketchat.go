@@ -0,0 +1,198 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/avast/retry-go/v4"
+	"github.com/dkorunic/e-dnevnik-bot/format"
+	"github.com/dkorunic/e-dnevnik-bot/logger"
+	"github.com/dkorunic/e-dnevnik-bot/metrics"
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+	"github.com/goccy/go-json"
+)
+
+const (
+	RocketChatAPILimit = 5 // self-hosted, default chat.postMessage rate limit is 5 requests per second
+	RocketChatWindow   = 1 * time.Second
+	RocketChatMinDelay = RocketChatWindow / RocketChatAPILimit
+	RocketChatTimeout  = 30 * time.Second
+)
+
+var (
+	ErrRocketChatEmptyServer    = errors.New("empty Rocket.Chat server URL")
+	ErrRocketChatEmptyAuth      = errors.New("empty Rocket.Chat user ID or auth token")
+	ErrRocketChatEmptyChannels  = errors.New("empty list of Rocket.Chat channels")
+	ErrRocketChatSendingMessage = errors.New("error sending Rocket.Chat message")
+	ErrRocketChatStatus         = errors.New("unexpected Rocket.Chat API response")
+)
+
+// rocketChatMessage is a Rocket.Chat chat.postMessage API request body.
+type rocketChatMessage struct {
+	Channel string `json:"channel"`
+	Text    string `json:"text"`
+}
+
+// rocketChatResponse is a Rocket.Chat API response body.
+type rocketChatResponse struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error"`
+}
+
+// RocketChat sends messages to Rocket.Chat channels, groups or users through the chat.postMessage REST API.
+//
+// ctx: the context in which the function is executed.
+// ch: the channel from which messages are received.
+// serverURL: the base URL of the Rocket.Chat server.
+// userID: the Rocket.Chat user ID of the bot user.
+// token: the Rocket.Chat personal access token of the bot user.
+// channels: the channels (#channel), users (@user) or room IDs to send messages to.
+// insecure: whether to skip TLS certificate verification of the server.
+// limit: the optional rate limit override (messages per window).
+// window: the optional rate limit window override.
+// retries: the number of retries in case of failure.
+// error: an error if there was a problem sending the message.
+func RocketChat(ctx context.Context, ch <-chan interface{}, serverURL, userID, token string, channels []string,
+	insecure bool, limit int, window time.Duration, retries uint,
+) error {
+	if serverURL == "" {
+		return fmt.Errorf("%w", ErrRocketChatEmptyServer)
+	}
+
+	if userID == "" || token == "" {
+		return fmt.Errorf("%w", ErrRocketChatEmptyAuth)
+	}
+
+	if len(channels) == 0 {
+		return fmt.Errorf("%w", ErrRocketChatEmptyChannels)
+	}
+
+	apiURL, err := url.JoinPath(serverURL, "api/v1/chat.postMessage")
+	if err != nil {
+		return err
+	}
+
+	client := newHTTPClient("Rocket.Chat", RocketChatTimeout, insecure)
+
+	logger.Debug().Msg("Started Rocket.Chat messenger")
+
+	rl, minDelay := newRateLimiter("RocketChat", limit, window, RocketChatAPILimit, RocketChatWindow)
+	cb := newBreaker(ctx, "rocketchat")
+
+	// process all messages
+	for o := range ch {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			g, ok := o.(msgtypes.Message)
+			if !ok {
+				logger.Warn().Msg("Received invalid type from channel, trying to continue")
+
+				continue
+			}
+
+			m := format.MarkupMsg(g)
+
+			// send to all recipients: channels, groups and users are permitted
+			for _, c := range channels {
+				// circuit breaker: service is unavailable in this run
+				if cb.open() {
+					metrics.MessagesFailed.WithLabelValues("rocketchat").Inc()
+					cb.skip(g)
+
+					continue
+				}
+
+				rl.Take()
+
+				var b []byte
+
+				b, err = json.Marshal(rocketChatMessage{Channel: c, Text: m})
+				if err != nil {
+					logger.Error().Msgf("%v: %v", ErrRocketChatSendingMessage, err)
+
+					break
+				}
+
+				// retryable and cancellable attempt to send a message
+				err = retry.Do(
+					func() error {
+						return rocketChatPost(ctx, client, apiURL, userID, token, b)
+					},
+					retry.Attempts(retries),
+					retry.Context(ctx),
+					retry.Delay(minDelay),
+				)
+				cb.record(err)
+
+				if err != nil {
+					metrics.MessagesFailed.WithLabelValues("rocketchat").Inc()
+					logger.Error().Msgf("%v: %v", ErrRocketChatSendingMessage, err)
+
+					break
+				}
+
+				metrics.MessagesSent.WithLabelValues("rocketchat").Inc()
+			}
+		}
+	}
+
+	return err
+}
+
+// rocketChatPost posts JSON message to Rocket.Chat chat.postMessage API URL with user ID and token authentication,
+// returning an error on non-2xx or unsuccessful response.
+func rocketChatPost(ctx context.Context, client *http.Client, apiURL, userID, token string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-Id", userID)
+	req.Header.Set("X-Auth-Token", token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var r rocketChatResponse
+
+	// error details are in the response body, if any
+	_ = json.NewDecoder(resp.Body).Decode(&r)
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices || !r.Success {
+		return fmt.Errorf("%w: %v %v", ErrRocketChatStatus, resp.Status, r.Error)
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+func TestRocketChatPost(t *testing.T) {
+	type request struct {
+		path, userID, token string
+		msg                 rocketChatMessage
+	}
+
+	reqs := make(chan request, 2)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m rocketChatMessage
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			t.Errorf("unable to decode body: %v", err)
+		}
+
+		reqs <- request{r.URL.Path, r.Header.Get("X-User-Id"), r.Header.Get("X-Auth-Token"), m}
+
+		if m.Channel == "#missing" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"success":false,"error":"error-invalid-channel"}`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"success":true}`))
+	}))
+	defer srv.Close()
+
+	apiURL := srv.URL + "/api/v1/chat.postMessage"
+
+	b, err := json.Marshal(rocketChatMessage{Channel: "#razred", Text: "*Nova ocjena*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := rocketChatPost(context.Background(), srv.Client(), apiURL, "uid", "secret", b); err != nil {
+		t.Fatalf("rocketChatPost() = %v", err)
+	}
+
+	r := <-reqs
+	if r.path != "/api/v1/chat.postMessage" || r.userID != "uid" || r.token != "secret" ||
+		r.msg.Channel != "#razred" || r.msg.Text != "*Nova ocjena*" {
+		t.Errorf("unexpected request: %+v", r)
+	}
+
+	b, err = json.Marshal(rocketChatMessage{Channel: "#missing", Text: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = rocketChatPost(context.Background(), srv.Client(), apiURL, "uid", "secret", b)
+	if !errors.Is(err, ErrRocketChatStatus) {
+		t.Errorf("rocketChatPost() = %v, want %v", err, ErrRocketChatStatus)
+	}
+}
@@ -30,9 +30,9 @@ import (
 	"github.com/avast/retry-go/v4"
 	"github.com/dkorunic/e-dnevnik-bot/format"
 	"github.com/dkorunic/e-dnevnik-bot/logger"
+	"github.com/dkorunic/e-dnevnik-bot/metrics"
 	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
 	"github.com/slack-go/slack"
-	"go.uber.org/ratelimit"
 )
 
 const (
@@ -53,9 +53,14 @@ var (
 // ch: the channel from which messages are received.
 // token: the Slack API key.
 // chatIDs: the IDs of the recipients.
+// threads: whether to thread messages of the same user and subject under the first such message in a run.
+// limit: the optional rate limit override (messages per window).
+// window: the optional rate limit window override.
 // retries: the number of retries in case of failure.
 // error: an error if there was a problem sending the message.
-func Slack(ctx context.Context, ch <-chan interface{}, token string, chatIDs []string, retries uint) error {
+func Slack(ctx context.Context, ch <-chan interface{}, token string, chatIDs []string, threads bool, limit int,
+	window time.Duration, retries uint,
+) error {
 	if token == "" {
 		return fmt.Errorf("%w", ErrSlackEmptyAPIKey)
 	}
@@ -69,10 +74,14 @@ func Slack(ctx context.Context, ch <-chan interface{}, token string, chatIDs []s
 
 	logger.Debug().Msg("Started Slack messenger")
 
-	rl := ratelimit.New(SlackAPILImit, ratelimit.Per(SlackWindow))
+	rl, minDelay := newRateLimiter("Slack", limit, window, SlackAPILImit, SlackWindow)
+	cb := newBreaker(ctx, "slack")
 
 	var err error
 
+	// parent messages per recipient, user and subject in this run
+	parents := make(map[string]slackThread)
+
 	// process all messages
 	for o := range ch {
 		select {
@@ -87,34 +96,76 @@ func Slack(ctx context.Context, ch <-chan interface{}, token string, chatIDs []s
 			}
 
 			// format message as Markup
-			m := format.MarkupMsg(g.Username, g.Subject, g.IsExam, g.Descriptions, g.Fields)
+			m := format.MarkupMsg(g)
 
 			// send to all recipients: channels and nicknames are permitted
 			for _, u := range chatIDs {
+				// circuit breaker: service is unavailable in this run
+				if cb.open() {
+					metrics.MessagesFailed.WithLabelValues("slack").Inc()
+					cb.skip(g)
+
+					continue
+				}
+
 				rl.Take()
 
+				key := slackThreadKey(u, g)
+				channel := u
+				opts := []slack.MsgOption{
+					slack.MsgOptionText(m, false),
+					slack.MsgOptionAsUser(true),
+				}
+
+				// reply in the thread of an earlier message with the same subject
+				parent, threaded := parents[key]
+				if threads && threaded {
+					channel = parent.channel
+					opts = append(opts, slack.MsgOptionTS(parent.ts))
+				}
+
+				var respChannel, respTS string
+
 				// retryable and cancellable attempt to send a message
 				err = retry.Do(
 					func() error {
-						_, _, err := api.PostMessage(u,
-							slack.MsgOptionText(m, false),
-							slack.MsgOptionAsUser(true),
-						)
+						var err error
+						respChannel, respTS, err = api.PostMessage(channel, opts...)
 
 						return err
 					},
 					retry.Attempts(retries),
 					retry.Context(ctx),
-					retry.Delay(SlackMinDelay),
+					retry.Delay(minDelay),
 				)
+				cb.record(err)
+
 				if err != nil {
+					metrics.MessagesFailed.WithLabelValues("slack").Inc()
 					logger.Error().Msgf("%v: %v", ErrSlackSendingMessage, err)
 
 					break
 				}
+
+				metrics.MessagesSent.WithLabelValues("slack").Inc()
+
+				if threads && !threaded {
+					parents[key] = slackThread{channel: respChannel, ts: respTS}
+				}
 			}
 		}
 	}
 
 	return err
 }
+
+// slackThread holds a channel and a timestamp of a thread parent message.
+type slackThread struct {
+	channel string
+	ts      string
+}
+
+// slackThreadKey returns a thread key for the message sent to a recipient, unique per recipient, user and subject.
+func slackThreadKey(chatID string, g msgtypes.Message) string {
+	return chatID + "\x00" + g.Username + "\x00" + g.Subject
+}
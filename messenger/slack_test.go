@@ -0,0 +1,57 @@
+// @license
+// Copyright (C) 2022  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"testing"
+
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+)
+
+func TestSlackThreadKey(t *testing.T) {
+	g := msgtypes.Message{Username: "user", Subject: "Matematika"}
+
+	same := g
+	same.Code = msgtypes.Exam
+
+	if slackThreadKey("C1", g) != slackThreadKey("C1", same) {
+		t.Errorf("slackThreadKey() differs for the same user and subject")
+	}
+
+	other := []struct {
+		name   string
+		chatID string
+		g      msgtypes.Message
+	}{
+		{"other recipient", "C2", g},
+		{"other user", "C1", msgtypes.Message{Username: "user2", Subject: "Matematika"}},
+		{"other subject", "C1", msgtypes.Message{Username: "user", Subject: "Fizika"}},
+	}
+
+	for _, tt := range other {
+		t.Run(tt.name, func(t *testing.T) {
+			if slackThreadKey(tt.chatID, tt.g) == slackThreadKey("C1", g) {
+				t.Errorf("slackThreadKey() matches for %v", tt.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/avast/retry-go/v4"
+	"github.com/dkorunic/e-dnevnik-bot/format"
+	"github.com/dkorunic/e-dnevnik-bot/logger"
+	"github.com/dkorunic/e-dnevnik-bot/metrics"
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+	"github.com/goccy/go-json"
+)
+
+const (
+	TeamsAPILimit = 4 // 4 req/s per incoming webhook
+	TeamsWindow   = 1 * time.Second
+	TeamsMinDelay = TeamsWindow / TeamsAPILimit
+	TeamsTimeout  = 30 * time.Second
+)
+
+var (
+	ErrTeamsEmptyWebhooks  = errors.New("empty list of Microsoft Teams webhook URLs")
+	ErrTeamsSendingMessage = errors.New("error sending Microsoft Teams message")
+	ErrTeamsStatus         = errors.New("unexpected Microsoft Teams webhook response")
+)
+
+// teamsCard is a legacy actionable MessageCard supported by Microsoft Teams incoming webhooks.
+type teamsCard struct {
+	Type     string         `json:"@type"`
+	Context  string         `json:"@context"`
+	Summary  string         `json:"summary"`
+	Title    string         `json:"title"`
+	Sections []teamsSection `json:"sections"`
+}
+
+// teamsSection is a MessageCard section holding facts.
+type teamsSection struct {
+	Text  string      `json:"text,omitempty"`
+	Facts []teamsFact `json:"facts"`
+}
+
+// teamsFact is a MessageCard name/value pair.
+type teamsFact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Teams sends messages through Microsoft Teams incoming webhooks.
+//
+// ctx: the context in which the function is executed.
+// ch: the channel from which messages are received.
+// webhookURLs: the incoming webhook URLs of the recipients.
+// limit: the optional rate limit override (messages per window).
+// window: the optional rate limit window override.
+// retries: the number of retries in case of failure.
+// error: an error if there was a problem sending the message.
+func Teams(ctx context.Context, ch <-chan interface{}, webhookURLs []string, limit int, window time.Duration, retries uint) error {
+	if len(webhookURLs) == 0 {
+		return fmt.Errorf("%w", ErrTeamsEmptyWebhooks)
+	}
+
+	client := &http.Client{Timeout: TeamsTimeout}
+
+	logger.Debug().Msg("Started Microsoft Teams messenger")
+
+	rl, minDelay := newRateLimiter("Teams", limit, window, TeamsAPILimit, TeamsWindow)
+	cb := newBreaker(ctx, "teams")
+
+	var err error
+
+	// process all messages
+	for o := range ch {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			g, ok := o.(msgtypes.Message)
+			if !ok {
+				logger.Warn().Msg("Received invalid type from channel, trying to continue")
+
+				continue
+			}
+
+			// format message as MessageCard
+			body, err := json.Marshal(teamsMessage(g))
+			if err != nil {
+				logger.Error().Msgf("%v: %v", ErrTeamsSendingMessage, err)
+
+				continue
+			}
+
+			// send to all recipients
+			for _, u := range webhookURLs {
+				// circuit breaker: service is unavailable in this run
+				if cb.open() {
+					metrics.MessagesFailed.WithLabelValues("teams").Inc()
+					cb.skip(g)
+
+					continue
+				}
+
+				rl.Take()
+
+				// retryable and cancellable attempt to send a message
+				err = retry.Do(
+					func() error {
+						return teamsPost(ctx, client, u, body)
+					},
+					retry.Attempts(retries),
+					retry.Context(ctx),
+					retry.Delay(minDelay),
+				)
+				cb.record(err)
+
+				if err != nil {
+					metrics.MessagesFailed.WithLabelValues("teams").Inc()
+					logger.Error().Msgf("%v: %v", ErrTeamsSendingMessage, err)
+
+					break
+				}
+
+				metrics.MessagesSent.WithLabelValues("teams").Inc()
+			}
+		}
+	}
+
+	return err
+}
+
+// teamsMessage builds a MessageCard with message subject as a title and descriptions and fields as facts.
+func teamsMessage(g msgtypes.Message) teamsCard {
+	sb := &strings.Builder{}
+	format.PlainFormatSubject(sb, g)
+
+	facts := make([]teamsFact, 0, len(g.Fields))
+	for i := range g.Fields {
+		facts = append(facts, teamsFact{
+			Name:  g.Descriptions[i],
+			Value: format.FieldValue(g.Fields, g.PreviousFields, i),
+		})
+	}
+
+	return teamsCard{
+		Type:     "MessageCard",
+		Context:  "https://schema.org/extensions",
+		Summary:  sb.String(),
+		Title:    sb.String(),
+		Sections: []teamsSection{{Text: format.AverageLine(g.Average), Facts: facts}},
+	}
+}
+
+// teamsPost posts JSON body to an incoming webhook URL, returning an error on non-2xx response.
+func teamsPost(ctx context.Context, client *http.Client, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("%w: %v", ErrTeamsStatus, resp.Status)
+	}
+
+	return nil
+}
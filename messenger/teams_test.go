@@ -0,0 +1,69 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+	"github.com/goccy/go-json"
+)
+
+func TestTeams(t *testing.T) {
+	cards := make(chan teamsCard, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var c teamsCard
+		if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+			t.Errorf("unable to decode card: %v", err)
+		}
+
+		cards <- c
+	}))
+	defer srv.Close()
+
+	ch := make(chan interface{}, 1)
+	ch <- msgtypes.Message{
+		Username:     "korisnik@skole.hr",
+		Subject:      "Matematika",
+		Code:         msgtypes.Grade,
+		Descriptions: []string{"Datum", "Ocjena"},
+		Fields:       []string{"2.1.", "5"},
+	}
+	close(ch)
+
+	if err := Teams(context.Background(), ch, []string{srv.URL}, 0, 0, 1); err != nil {
+		t.Fatalf("Teams() = %v", err)
+	}
+
+	c := <-cards
+	if c.Type != "MessageCard" || c.Title != "Nova ocjena: korisnik@skole.hr / Matematika" {
+		t.Errorf("unexpected card: %+v", c)
+	}
+
+	if len(c.Sections) != 1 || len(c.Sections[0].Facts) != 2 || c.Sections[0].Facts[1] != (teamsFact{"Ocjena", "5"}) {
+		t.Errorf("unexpected card facts: %+v", c.Sections)
+	}
+}
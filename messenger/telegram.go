@@ -25,21 +25,25 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"time"
 
 	"github.com/avast/retry-go/v4"
 	"github.com/dkorunic/e-dnevnik-bot/format"
 	"github.com/dkorunic/e-dnevnik-bot/logger"
+	"github.com/dkorunic/e-dnevnik-bot/metrics"
 	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"go.uber.org/ratelimit"
+	"github.com/goccy/go-json"
 )
 
 const (
 	TelegramAPILimit = 30 // 30 API req/s per user
 	TelegramWindow   = 1 * time.Second
 	TelegramMinDelay = TelegramWindow / TelegramAPILimit
+	TelegramDefault  = "default" // default topic name for all event types
+	TelegramUrgent   = "‼️ "     // emphasis prepended to urgent exam messages
 )
 
 var (
@@ -47,6 +51,7 @@ var (
 	ErrTelegramEmptyUserIDs   = errors.New("empty list of Telegram Chat IDs")
 	ErrTelegramInvalidChatID  = errors.New("invalid Telegram Chat ID")
 	ErrTelegramSendingMessage = errors.New("error sending Telegram message")
+	ErrTelegramPinningMessage = errors.New("error pinning Telegram message")
 )
 
 // Telegram sends messages through the Telegram API.
@@ -56,10 +61,18 @@ var (
 // - ch: a channel for receiving messages to be sent.
 // - apiKey: the API key for accessing the Telegram API.
 // - chatIDs: a slice of strings containing the IDs of the chat recipients.
+// - topics: optional mapping of event types to supergroup topic (message thread) IDs.
+// - urgentDays: exams taking place in at most this many days are emphasized (0 disables).
+// - pinUrgent: pin urgent exam messages in the chat.
+// - silent: event types sent without a notification sound.
+// - limit: optional rate limit override (messages per window).
+// - window: optional rate limit window override.
 // - retries: the number of times to retry sending a message in case of failure.
 //
 // It returns an error indicating any failures that occurred during the process.
-func Telegram(ctx context.Context, ch <-chan interface{}, apiKey string, chatIDs []string, retries uint) error {
+func Telegram(ctx context.Context, ch <-chan interface{}, apiKey string, chatIDs []string, topics map[string]int,
+	urgentDays int, pinUrgent bool, silent []string, limit int, window time.Duration, retries uint,
+) error {
 	if apiKey == "" {
 		return fmt.Errorf("%w", ErrTelegramEmptyAPIKey)
 	}
@@ -78,7 +91,8 @@ func Telegram(ctx context.Context, ch <-chan interface{}, apiKey string, chatIDs
 
 	logger.Debug().Msg("Started Telegram messenger")
 
-	rl := ratelimit.New(TelegramAPILimit, ratelimit.Per(TelegramWindow))
+	rl, minDelay := newRateLimiter("Telegram", limit, window, TelegramAPILimit, TelegramWindow)
+	cb := newBreaker(ctx, "telegram")
 
 	// process all messages
 	for o := range ch {
@@ -93,8 +107,13 @@ func Telegram(ctx context.Context, ch <-chan interface{}, apiKey string, chatIDs
 				continue
 			}
 
-			// format message as HTML
-			m := format.HTMLMsg(g.Username, g.Subject, g.IsExam, g.Descriptions, g.Fields)
+			// format message as HTML, emphasizing urgent exams
+			m := format.HTMLMsg(g)
+
+			urgent := telegramUrgent(g, urgentDays, time.Now())
+			if urgent {
+				m = TelegramUrgent + m
+			}
 
 			// send to all recipients
 			for _, u := range chatIDs {
@@ -105,35 +124,97 @@ func Telegram(ctx context.Context, ch <-chan interface{}, apiKey string, chatIDs
 					return err
 				}
 
-				msg := tgbotapi.MessageConfig{
-					BaseChat: tgbotapi.BaseChat{
-						ChatID: uu,
-					},
-					Text:      m,
-					ParseMode: tgbotapi.ModeHTML,
+				// raw request parameters, as message thread ID is not supported by MessageConfig
+				params := tgbotapi.Params{
+					"text":       m,
+					"parse_mode": tgbotapi.ModeHTML,
+				}
+				params.AddNonZero64("chat_id", uu)
+				params.AddNonZero("message_thread_id", telegramTopic(topics, g.Code))
+				params.AddBool("disable_notification", slices.Contains(silent, g.Code.String()))
+
+				// circuit breaker: service is unavailable in this run
+				if cb.open() {
+					metrics.MessagesFailed.WithLabelValues("telegram").Inc()
+					cb.skip(g)
+
+					continue
 				}
 
 				rl.Take()
 
+				var resp *tgbotapi.APIResponse
+
 				// retryable and cancellable attempt to send a message
 				err = retry.Do(
 					func() error {
-						_, err := bot.Send(msg)
+						var err error
+						resp, err = bot.MakeRequest("sendMessage", params)
 
 						return err
 					},
 					retry.Attempts(retries),
 					retry.Context(ctx),
-					retry.Delay(TelegramMinDelay),
+					retry.Delay(minDelay),
 				)
+				cb.record(err)
+
 				if err != nil {
+					metrics.MessagesFailed.WithLabelValues("telegram").Inc()
 					logger.Error().Msgf("%v: %v", ErrTelegramSendingMessage, err)
 
 					break
 				}
+
+				metrics.MessagesSent.WithLabelValues("telegram").Inc()
+
+				if urgent && pinUrgent {
+					telegramPin(bot, uu, resp)
+				}
 			}
 		}
 	}
 
 	return err
 }
+
+// telegramTopic returns supergroup topic ID for the event type, falling back to the default topic and to no topic at
+// all (zero) if neither has been configured.
+func telegramTopic(topics map[string]int, code msgtypes.EventCode) int {
+	if t, ok := topics[code.String()]; ok {
+		return t
+	}
+
+	return topics[TelegramDefault]
+}
+
+// telegramUrgent checks if the message is an exam taking place today or in at most urgentDays days.
+func telegramUrgent(g msgtypes.Message, urgentDays int, now time.Time) bool {
+	if urgentDays <= 0 || g.Code != msgtypes.Exam || g.Timestamp.IsZero() {
+		return false
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	return !g.Timestamp.Before(today) && g.Timestamp.Before(today.AddDate(0, 0, urgentDays+1))
+}
+
+// telegramPin silently pins a sent message, only logging failures (ie. bot is not a chat administrator), as the message
+// itself has already been delivered.
+func telegramPin(bot *tgbotapi.BotAPI, chatID int64, resp *tgbotapi.APIResponse) {
+	var msg tgbotapi.Message
+
+	if err := json.Unmarshal(resp.Result, &msg); err != nil {
+		logger.Warn().Msgf("%v: %v", ErrTelegramPinningMessage, err)
+
+		return
+	}
+
+	if _, err := bot.Request(tgbotapi.PinChatMessageConfig{
+		ChatID:              chatID,
+		MessageID:           msg.MessageID,
+		DisableNotification: true,
+	}); err != nil {
+		logger.Warn().Msgf("%v: %v", ErrTelegramPinningMessage, err)
+	}
+}
@@ -0,0 +1,84 @@
+// @license
+// Copyright (C) 2022  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+)
+
+func TestTelegramTopic(t *testing.T) {
+	tests := []struct {
+		name   string
+		topics map[string]int
+		code   msgtypes.EventCode
+		want   int
+	}{
+		{"no topics", nil, msgtypes.Exam, 0},
+		{"single topic", map[string]int{TelegramDefault: 3}, msgtypes.Exam, 3},
+		{"exam topic", map[string]int{"exam": 5, "grade": 7}, msgtypes.Exam, 5},
+		{"grade topic", map[string]int{"exam": 5, "grade": 7}, msgtypes.Grade, 7},
+		{"default fallback", map[string]int{"exam": 5, TelegramDefault: 3}, msgtypes.Absence, 3},
+		{"no fallback", map[string]int{"exam": 5}, msgtypes.Grade, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := telegramTopic(tt.topics, tt.code); got != tt.want {
+				t.Errorf("telegramTopic() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTelegramUrgent(t *testing.T) {
+	now := time.Date(2024, time.March, 12, 15, 30, 0, 0, time.UTC)
+	day := func(d int) time.Time { return time.Date(2024, time.March, 12+d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name       string
+		code       msgtypes.EventCode
+		timestamp  time.Time
+		urgentDays int
+		want       bool
+	}{
+		{"disabled", msgtypes.Exam, day(1), 0, false},
+		{"exam today", msgtypes.Exam, day(0), 1, true},
+		{"exam tomorrow", msgtypes.Exam, day(1), 1, true},
+		{"exam in two days", msgtypes.Exam, day(2), 1, false},
+		{"exam in two days, wider period", msgtypes.Exam, day(2), 3, true},
+		{"past exam", msgtypes.Exam, day(-1), 3, false},
+		{"unknown date", msgtypes.Exam, time.Time{}, 3, false},
+		{"grade", msgtypes.Grade, day(0), 3, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := msgtypes.Message{Code: tt.code, Timestamp: tt.timestamp}
+			if got := telegramUrgent(g, tt.urgentDays, now); got != tt.want {
+				t.Errorf("telegramUrgent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+
+	"github.com/dkorunic/e-dnevnik-bot/logger"
+)
+
+// newHTTPClient returns a HTTP client with the given timeout, optionally skipping TLS certificate verification for
+// self-hosted servers with self-signed certificates.
+func newHTTPClient(name string, timeout time.Duration, insecure bool) *http.Client {
+	if !insecure {
+		return &http.Client{Timeout: timeout}
+	}
+
+	warnInsecure(name)
+
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert
+	transport.TLSClientConfig = &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: true, //nolint:gosec
+	}
+
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// warnInsecure logs a warning that TLS certificate verification is disabled for the given messenger.
+func warnInsecure(name string) {
+	logger.Warn().Msgf("%v messenger: TLS certificate verification is disabled (insecure_skip_verify), "+
+		"connections are vulnerable to interception", name)
+}
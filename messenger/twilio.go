@@ -0,0 +1,183 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/avast/retry-go/v4"
+	"github.com/dkorunic/e-dnevnik-bot/format"
+	"github.com/dkorunic/e-dnevnik-bot/logger"
+	"github.com/dkorunic/e-dnevnik-bot/metrics"
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+)
+
+const (
+	TwilioAPILimit = 1 // long code numbers are limited to 1 SMS/s
+	TwilioWindow   = 1 * time.Second
+	TwilioMinDelay = TwilioWindow / TwilioAPILimit
+	TwilioTimeout  = 30 * time.Second
+	TwilioURL      = "https://api.twilio.com/2010-04-01/Accounts/"
+	TwilioMaxLen   = 320 // SMS length limit, to keep message costs down
+)
+
+var (
+	ErrTwilioEmptyAccount     = errors.New("empty Twilio account SID or auth token")
+	ErrTwilioEmptyNumbers     = errors.New("empty Twilio sender or list of recipient phone numbers")
+	ErrTwilioSendingMessage   = errors.New("error sending Twilio message")
+	ErrTwilioStatus           = errors.New("unexpected Twilio API response")
+	ErrTwilioInvalidAccountID = errors.New("invalid Twilio account SID")
+)
+
+// Twilio sends messages as SMS through the Twilio Messages API.
+//
+// ctx: the context in which the function is executed.
+// ch: the channel from which messages are received.
+// accountSID: the Twilio account SID.
+// authToken: the Twilio auth token.
+// from: the sender phone number.
+// to: the phone numbers of the recipients.
+// limit: the optional rate limit override (messages per window).
+// window: the optional rate limit window override.
+// retries: the number of retries in case of failure.
+// error: an error if there was a problem sending the message.
+func Twilio(ctx context.Context, ch <-chan interface{}, accountSID, authToken, from string, to []string, limit int,
+	window time.Duration, retries uint,
+) error {
+	if accountSID == "" || authToken == "" {
+		return fmt.Errorf("%w", ErrTwilioEmptyAccount)
+	}
+
+	if from == "" || len(to) == 0 {
+		return fmt.Errorf("%w", ErrTwilioEmptyNumbers)
+	}
+
+	apiURL, err := twilioURL(TwilioURL, accountSID)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: TwilioTimeout}
+
+	logger.Debug().Msg("Started Twilio messenger")
+
+	rl, minDelay := newRateLimiter("Twilio", limit, window, TwilioAPILimit, TwilioWindow)
+	cb := newBreaker(ctx, "twilio")
+
+	// process all messages
+	for o := range ch {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			g, ok := o.(msgtypes.Message)
+			if !ok {
+				logger.Warn().Msg("Received invalid type from channel, trying to continue")
+
+				continue
+			}
+
+			// SMS has no markup, so format message as a single line of cleartext
+			m := format.SMSMsg(g, TwilioMaxLen)
+
+			// send to all recipients
+			for _, u := range to {
+				// circuit breaker: service is unavailable in this run
+				if cb.open() {
+					metrics.MessagesFailed.WithLabelValues("twilio").Inc()
+					cb.skip(g)
+
+					continue
+				}
+
+				rl.Take()
+
+				v := url.Values{
+					"From": {from},
+					"To":   {u},
+					"Body": {m},
+				}
+
+				// retryable and cancellable attempt to send a message
+				err = retry.Do(
+					func() error {
+						return twilioPost(ctx, client, apiURL, accountSID, authToken, v)
+					},
+					retry.Attempts(retries),
+					retry.Context(ctx),
+					retry.Delay(minDelay),
+				)
+				cb.record(err)
+
+				if err != nil {
+					metrics.MessagesFailed.WithLabelValues("twilio").Inc()
+					logger.Error().Msgf("%v: %v", ErrTwilioSendingMessage, err)
+
+					break
+				}
+
+				metrics.MessagesSent.WithLabelValues("twilio").Inc()
+			}
+		}
+	}
+
+	return err
+}
+
+// twilioURL returns Twilio Messages API URL for the account.
+func twilioURL(baseURL, accountSID string) (string, error) {
+	if strings.ContainsAny(accountSID, "/?#") {
+		return "", fmt.Errorf("%w: %v", ErrTwilioInvalidAccountID, accountSID)
+	}
+
+	return url.JoinPath(baseURL, accountSID, "Messages.json")
+}
+
+// twilioPost posts form values to Twilio Messages API URL with basic authentication, returning an error on non-2xx
+// response.
+func twilioPost(ctx context.Context, client *http.Client, apiURL, accountSID, authToken string, v url.Values) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, strings.NewReader(v.Encode()))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(accountSID, authToken)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("%w: %v", ErrTwilioStatus, resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package messenger
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestTwilioPost(t *testing.T) {
+	type request struct {
+		path, user, pass string
+		form             url.Values
+	}
+
+	reqs := make(chan request, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("unable to parse form: %v", err)
+		}
+
+		user, pass, _ := r.BasicAuth()
+		reqs <- request{r.URL.Path, user, pass, r.PostForm}
+
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	apiURL, err := twilioURL(srv.URL+"/2010-04-01/Accounts/", "AC123")
+	if err != nil {
+		t.Fatalf("twilioURL() = %v", err)
+	}
+
+	v := url.Values{"From": {"+385911111111"}, "To": {"+385922222222"}, "Body": {"Nova ocjena"}}
+
+	if err := twilioPost(context.Background(), srv.Client(), apiURL, "AC123", "secret", v); err != nil {
+		t.Fatalf("twilioPost() = %v", err)
+	}
+
+	r := <-reqs
+	if r.path != "/2010-04-01/Accounts/AC123/Messages.json" || r.user != "AC123" || r.pass != "secret" {
+		t.Errorf("unexpected request: %+v", r)
+	}
+
+	if r.form.Get("To") != "+385922222222" || r.form.Get("Body") != "Nova ocjena" {
+		t.Errorf("unexpected form: %v", r.form)
+	}
+
+	if _, err := twilioURL(TwilioURL, "AC1/../x"); err == nil {
+		t.Error("twilioURL() with invalid account SID should fail")
+	}
+}
@@ -0,0 +1,101 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/dkorunic/e-dnevnik-bot/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	Namespace         = "ednevnik"
+	MetricsPath       = "/metrics"
+	ReadHeaderTimeout = 10 * time.Second
+	ShutdownTimeout   = 5 * time.Second
+)
+
+var (
+	ScrapeSuccess = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "scrape_success_total",
+		Help:      "Total number of successful scrapes per user.",
+	}, []string{"user"})
+	ScrapeFailure = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "scrape_failure_total",
+		Help:      "Total number of failed scrapes per user.",
+	}, []string{"user"})
+	MessagesSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "messages_sent_total",
+		Help:      "Total number of messages sent per messenger.",
+	}, []string{"messenger"})
+	MessagesFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "messages_failed_total",
+		Help:      "Total number of messages failed to send per messenger.",
+	}, []string{"messenger"})
+	DedupHits = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "dedup_hits_total",
+		Help:      "Total number of scraped events already found in the alert database.",
+	})
+	LastScrape = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "last_successful_scrape_timestamp_seconds",
+		Help:      "Unix timestamp of the last successful scrape.",
+	})
+)
+
+// Serve starts Prometheus metrics HTTP listener on a given address, shutting it down when context gets cancelled.
+func Serve(ctx context.Context, addr string) {
+	mux := http.NewServeMux()
+	mux.Handle(MetricsPath, promhttp.Handler())
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: ReadHeaderTimeout,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		sCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+		defer cancel()
+
+		_ = srv.Shutdown(sCtx) //nolint:contextcheck
+	}()
+
+	logger.Info().Msgf("Serving Prometheus metrics on %v%v", addr, MetricsPath)
+
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error().Msgf("Unable to serve Prometheus metrics: %v", err)
+	}
+}
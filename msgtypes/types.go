@@ -23,12 +23,54 @@ package msgtypes
 
 import "time"
 
+// EventCode is a type of the scraped event.
+type EventCode int
+
+const (
+	Grade            EventCode = iota // regular grade
+	Exam                              // scheduled exam
+	Absence                           // class absence
+	EnrollmentChange                  // active class enrollment change
+	Note                              // teacher note
+	Digest                            // digest of multiple events
+	Schedule                          // class timetable change
+	NationalExam                      // national exam result
+)
+
+// String returns a lowercase name of the event code.
+func (c EventCode) String() string {
+	switch c {
+	case Exam:
+		return "exam"
+	case Absence:
+		return "absence"
+	case EnrollmentChange:
+		return "enrollment"
+	case Note:
+		return "note"
+	case Digest:
+		return "digest"
+	case Schedule:
+		return "schedule"
+	case NationalExam:
+		return "national_exam"
+	default:
+		return "grade"
+	}
+}
+
 // Message structure holds alert subject and description as well as grades fields, as well as corresponding username.
 type Message struct {
-	Timestamp    time.Time // event timestamp
-	Username     string    // username (SSO/SAML)
-	Subject      string    // subject
-	Descriptions []string  // descriptions for fields
-	Fields       []string  // fields with actual grades/exams and remarks
-	IsExam       bool      // message is an exam event
+	Timestamp      time.Time // event timestamp
+	Username       string    // username (SSO/SAML)
+	Student        string    // student full name or configured display name (empty if unknown)
+	School         string    // school name (empty if unknown)
+	URL            string    // link to the class on e-dnevnik site (empty if unknown)
+	Subject        string    // subject
+	Descriptions   []string  // descriptions for fields
+	Fields         []string  // fields with actual grades/exams and remarks
+	PreviousFields []string  // previous fields of an edited grade (empty if not edited)
+	Average        float64   // current subject grade average (zero if unknown)
+	Code           EventCode // event type
+	Reminder       bool      // message is a re-notification of an already sent event
 }
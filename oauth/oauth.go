@@ -82,6 +82,17 @@ var contentFS embed.FS
 // - *http.Client: the HTTP client.
 // - error: an error if any occurred during the execution of the function.
 func GetClient(ctx context.Context, config *oauth2.Config, tokenPath string) (*http.Client, error) {
+	tok, err := GetToken(ctx, config, tokenPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return config.Client(ctx, tok), nil
+}
+
+// GetToken retrieves an OAuth2 token from the token file, refreshing it if expired, or obtains it interactively
+// through the system browser if there is no token file, saving new or refreshed token to the file.
+func GetToken(ctx context.Context, config *oauth2.Config, tokenPath string) (*oauth2.Token, error) {
 	tok, err := tokenFromFile(tokenPath)
 	saveToFile := false
 
@@ -118,7 +129,7 @@ func GetClient(ctx context.Context, config *oauth2.Config, tokenPath string) (*h
 		}
 	}
 
-	return config.Client(ctx, tok), nil
+	return tok, nil
 }
 
 // getTokenFromWeb retrieves an OAuth2 token from a web-based authentication flow.
@@ -0,0 +1,473 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dkorunic/e-dnevnik-bot/db"
+	"github.com/dkorunic/e-dnevnik-bot/logger"
+	"github.com/dkorunic/e-dnevnik-bot/messenger"
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+	"github.com/dkorunic/e-dnevnik-bot/scrape"
+	"github.com/dkorunic/e-dnevnik-bot/tracing"
+)
+
+const (
+	profileConfExt     = ".toml"                // profile configuration file extension
+	profileDBExt       = ".db"                  // profile alert database file extension
+	profileCalTokenExt = "_calendar_token.json" // profile Google Calendar token file suffix
+	intervalSlack      = 30 * time.Second       // tolerance of per-user poll interval checks for ticker jitter
+)
+
+var (
+	ErrNoProfiles       = errors.New("no configuration profiles (*.toml) found")
+	ErrProfilesLanguage = errors.New("all configuration profiles have to use the same language")
+	ErrProfilesTemplate = errors.New("all configuration profiles have to use the same message template")
+	ErrProfilesPrefixes = errors.New("all configuration profiles have to use the same message prefixes")
+	ErrProfilesTrigger  = errors.New("all configuration profiles have to use the same trigger secret")
+)
+
+// profile holds an independent configuration with its own alert database, Google Calendar token and run state, so
+// that failures of one profile never affect the others.
+type profile struct {
+	name       string               // profile name (empty for a single configuration file)
+	confFile   string               // configuration file
+	dbFile     string               // alert database file
+	calTokFile string               // Google Calendar token file
+	config     tomlConfig           // loaded configuration
+	failed     atomic.Bool          // errors were encountered in the current run
+	digest     digestState          // digest schedule state
+	results    *runResults          // results of the current run
+	lastScrape map[string]time.Time // last scrape time per user, for per-user poll intervals
+	breakers   messenger.Breakers   // messenger circuit breaker state between runs
+}
+
+// runResults holds results of a single run: per-user scrape results and new alert counts, and per-messenger send
+// results, where a nil error means success.
+type runResults struct {
+	mu      sync.Mutex
+	scrapes map[string]error
+	alerts  map[string]int
+	sends   map[string]error
+}
+
+// newRunResults returns empty run results.
+func newRunResults() *runResults {
+	return &runResults{
+		scrapes: make(map[string]error),
+		alerts:  make(map[string]int),
+		sends:   make(map[string]error),
+	}
+}
+
+// digestState holds digest schedule state, kept in memory between polls, while alerts waiting to be sent in a digest
+// are queued in the alert database.
+type digestState struct {
+	last time.Time // time of the last sent digest
+	runs int       // number of polls since the last sent digest
+}
+
+// loadProfiles loads a single configuration file with global database and calendar token paths or, if a profiles
+// directory is set, every configuration file in it as a separate profile named after the file, with the database and
+// calendar token stored alongside.
+func loadProfiles() ([]*profile, error) {
+	if *profilesDir == "" {
+		config, err := loadConfig(*confFile)
+		if err != nil {
+			return nil, err
+		}
+
+		config.applyFormat()
+
+		return []*profile{{confFile: *confFile, dbFile: *dbFile, calTokFile: *calTokFile, config: config}}, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(*profilesDir, "*"+profileConfExt))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%w: %v", ErrNoProfiles, *profilesDir)
+	}
+
+	profiles := make([]*profile, 0, len(files))
+
+	for _, f := range files {
+		name := strings.TrimSuffix(filepath.Base(f), profileConfExt)
+
+		logger.Info().Msgf("Loading configuration profile %v", name)
+
+		config, err := loadConfig(f)
+		if err != nil {
+			return nil, fmt.Errorf("profile %v: %w", name, err)
+		}
+
+		if len(profiles) > 0 {
+			if err := checkProfile(profiles[0].config, config, name); err != nil {
+				return nil, err
+			}
+		}
+
+		profiles = append(profiles, &profile{
+			name:       name,
+			confFile:   f,
+			dbFile:     filepath.Join(*profilesDir, name+profileDBExt),
+			calTokFile: filepath.Join(*profilesDir, name+profileCalTokenExt),
+			config:     config,
+		})
+	}
+
+	profiles[0].config.applyFormat()
+
+	return profiles, nil
+}
+
+// checkProfile verifies that the profile configuration agrees with the first profile on all process-wide settings.
+func checkProfile(first, config tomlConfig, name string) error {
+	// message language is process-wide
+	if first.Language != config.Language {
+		return fmt.Errorf("%w: %v", ErrProfilesLanguage, name)
+	}
+
+	// message template is process-wide
+	if first.Template != config.Template {
+		return fmt.Errorf("%w: %v", ErrProfilesTemplate, name)
+	}
+
+	// message prefixes are process-wide
+	if !maps.Equal(first.Prefixes, config.Prefixes) {
+		return fmt.Errorf("%w: %v", ErrProfilesPrefixes, name)
+	}
+
+	// on-demand scrape trigger is process-wide
+	if first.TriggerSecret != config.TriggerSecret {
+		return fmt.Errorf("%w: %v", ErrProfilesTrigger, name)
+	}
+
+	return nil
+}
+
+// reloadProfiles attempts to load configuration of all profiles again, keeping the current configuration of a profile
+// if the new one is invalid and of all profiles if the resulting configurations disagree on process-wide settings.
+func reloadProfiles(ctx context.Context, profiles []*profile) {
+	configs := make([]*tomlConfig, len(profiles))
+
+	for i, p := range profiles {
+		logger.Info().Msgf("Reloading configuration from %v", p.confFile)
+
+		config, err := loadConfig(p.confFile)
+		if err != nil {
+			logger.Error().Msgf("Error reloading configuration, keeping the current one: %v", err)
+
+			continue
+		}
+
+		configs[i] = &config
+	}
+
+	// compare configurations as they would be after the reload
+	resulting := func(i int) tomlConfig {
+		if configs[i] != nil {
+			return *configs[i]
+		}
+
+		return profiles[i].config
+	}
+
+	for i := 1; i < len(profiles); i++ {
+		if err := checkProfile(resulting(0), resulting(i), profiles[i].name); err != nil {
+			logger.Error().Msgf("Error reloading configuration, keeping the current one for all profiles: %v", err)
+
+			return
+		}
+	}
+
+	for i, p := range profiles {
+		if configs[i] != nil {
+			reloadConfig(ctx, p, *configs[i])
+		}
+	}
+
+	profiles[0].config.applyFormat()
+}
+
+// due reports if the user should be scraped in the run starting now, which is when the user was never successfully
+// scraped or its poll interval has elapsed since the last successful scrape.
+func (p *profile) due(u user, now time.Time) bool {
+	last, ok := p.lastScrape[u.Username]
+
+	return !ok || now.Sub(last) >= u.interval()-intervalSlack
+}
+
+// markScraped records the run starting at now as the last scrape of all users successfully scraped in it, so that
+// users failing to be scraped are retried on the next tick regardless of their poll intervals.
+func (p *profile) markScraped(now time.Time) {
+	p.results.mu.Lock()
+	defer p.results.mu.Unlock()
+
+	for username, err := range p.results.scrapes {
+		if err != nil {
+			continue
+		}
+
+		if p.lastScrape == nil {
+			p.lastScrape = make(map[string]time.Time)
+		}
+
+		p.lastScrape[username] = now
+	}
+}
+
+// pollInterval returns interval between polls, which is the shortest of global and per-user poll intervals of all
+// profiles.
+func pollInterval(profiles []*profile) time.Duration {
+	interval := *tickInterval
+
+	for _, p := range profiles {
+		for _, u := range p.config.User {
+			interval = min(interval, u.interval())
+		}
+	}
+
+	return interval
+}
+
+// report records send result of a messenger, if results are being collected.
+func (p *profile) report(name string, err error) {
+	if p.results == nil {
+		return
+	}
+
+	p.results.mu.Lock()
+	defer p.results.mu.Unlock()
+
+	p.results.sends[name] = err
+}
+
+// reportScrape records scrape result of a user, if results are being collected.
+func (p *profile) reportScrape(username string, err error) {
+	if p.results == nil {
+		return
+	}
+
+	p.results.mu.Lock()
+	defer p.results.mu.Unlock()
+
+	p.results.scrapes[username] = err
+}
+
+// scrapedAll reports if all users have been successfully scraped in the current run.
+func (p *profile) scrapedAll() bool {
+	if p.results == nil {
+		return false
+	}
+
+	p.results.mu.Lock()
+	defer p.results.mu.Unlock()
+
+	for _, u := range p.config.User {
+		if err, found := p.results.scrapes[u.Username]; !found || err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// scrapeSummary logs scrape outcome of every configured user in the current run, including the class of failures.
+func (p *profile) scrapeSummary() {
+	p.results.mu.Lock()
+	defer p.results.mu.Unlock()
+
+	prefix := ""
+	if p.name != "" {
+		prefix = p.name + ": "
+	}
+
+	for _, u := range p.config.User {
+		err, found := p.results.scrapes[u.Username]
+
+		switch {
+		case !found:
+			logger.Info().Msgf("Scrape %v%v: SKIPPED", prefix, u.Username)
+		case err == nil:
+			logger.Info().Msgf("Scrape %v%v: OK", prefix, u.Username)
+		default:
+			class := scrape.Classify(err)
+			if class.Transient() {
+				logger.Warn().Msgf("Scrape %v%v: FAIL (%v, will retry on next run): %v", prefix, u.Username, class, err)
+
+				continue
+			}
+
+			logger.Error().Msgf("Scrape %v%v: FAIL (%v): %v", prefix, u.Username, class, err)
+		}
+	}
+}
+
+// countAlert counts a new alert for a user, if results are being collected.
+func (p *profile) countAlert(username string) {
+	if p.results == nil {
+		return
+	}
+
+	p.results.mu.Lock()
+	defer p.results.mu.Unlock()
+
+	p.results.alerts[username]++
+}
+
+// testSummary logs PASS/FAIL send result of every configured messenger in the order of messenger names, returning
+// true if all of them passed.
+func (p *profile) testSummary() bool {
+	p.results.mu.Lock()
+	defer p.results.mu.Unlock()
+
+	prefix := ""
+	if p.name != "" {
+		prefix = p.name + ": "
+	}
+
+	passed := true
+
+	for _, name := range messengerNames {
+		err, found := p.results.sends[name]
+		if !found {
+			continue
+		}
+
+		if err != nil {
+			logger.Error().Msgf("Test %v%v: FAIL: %v", prefix, name, err)
+
+			passed = false
+
+			continue
+		}
+
+		logger.Info().Msgf("Test %v%v: PASS", prefix, name)
+	}
+
+	return passed
+}
+
+// openDB opens the profile alert database, returning nil in a dry run without an existing database, as a new
+// database is never created then.
+func (p *profile) openDB() (*db.Edb, error) {
+	if *dryRun && !db.Exists(p.dbFile) {
+		return nil, nil
+	}
+
+	eDB, err := db.New(p.dbFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open database: %w", err)
+	}
+
+	return eDB, nil
+}
+
+// storeSkipped queues messages skipped by tripped messenger circuit breakers in the alert database, so that they are sent
+// again in the next run.
+func (p *profile) storeSkipped(eDB *db.Edb) {
+	for name, msgs := range p.breakers.Skipped() {
+		if eDB == nil {
+			continue
+		}
+
+		if err := eDB.PutFailed(name, msgs); err != nil {
+			logger.Error().Msgf("Problem with database, unable to keep messages skipped by %v messenger: %v", name, err)
+			p.failed.Store(true)
+
+			continue
+		}
+
+		logger.Info().Msgf("Keeping %v messages skipped by %v messenger for the next run", len(msgs), name)
+	}
+}
+
+// run does a single scrape, dedup and send run of the profile, returning true if no errors were encountered.
+func (p *profile) run(ctx context.Context) bool {
+	if p.name != "" {
+		logger.Info().Msgf("Running configuration profile %v", p.name)
+	}
+
+	ctx, span := tracing.Start(ctx, "run", tracing.Profile(p.name))
+	defer span.End()
+
+	p.failed.Store(false)
+	p.results = newRunResults()
+
+	// alert database shared by all routines of the run
+	eDB, err := p.openDB()
+	if err != nil {
+		logger.Error().Msgf("Problem with database, skipping this run: %v", err)
+		p.failed.Store(true)
+
+		return false
+	}
+
+	if eDB != nil {
+		defer eDB.Close()
+	}
+
+	gradesScraped := make(chan msgtypes.Message, chanBufLen)
+	gradesMsg := make(chan msgtypes.Message, chanBufLen)
+
+	var wgScrape, wgFilter, wgMsg sync.WaitGroup
+
+	now := time.Now()
+
+	// subjects/grades/exams scraper routines
+	scrapers(ctx, &wgScrape, gradesScraped, p, now)
+
+	// message/alert database checking routine
+	msgDedup(ctx, &wgFilter, gradesScraped, gradesMsg, p, eDB)
+
+	// messenger routines
+	msgSend(ctx, &wgMsg, gradesMsg, p, eDB)
+
+	wgScrape.Wait()
+	close(gradesScraped)
+
+	p.markScraped(now)
+	p.scrapeSummary()
+
+	wgFilter.Wait()
+	wgMsg.Wait()
+
+	p.storeSkipped(eDB)
+
+	if p.failed.Load() && p.name != "" {
+		logger.Warn().Msgf("Configuration profile %v run encountered errors", p.name)
+	}
+
+	return !p.failed.Load()
+}
@@ -25,17 +25,24 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/blang/semver/v4"
 	"github.com/dkorunic/e-dnevnik-bot/db"
+	"github.com/dkorunic/e-dnevnik-bot/format"
 	"github.com/dkorunic/e-dnevnik-bot/logger"
 	"github.com/dkorunic/e-dnevnik-bot/messenger"
+	"github.com/dkorunic/e-dnevnik-bot/metrics"
 	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
 	"github.com/dkorunic/e-dnevnik-bot/scrape"
+	"github.com/dkorunic/e-dnevnik-bot/tracing"
 	"github.com/dustin/go-broadcast"
+	"github.com/goccy/go-json"
 	"github.com/google/go-github/v68/github"
+	"github.com/minio/sha256-simd"
 	"github.com/tj/go-spin"
 )
 
@@ -47,46 +54,179 @@ const (
 )
 
 var (
-	ErrScrapingUser = errors.New("error scraping data for user")
-	ErrDiscord      = errors.New("Discord messenger issue")  //nolint:stylecheck
-	ErrTelegram     = errors.New("Telegram messenger issue") //nolint:stylecheck
-	ErrSlack        = errors.New("Slack messenger issue")    //nolint:stylecheck
-	ErrMail         = errors.New("Mail messenger issue")     //nolint:stylecheck
-	ErrCalendar     = errors.New("Google Calendar issue")    //nolint:stylecheck
-
-	formatHRDateOnly = "2.1."
+	ErrScrapingUser  = errors.New("error scraping data for user")
+	ErrDiscord       = errors.New("Discord messenger issue")         //nolint:stylecheck
+	ErrTelegram      = errors.New("Telegram messenger issue")        //nolint:stylecheck
+	ErrSlack         = errors.New("Slack messenger issue")           //nolint:stylecheck
+	ErrMail          = errors.New("Mail messenger issue")            //nolint:stylecheck
+	ErrCalendar      = errors.New("Google Calendar issue")           //nolint:stylecheck
+	ErrTeams         = errors.New("Microsoft Teams messenger issue") //nolint:stylecheck
+	ErrPushover      = errors.New("Pushover messenger issue")        //nolint:stylecheck
+	ErrGotify        = errors.New("Gotify messenger issue")          //nolint:stylecheck
+	ErrMastodon      = errors.New("Mastodon messenger issue")        //nolint:stylecheck
+	ErrTwilio        = errors.New("Twilio messenger issue")          //nolint:stylecheck
+	ErrRocketChat    = errors.New("Rocket.Chat messenger issue")     //nolint:stylecheck
+	ErrMQTT          = errors.New("MQTT messenger issue")            //nolint:stylecheck
+	ErrIRC           = errors.New("IRC messenger issue")             //nolint:stylecheck
+	ErrPushbullet    = errors.New("Pushbullet messenger issue")      //nolint:stylecheck
+	ErrHomeAssistant = errors.New("Home Assistant messenger issue")  //nolint:stylecheck
+	ErrApprise       = errors.New("Apprise messenger issue")         //nolint:stylecheck
+	ErrJSONLines     = errors.New("JSON Lines messenger issue")      //nolint:stylecheck
+	ErrFamilyDigest  = errors.New("family digest issue")
+
+	enrollmentBucket = "classes"
+	gradeBucket      = "grade-rows"
+	scheduleBucket   = "schedule"
+	quietQueue       = "quiet"
+	digestQueue      = "digest"
 )
 
-// scrapers will call subjects/grades/exams scraping for every configured AAI/AOSI user and send grades/exams messages
-// to a channel.
-func scrapers(ctx context.Context, wgScrape *sync.WaitGroup, gradesScraped chan<- msgtypes.Message, config tomlConfig) {
+// scrapers will call subjects/grades/exams scraping for every configured AAI/AOSI user, with a limited number of users
+// scraped concurrently, and send grades/exams messages to a channel. Only non-transient (auth and parse) scrape
+// failures mark the run as failed. Users with own poll intervals are scraped only if due in the run starting at now.
+func scrapers(ctx context.Context, wgScrape *sync.WaitGroup, gradesScraped chan<- msgtypes.Message, p *profile,
+	now time.Time,
+) {
 	logger.Debug().Msg("Starting scrapers")
 
+	config := p.config
+
+	// limit concurrently scraped users
+	sem := make(chan struct{}, *userConcurrency)
+
 	for _, i := range config.User {
+		// users with own poll intervals are scraped only when due
+		if !p.due(i, now) {
+			logger.Debug().Msgf("Skipping user %v, poll interval %v not elapsed yet", i.Username, i.interval())
+
+			continue
+		}
+
 		wgScrape.Add(1)
 
 		go func() {
 			defer wgScrape.Done()
 
-			err := scrape.GetGradesAndEvents(ctx, gradesScraped, i.Username, i.Password, *retries)
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-sem }()
+
+			err := scrape.GetGradesAndEvents(ctx, gradesScraped, i.Username, i.Password, config.fetchOptions(), *classConcurrency,
+				*retries, *retryDelay, *schedule, *gradeCutoff)
 			if err != nil {
-				logger.Warn().Msgf("%v %v: %v", ErrScrapingUser, i.Username, err)
-				exitWithError.Store(true)
+				class := scrape.Classify(err)
+
+				metrics.ScrapeFailure.WithLabelValues(i.Username).Inc()
+				logger.Warn().Msgf("%v %v (%v error): %v", ErrScrapingUser, i.Username, class, err)
+				p.reportScrape(i.Username, err)
+
+				// transient failures are retried on the next run and do not fail this one
+				if !class.Transient() {
+					p.failed.Store(true)
+				}
+
+				return
 			}
+
+			p.reportScrape(i.Username, nil)
+			metrics.ScrapeSuccess.WithLabelValues(i.Username).Inc()
+			metrics.LastScrape.SetToCurrentTime()
 		}()
 	}
 }
 
-// msgSend will process grades/exams messages and broadcast to one or more message services.
-func msgSend(ctx context.Context, wgMsg *sync.WaitGroup, gradesMsg <-chan msgtypes.Message, config tomlConfig) {
+// selectMessengers disables messengers (and family digest along with mail) not selected with --only-messenger or
+// deselected with --skip-messenger for the current run, logging the effective set of active messengers if any of the flags is set.
+func selectMessengers(config *tomlConfig) {
+	if len(*onlyMessengers) == 0 && len(*skipMessengers) == 0 {
+		return
+	}
+
+	switches := config.messengerSwitches()
+	active := make([]string, 0, len(messengerNames))
+
+	for _, name := range messengerNames {
+		enabled := switches[name]
+
+		if (len(*onlyMessengers) > 0 && !slices.Contains(*onlyMessengers, name)) ||
+			slices.Contains(*skipMessengers, name) {
+			*enabled = false
+		}
+
+		if *enabled {
+			active = append(active, name)
+		}
+	}
+
+	// family digest is delivered through the mail messenger
+	if !config.mailEnabled {
+		config.familyEnabled = false
+	}
+
+	if len(active) == 0 {
+		logger.Warn().Msg("No active messengers after command line overrides, alerts will not be sent")
+
+		return
+	}
+
+	logger.Info().Msgf("Active messengers after command line overrides: %v", strings.Join(active, ", "))
+}
+
+// msgSend will process grades/exams messages and broadcast to one or more message services. Alerts held back during
+// quiet hours and alerts waiting for a digest are queued in the alert database, and both quiet hours and digest mode
+// are ignored without a database.
+func msgSend(ctx context.Context, wgMsg *sync.WaitGroup, gradesMsg <-chan msgtypes.Message, p *profile, eDB *db.Edb) {
+	config := p.config
+	selectMessengers(&config)
+
 	wgMsg.Add(1)
 
 	go func() {
 		defer wgMsg.Done()
 
+		// dry-run: only log alerts and never send them
+		if *dryRun {
+			for g := range gradesMsg {
+				p.countAlert(g.Username)
+				logger.Info().Msgf("Dry run, not sending alert for: %v/%v: %+v", g.Username, g.Subject, g)
+			}
+
+			return
+		}
+
 		bcast := broadcast.NewBroadcaster(broadcastBufLen)
 		defer bcast.Close()
 
+		targets := userTargets(config)
+
+		// circuit breakers of the profile messengers
+		ctx := messenger.WithBreakers(ctx, &p.breakers)
+
+		// messages skipped by tripped circuit breakers in previous runs, sent again by active messengers
+		failed := make(map[string][]msgtypes.Message)
+
+		for name, enabled := range config.messengerSwitches() {
+			if !*enabled || eDB == nil {
+				continue
+			}
+
+			msgs, err := eDB.PopFailed(name)
+			if err != nil {
+				logger.Error().Msgf("Problem with database, unable to fetch messages skipped by %v messenger: %v", name, err)
+				p.failed.Store(true)
+
+				continue
+			}
+
+			if len(msgs) > 0 {
+				logger.Info().Msgf("Sending %v messages skipped by %v messenger in previous runs", len(msgs), name)
+				failed[name] = msgs
+			}
+		}
+
 		// Discord sender
 		if config.discordEnabled {
 			ch := make(chan interface{}) // broadcast listener
@@ -101,9 +241,23 @@ func msgSend(ctx context.Context, wgMsg *sync.WaitGroup, gradesMsg <-chan msgtyp
 				defer wgMsg.Done()
 				logger.Debug().Msg("Discord messenger started")
 
-				if err := messenger.Discord(ctx, ch, config.Discord.Token, config.Discord.UserIDs, *retries); err != nil {
+				ctx, span := tracing.Start(ctx, "send", tracing.Messenger(discordName))
+
+				var err error
+
+				// channel webhooks are used instead of a bot
+				if len(config.Discord.Webhooks) > 0 {
+					err = messenger.DiscordWebhook(ctx, filterTargets(ch, discordName, targets, failed[discordName]), config.Discord.Webhooks, config.Discord.RateLimit, config.Discord.Window, *retries)
+				} else {
+					err = messenger.Discord(ctx, filterTargets(ch, discordName, targets, failed[discordName]), config.Discord.Token, config.Discord.UserIDs, config.Discord.RateLimit, config.Discord.Window, *retries)
+				}
+
+				tracing.End(span, err)
+				p.report(discordName, err)
+
+				if err != nil {
 					logger.Warn().Msgf("%v: %v", ErrDiscord, err)
-					exitWithError.Store(true)
+					p.failed.Store(true)
 				}
 			}()
 		}
@@ -122,9 +276,17 @@ func msgSend(ctx context.Context, wgMsg *sync.WaitGroup, gradesMsg <-chan msgtyp
 				defer wgMsg.Done()
 				logger.Debug().Msg("Telegram messenger started")
 
-				if err := messenger.Telegram(ctx, ch, config.Telegram.Token, config.Telegram.ChatIDs, *retries); err != nil {
+				ctx, span := tracing.Start(ctx, "send", tracing.Messenger(telegramName))
+
+				err := messenger.Telegram(ctx, filterTargets(ch, telegramName, targets, failed[telegramName]), config.Telegram.Token, config.Telegram.ChatIDs, config.Telegram.Topics,
+					config.Telegram.UrgentDays, config.Telegram.PinUrgent, config.Telegram.Silent, config.Telegram.RateLimit, config.Telegram.Window, *retries)
+
+				tracing.End(span, err)
+				p.report(telegramName, err)
+
+				if err != nil {
 					logger.Warn().Msgf("%v: %v", ErrTelegram, err)
-					exitWithError.Store(true)
+					p.failed.Store(true)
 				}
 			}()
 		}
@@ -143,15 +305,22 @@ func msgSend(ctx context.Context, wgMsg *sync.WaitGroup, gradesMsg <-chan msgtyp
 				defer wgMsg.Done()
 				logger.Debug().Msg("Slack messenger started")
 
-				if err := messenger.Slack(ctx, ch, config.Slack.Token, config.Slack.ChatIDs, *retries); err != nil {
+				ctx, span := tracing.Start(ctx, "send", tracing.Messenger(slackName))
+
+				err := messenger.Slack(ctx, filterTargets(ch, slackName, targets, failed[slackName]), config.Slack.Token, config.Slack.ChatIDs, config.Slack.Threads, config.Slack.RateLimit, config.Slack.Window, *retries)
+
+				tracing.End(span, err)
+				p.report(slackName, err)
+
+				if err != nil {
 					logger.Warn().Msgf("%v: %v", ErrSlack, err)
-					exitWithError.Store(true)
+					p.failed.Store(true)
 				}
 			}()
 		}
 
-		// mail Sender
-		if config.mailEnabled {
+		// Microsoft Teams sender
+		if config.teamsEnabled {
 			ch := make(chan interface{}) // broadcast listener
 			defer close(ch)
 
@@ -162,17 +331,24 @@ func msgSend(ctx context.Context, wgMsg *sync.WaitGroup, gradesMsg <-chan msgtyp
 
 			go func() {
 				defer wgMsg.Done()
-				logger.Debug().Msg("Mail messenger started")
+				logger.Debug().Msg("Microsoft Teams messenger started")
 
-				if err := messenger.Mail(ctx, ch, config.Mail.Server, config.Mail.Port, config.Mail.Username, config.Mail.Password, config.Mail.From, config.Mail.Subject, config.Mail.To, *retries); err != nil {
-					logger.Warn().Msgf("%v: %v", ErrMail, err)
-					exitWithError.Store(true)
+				ctx, span := tracing.Start(ctx, "send", tracing.Messenger(teamsName))
+
+				err := messenger.Teams(ctx, filterTargets(ch, teamsName, targets, failed[teamsName]), config.Teams.Webhooks, config.Teams.RateLimit, config.Teams.Window, *retries)
+
+				tracing.End(span, err)
+				p.report(teamsName, err)
+
+				if err != nil {
+					logger.Warn().Msgf("%v: %v", ErrTeams, err)
+					p.failed.Store(true)
 				}
 			}()
 		}
 
-		// Google Calendar Sender
-		if config.calendarEnabled {
+		// Pushover sender
+		if config.pushoverEnabled {
 			ch := make(chan interface{}) // broadcast listener
 			defer close(ch)
 
@@ -183,96 +359,989 @@ func msgSend(ctx context.Context, wgMsg *sync.WaitGroup, gradesMsg <-chan msgtyp
 
 			go func() {
 				defer wgMsg.Done()
-				logger.Debug().Msgf("Calendar messenger started")
+				logger.Debug().Msg("Pushover messenger started")
 
-				if err := messenger.Calendar(ctx, ch, config.Calendar.Name, *calTokFile, *retries); err != nil {
-					logger.Warn().Msgf("%v: %v", ErrCalendar, err)
-					exitWithError.Store(true)
+				ctx, span := tracing.Start(ctx, "send", tracing.Messenger(pushoverName))
+
+				err := messenger.Pushover(ctx, filterTargets(ch, pushoverName, targets, failed[pushoverName]), config.Pushover.Token, config.Pushover.UserKeys, config.Pushover.Priority, config.Pushover.examPriority(), config.Pushover.RateLimit, config.Pushover.Window, *retries)
+
+				tracing.End(span, err)
+				p.report(pushoverName, err)
+
+				if err != nil {
+					logger.Warn().Msgf("%v: %v", ErrPushover, err)
+					p.failed.Store(true)
 				}
 			}()
 		}
 
-		// broadcast incoming messages
-		for g := range gradesMsg {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-				bcast.Submit(g)
-			}
+		// Gotify sender
+		if config.gotifyEnabled {
+			ch := make(chan interface{}) // broadcast listener
+			defer close(ch)
+
+			bcast.Register(ch) // broadcast registration
+			defer bcast.Unregister(ch)
+
+			wgMsg.Add(1)
+
+			go func() {
+				defer wgMsg.Done()
+				logger.Debug().Msg("Gotify messenger started")
+
+				ctx, span := tracing.Start(ctx, "send", tracing.Messenger(gotifyName))
+
+				err := messenger.Gotify(ctx, filterTargets(ch, gotifyName, targets, failed[gotifyName]), config.Gotify.Server, config.Gotify.Token, config.Gotify.priority(), config.Gotify.InsecureSkipVerify, config.Gotify.RateLimit, config.Gotify.Window, *retries)
+
+				tracing.End(span, err)
+				p.report(gotifyName, err)
+
+				if err != nil {
+					logger.Warn().Msgf("%v: %v", ErrGotify, err)
+					p.failed.Store(true)
+				}
+			}()
 		}
-	}()
-}
 
-// msgDedup acts like a filter: processes all incoming messages, calls in to database check and if it hasn't been found
-// and if it is not an initial run, it will pass through to messengers for further alerting.
-func msgDedup(ctx context.Context, wgFilter *sync.WaitGroup, gradesScraped <-chan msgtypes.Message, gradesMsg chan<- msgtypes.Message) {
-	wgFilter.Add(1)
+		// Mastodon sender
+		if config.mastodonEnabled {
+			ch := make(chan interface{}) // broadcast listener
+			defer close(ch)
 
-	go func() {
-		defer wgFilter.Done()
+			bcast.Register(ch) // broadcast registration
+			defer bcast.Unregister(ch)
 
-		// open KV store
-		eDB, err := db.New(*dbFile)
-		if err != nil {
-			logger.Fatal().Msgf("Unable to open database: %v", err)
+			wgMsg.Add(1)
+
+			go func() {
+				defer wgMsg.Done()
+				logger.Debug().Msg("Mastodon messenger started")
+
+				ctx, span := tracing.Start(ctx, "send", tracing.Messenger(mastodonName))
+
+				err := messenger.Mastodon(ctx, filterTargets(ch, mastodonName, targets, failed[mastodonName]), config.Mastodon.Instance, config.Mastodon.Token, config.Mastodon.Visibility, config.Mastodon.Accounts, config.Mastodon.InsecureSkipVerify, config.Mastodon.RateLimit, config.Mastodon.Window, *retries)
+
+				tracing.End(span, err)
+				p.report(mastodonName, err)
+
+				if err != nil {
+					logger.Warn().Msgf("%v: %v", ErrMastodon, err)
+					p.failed.Store(true)
+				}
+			}()
+		}
+
+		// Twilio sender
+		if config.twilioEnabled {
+			ch := make(chan interface{}) // broadcast listener
+			defer close(ch)
+
+			bcast.Register(ch) // broadcast registration
+			defer bcast.Unregister(ch)
+
+			wgMsg.Add(1)
+
+			go func() {
+				defer wgMsg.Done()
+				logger.Debug().Msg("Twilio messenger started")
+
+				ctx, span := tracing.Start(ctx, "send", tracing.Messenger(twilioName))
+
+				err := messenger.Twilio(ctx, filterTargets(ch, twilioName, targets, failed[twilioName]), config.Twilio.AccountSID, config.Twilio.Token, config.Twilio.From, config.Twilio.To, config.Twilio.RateLimit, config.Twilio.Window, *retries)
+
+				tracing.End(span, err)
+				p.report(twilioName, err)
+
+				if err != nil {
+					logger.Warn().Msgf("%v: %v", ErrTwilio, err)
+					p.failed.Store(true)
+				}
+			}()
+		}
+
+		// Rocket.Chat sender
+		if config.rocketChatEnabled {
+			ch := make(chan interface{}) // broadcast listener
+			defer close(ch)
+
+			bcast.Register(ch) // broadcast registration
+			defer bcast.Unregister(ch)
+
+			wgMsg.Add(1)
+
+			go func() {
+				defer wgMsg.Done()
+				logger.Debug().Msg("Rocket.Chat messenger started")
+
+				ctx, span := tracing.Start(ctx, "send", tracing.Messenger(rocketChatName))
+
+				err := messenger.RocketChat(ctx, filterTargets(ch, rocketChatName, targets, failed[rocketChatName]), config.RocketChat.Server, config.RocketChat.UserID, config.RocketChat.Token, config.RocketChat.Channels, config.RocketChat.InsecureSkipVerify, config.RocketChat.RateLimit, config.RocketChat.Window, *retries)
+
+				tracing.End(span, err)
+				p.report(rocketChatName, err)
+
+				if err != nil {
+					logger.Warn().Msgf("%v: %v", ErrRocketChat, err)
+					p.failed.Store(true)
+				}
+			}()
 		}
-		defer eDB.Close()
 
-		if !eDB.Existing() {
-			logger.Info().Msg("Newly initialized database, won't sent alerts in this run")
+		// MQTT publisher
+		if config.mqttEnabled {
+			ch := make(chan interface{}) // broadcast listener
+			defer close(ch)
+
+			bcast.Register(ch) // broadcast registration
+			defer bcast.Unregister(ch)
+
+			wgMsg.Add(1)
+
+			go func() {
+				defer wgMsg.Done()
+				logger.Debug().Msg("MQTT messenger started")
+
+				ctx, span := tracing.Start(ctx, "send", tracing.Messenger(mqttName))
+
+				err := messenger.MQTT(ctx, filterTargets(ch, mqttName, targets, failed[mqttName]), config.MQTT.Broker, config.MQTT.ClientID, config.MQTT.TopicPrefix, config.MQTT.Username, config.MQTT.Password, config.MQTT.qos(), config.MQTT.Retained, config.MQTT.RateLimit, config.MQTT.Window, *retries)
+
+				tracing.End(span, err)
+				p.report(mqttName, err)
+
+				if err != nil {
+					logger.Warn().Msgf("%v: %v", ErrMQTT, err)
+					p.failed.Store(true)
+				}
+			}()
 		}
 
-		// cache current time for later
-		now := time.Now()
+		// IRC sender
+		if config.ircEnabled {
+			ch := make(chan interface{}) // broadcast listener
+			defer close(ch)
 
-		for g := range gradesScraped {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-				// log all events
-				if *debugEvents {
-					logger.Debug().Msgf("Received event for: %v/%v: %+v", g.Username, g.Subject, g)
+			bcast.Register(ch) // broadcast registration
+			defer bcast.Unregister(ch)
+
+			wgMsg.Add(1)
+
+			go func() {
+				defer wgMsg.Done()
+				logger.Debug().Msg("IRC messenger started")
+
+				ctx, span := tracing.Start(ctx, "send", tracing.Messenger(ircName))
+
+				err := messenger.IRC(ctx, filterTargets(ch, ircName, targets, failed[ircName]), config.IRC.Server, config.IRC.port(), config.IRC.TLS, config.IRC.Nick, config.IRC.Channels, config.IRC.SASLUser, config.IRC.SASLPassword, config.IRC.RateLimit, config.IRC.Window, *retries)
+
+				tracing.End(span, err)
+				p.report(ircName, err)
+
+				if err != nil {
+					logger.Warn().Msgf("%v: %v", ErrIRC, err)
+					p.failed.Store(true)
 				}
+			}()
+		}
+
+		// Pushbullet sender
+		if config.pushbulletEnabled {
+			ch := make(chan interface{}) // broadcast listener
+			defer close(ch)
+
+			bcast.Register(ch) // broadcast registration
+			defer bcast.Unregister(ch)
+
+			wgMsg.Add(1)
+
+			go func() {
+				defer wgMsg.Done()
+				logger.Debug().Msg("Pushbullet messenger started")
+
+				ctx, span := tracing.Start(ctx, "send", tracing.Messenger(pushbulletName))
+
+				err := messenger.Pushbullet(ctx, filterTargets(ch, pushbulletName, targets, failed[pushbulletName]), config.Pushbullet.Token, config.Pushbullet.DeviceIDs, config.Pushbullet.RateLimit, config.Pushbullet.Window, *retries)
+
+				tracing.End(span, err)
+				p.report(pushbulletName, err)
 
-				// check if it is an already known alert
-				found, err := eDB.CheckAndFlag(g.Username, g.Subject, g.Fields)
 				if err != nil {
-					logger.Fatal().Msgf("Problem with database, cannot continue: %v", err)
-				}
-
-				// check if is the initial run and send only if not
-				if !found && eDB.Existing() {
-					// check if it is an old event that should be ignored
-					if *relevancePeriod > 0 && !g.IsExam && len(g.Fields) > 0 {
-						t, err := time.Parse(formatHRDateOnly, g.Fields[0])
-						if err != nil {
-							logger.Error().Msgf("Unable to parse date for: %v/%v: %+v: %v", g.Username, g.Subject, g, err)
-						} else {
-							// assume current or previous year
-							if t.Month() > now.Month() {
-								t = t.AddDate(now.Year()-1, 0, 0)
-							} else {
-								t = t.AddDate(now.Year(), 0, 0)
-							}
-
-							if time.Since(t) > *relevancePeriod {
-								logger.Warn().Msgf("Ignoring changes in an old event: %v/%v: %+v", g.Username, g.Subject, g)
-
-								continue
-							}
-						}
-					}
+					logger.Warn().Msgf("%v: %v", ErrPushbullet, err)
+					p.failed.Store(true)
+				}
+			}()
+		}
 
-					logger.Info().Msgf("New alert for: %v/%v: %+v", g.Username, g.Subject, g)
-					gradesMsg <- g
+		// Home Assistant sender
+		if config.homeAssistantEnabled {
+			ch := make(chan interface{}) // broadcast listener
+			defer close(ch)
+
+			bcast.Register(ch) // broadcast registration
+			defer bcast.Unregister(ch)
+
+			wgMsg.Add(1)
+
+			go func() {
+				defer wgMsg.Done()
+				logger.Debug().Msg("Home Assistant messenger started")
+
+				ctx, span := tracing.Start(ctx, "send", tracing.Messenger(homeAssistantName))
+
+				err := messenger.HomeAssistant(ctx, filterTargets(ch, homeAssistantName, targets, failed[homeAssistantName]), config.HomeAssistant.Server, config.HomeAssistant.Token, config.HomeAssistant.Services, config.HomeAssistant.InsecureSkipVerify, config.HomeAssistant.RateLimit, config.HomeAssistant.Window, *retries)
+
+				tracing.End(span, err)
+				p.report(homeAssistantName, err)
+
+				if err != nil {
+					logger.Warn().Msgf("%v: %v", ErrHomeAssistant, err)
+					p.failed.Store(true)
 				}
-			}
+			}()
 		}
 
-		close(gradesMsg)
-	}()
+		// Apprise sender
+		if config.appriseEnabled {
+			ch := make(chan interface{}) // broadcast listener
+			defer close(ch)
+
+			bcast.Register(ch) // broadcast registration
+			defer bcast.Unregister(ch)
+
+			wgMsg.Add(1)
+
+			go func() {
+				defer wgMsg.Done()
+				logger.Debug().Msg("Apprise messenger started")
+
+				ctx, span := tracing.Start(ctx, "send", tracing.Messenger(appriseName))
+
+				err := messenger.Apprise(ctx, filterTargets(ch, appriseName, targets, failed[appriseName]), config.Apprise, *retries)
+
+				tracing.End(span, err)
+				p.report(appriseName, err)
+
+				if err != nil {
+					logger.Warn().Msgf("%v: %v", ErrApprise, err)
+					p.failed.Store(true)
+				}
+			}()
+		}
+
+		// JSON Lines sender
+		if config.jsonLinesEnabled {
+			ch := make(chan interface{}) // broadcast listener
+			defer close(ch)
+
+			bcast.Register(ch) // broadcast registration
+			defer bcast.Unregister(ch)
+
+			wgMsg.Add(1)
+
+			go func() {
+				defer wgMsg.Done()
+				logger.Debug().Msg("JSON Lines messenger started")
+
+				ctx, span := tracing.Start(ctx, "send", tracing.Messenger(jsonLinesName))
+
+				err := messenger.JSONLines(ctx, filterTargets(ch, jsonLinesName, targets, failed[jsonLinesName]), config.JSONLines.Path)
+
+				tracing.End(span, err)
+				p.report(jsonLinesName, err)
+
+				if err != nil {
+					logger.Warn().Msgf("%v: %v", ErrJSONLines, err)
+					p.failed.Store(true)
+				}
+			}()
+		}
+
+		// mail Sender
+		if config.mailEnabled {
+			ch := make(chan interface{}) // broadcast listener
+			defer close(ch)
+
+			bcast.Register(ch) // broadcast registration
+			defer bcast.Unregister(ch)
+
+			wgMsg.Add(1)
+
+			go func() {
+				defer wgMsg.Done()
+				logger.Debug().Msg("Mail messenger started")
+
+				ctx, span := tracing.Start(ctx, "send", tracing.Messenger(mailName))
+
+				err := messenger.Mail(ctx, filterTargets(ch, mailName, targets, failed[mailName]), config.Mail.Server, config.Mail.Port, config.Mail.Username, config.Mail.Password, config.Mail.Auth, config.Mail.TLS, config.Mail.InsecureSkipVerify, config.mailTokens, config.Mail.From, config.Mail.Subject, config.Mail.To, config.Mail.AttachICS, config.Mail.RateLimit, config.Mail.Window, *retries)
+
+				tracing.End(span, err)
+				p.report(mailName, err)
+
+				if err != nil {
+					logger.Warn().Msgf("%v: %v", ErrMail, err)
+					p.failed.Store(true)
+				}
+			}()
+		}
+
+		// Google Calendar Sender
+		if config.calendarEnabled {
+			ch := make(chan interface{}) // broadcast listener
+			defer close(ch)
+
+			bcast.Register(ch) // broadcast registration
+			defer bcast.Unregister(ch)
+
+			wgMsg.Add(1)
+
+			go func() {
+				defer wgMsg.Done()
+				logger.Debug().Msgf("Calendar messenger started")
+
+				ctx, span := tracing.Start(ctx, "send", tracing.Messenger(calendarName))
+
+				err := messenger.Calendar(ctx, filterTargets(ch, calendarName, targets, failed[calendarName]), config.Calendar.Name, p.calTokFile, config.Calendar.Reminders, config.Calendar.ReminderMethod, config.Calendar.Duration, config.Calendar.EventPrefix, config.Calendar.ColorID, config.Calendar.RateLimit, config.Calendar.Window, *retries)
+
+				tracing.End(span, err)
+				p.report(calendarName, err)
+
+				if err != nil {
+					logger.Warn().Msgf("%v: %v", ErrCalendar, err)
+					p.failed.Store(true)
+				}
+			}()
+		}
+
+		// family digest of all messages in this run
+		digest := make(map[string][]msgtypes.Message)
+
+		// hashes of all messages broadcasted in this run
+		sent := make(map[[sha256.Size]byte]struct{})
+
+		digestEnabled := eDB != nil && config.digestEnabled
+
+		// dispatch broadcasts a message or queues it for a digest
+		dispatch := func(g msgtypes.Message) {
+			// in digest mode queue all alerts except for exams, which are time-sensitive
+			if digestEnabled && g.Code != msgtypes.Exam {
+				if err := eDB.AppendQueue(digestQueue, g); err != nil {
+					logger.Error().Msgf("Problem with database, sending alert without digest: %v", err)
+					p.failed.Store(true)
+					bcast.Submit(g)
+				}
+			} else {
+				bcast.Submit(g)
+			}
+
+			if config.familyEnabled {
+				digest[g.Username] = append(digest[g.Username], g)
+			}
+		}
+
+		now := time.Now()
+		quiet := eDB != nil && config.quietEnabled && config.QuietHours.active(now)
+
+		// send alerts held back during quiet hours once they are over
+		if !quiet && eDB != nil {
+			held, err := eDB.PopQueue(quietQueue)
+			if err != nil {
+				logger.Error().Msgf("Problem with database, unable to fetch held back alerts: %v", err)
+				p.failed.Store(true)
+			}
+
+			if len(held) > 0 {
+				logger.Info().Msgf("Quiet hours are over, sending %v held back alerts", len(held))
+			}
+
+			for _, g := range held {
+				dispatch(g)
+			}
+		}
+
+		// broadcast incoming messages
+		for g := range gradesMsg {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+				// never broadcast a byte-identical message twice in the same run
+				if key, err := messageHash(g); err == nil {
+					if _, found := sent[key]; found {
+						logger.Debug().Msgf("Skipping duplicate alert for: %v/%v: %+v", g.Username, g.Subject, g)
+
+						continue
+					}
+
+					sent[key] = struct{}{}
+				}
+
+				p.countAlert(g.Username)
+
+				// during quiet hours hold back alerts until they are over
+				if quiet && config.QuietHours.holds(g, now) {
+					err := eDB.AppendQueue(quietQueue, g)
+					if err == nil {
+						logger.Debug().Msgf("Quiet hours, holding back alert for: %v/%v: %+v", g.Username, g.Subject, g)
+
+						continue
+					}
+
+					logger.Error().Msgf("Problem with database, sending alert despite quiet hours: %v", err)
+					p.failed.Store(true)
+				}
+
+				dispatch(g)
+			}
+		}
+
+		// send pending digest if due, or right away once digest mode has been disabled
+		if eDB != nil {
+			now = time.Now()
+			p.digest.runs++
+
+			if !digestEnabled || config.Digest.due(now, p.digest.last, p.digest.runs) {
+				msgs, err := eDB.PopQueue(digestQueue)
+				if err != nil {
+					logger.Error().Msgf("Problem with database, unable to fetch digest alerts: %v", err)
+					p.failed.Store(true)
+				}
+
+				if len(msgs) > 0 {
+					logger.Info().Msgf("Sending digest of %v alerts", len(msgs))
+
+					for _, d := range format.DigestMsg(msgs) {
+						bcast.Submit(d)
+					}
+				}
+
+				p.digest = digestState{last: now}
+			}
+		}
+
+		// send family digest once
+		if config.familyEnabled && len(digest) > 0 {
+			logger.Debug().Msg("Sending family digest")
+
+			if err := messenger.SendMailDigest(ctx, config.Mail.Server, config.Mail.Port, config.Mail.Username,
+				config.Mail.Password, config.Mail.Auth, config.Mail.TLS, config.Mail.InsecureSkipVerify, config.mailTokens, config.Mail.From, config.Family.Subject, config.Family.To,
+				format.FamilyDigest(digest), *retries); err != nil {
+				logger.Warn().Msgf("%v: %v", ErrFamilyDigest, err)
+				p.failed.Store(true)
+			}
+		}
+	}()
+}
+
+// messageHash returns SHA-256 hash of JSON encoded message, identifying byte-identical messages.
+func messageHash(g msgtypes.Message) ([sha256.Size]byte, error) {
+	b, err := json.Marshal(g)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+
+	return sha256.Sum256(b), nil
+}
+
+// messengerTargets holds messengers targeted per user and messengers coalescing alerts.
+type messengerTargets struct {
+	users    map[string]map[string]struct{} // messenger names per username, only for users with explicit targets
+	coalesce map[string]struct{}            // messenger names receiving one combined message per user and run
+}
+
+// userTargets builds a set of messenger names per username, skipping users without explicitly configured targets, and
+// a set of messenger names coalescing alerts.
+func userTargets(config tomlConfig) messengerTargets {
+	targets := messengerTargets{
+		users:    make(map[string]map[string]struct{}, len(config.User)),
+		coalesce: make(map[string]struct{}, len(config.Coalesce)),
+	}
+
+	for _, u := range config.User {
+		if len(u.Targets) == 0 {
+			continue
+		}
+
+		t := make(map[string]struct{}, len(u.Targets))
+		for _, name := range u.Targets {
+			t[name] = struct{}{}
+		}
+
+		targets.users[u.Username] = t
+	}
+
+	for _, name := range config.Coalesce {
+		targets.coalesce[name] = struct{}{}
+	}
+
+	return targets
+}
+
+// filterTargets passes through only messages belonging to users that either target the named messenger or have no
+// explicit targets at all, returning a filtered channel that gets closed when the input channel is closed. If the named
+// messenger coalesces alerts, messages are held back until the input channel is closed and then passed through as a
+// single combined message per user. Messages skipped by the messenger in previous runs are passed through first as they
+// are, as they have already been filtered.
+func filterTargets(ch <-chan interface{}, name string, targets messengerTargets, skipped []msgtypes.Message,
+) <-chan interface{} {
+	out := make(chan interface{})
+
+	_, coalesce := targets.coalesce[name]
+
+	go func() {
+		defer close(out)
+
+		for _, g := range skipped {
+			out <- g
+		}
+
+		var held []msgtypes.Message
+
+		for o := range ch {
+			if g, ok := o.(msgtypes.Message); ok {
+				if t, found := targets.users[g.Username]; found {
+					if _, enabled := t[name]; !enabled {
+						continue
+					}
+				}
+
+				if coalesce {
+					held = append(held, g)
+
+					continue
+				}
+			}
+
+			out <- o
+		}
+
+		for _, g := range coalesceMessages(held) {
+			out <- g
+		}
+	}()
+
+	return out
+}
+
+// coalesceMessages combines messages of each user into a single digest message, keeping messages of users with just a
+// single message as they are.
+func coalesceMessages(msgs []msgtypes.Message) []msgtypes.Message {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	count := make(map[string]int)
+	for _, g := range msgs {
+		count[g.Username]++
+	}
+
+	var single, multiple []msgtypes.Message
+
+	for _, g := range msgs {
+		if count[g.Username] == 1 {
+			single = append(single, g)
+		} else {
+			multiple = append(multiple, g)
+		}
+	}
+
+	return append(single, format.DigestMsg(multiple)...)
+}
+
+// msgDedup acts like a filter: processes all incoming messages, calls in to database check and if it hasn't been found
+// and if it is not an initial run, it will pass through to messengers for further alerting. Database errors mark the
+// profile run as failed, dropping the remaining messages of the run.
+func msgDedup(ctx context.Context, wgFilter *sync.WaitGroup, gradesScraped <-chan msgtypes.Message, gradesMsg chan<- msgtypes.Message, p *profile,
+	eDB *db.Edb,
+) {
+	wgFilter.Add(1)
+
+	go func() {
+		defer wgFilter.Done()
+		defer close(gradesMsg)
+
+		if err := dedup(ctx, gradesScraped, gradesMsg, p, eDB); err != nil {
+			logger.Error().Msgf("Problem with database, skipping remaining alerts of this run: %v", err)
+			p.failed.Store(true)
+
+			// drain remaining scraped events so that scrapers are not blocked
+			for range gradesScraped {
+			}
+		}
+	}()
+}
+
+// dedup processes all incoming messages for msgDedup, returning on the first database error.
+func dedup(ctx context.Context, gradesScraped <-chan msgtypes.Message, gradesMsg chan<- msgtypes.Message, p *profile,
+	eDB *db.Edb,
+) (err error) {
+	config := p.config
+
+	_, span := tracing.Start(ctx, "dedup", tracing.Profile(p.name))
+	defer func() { tracing.End(span, err) }()
+
+	// dry-run: never create a new database
+	if eDB == nil {
+		logger.Info().Msg("Dry run without an existing database, no alerts would be sent in this run")
+
+		// drain all scraped events
+		for range gradesScraped {
+		}
+
+		return nil
+	}
+
+	// initial run only records events, unless asked to send them all, and so does the run upgrading database schema
+	sendAlerts := (eDB.Existing() && !eDB.Upgraded()) || (!eDB.Existing() && *seedAndSend)
+
+	switch {
+	case eDB.Upgraded():
+		logger.Info().Msg("Upgraded database schema, won't send alerts in this run")
+	case !eDB.Existing() && *seedAndSend:
+		logger.Info().Msg("Newly initialized database, sending alerts for all current events in this run")
+	case !eDB.Existing():
+		logger.Info().Msg("Newly initialized database, won't sent alerts in this run")
+	}
+
+	// cache current time for later
+	now := time.Now()
+
+	// per-user subject filters and display names
+	users := make(map[string]user, len(config.User))
+	for _, u := range config.User {
+		users[u.Username] = u
+	}
+
+	// displayName sets configured student display name, dropping scraped student name unless friendly names are
+	// enabled
+	displayName := func(g msgtypes.Message) msgtypes.Message {
+		switch u, ok := users[g.Username]; {
+		case ok && u.DisplayName != "":
+			g.Student = u.DisplayName
+		case !config.FriendlyNames:
+			g.Student = ""
+		}
+
+		return g
+	}
+
+	// filtered reports if alerts for the event subject are not wanted for the user
+	filtered := func(g msgtypes.Message) bool {
+		if u, ok := users[g.Username]; ok && !u.wantsSubject(g.Subject) {
+			logger.Debug().Msgf("Dropping filtered subject alert for: %v/%v: %+v", g.Username, g.Subject, g)
+
+			return true
+		}
+
+		return false
+	}
+
+	// grade rows per user, subject and date seen in this run, and new grades waiting for them all to be known
+	gradeRows := make(map[gradeRow][][]string)
+
+	var newGrades []newGrade
+
+	for g := range gradesScraped {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+			g = displayName(g)
+
+			// log all events
+			if *debugEvents {
+				logger.Debug().Msgf("Received event for: %v/%v: %+v", g.Username, g.Subject, g)
+			}
+
+			// enrollment changes are tracked by comparing active classes with the previous run
+			if g.Code == msgtypes.EnrollmentChange {
+				if *enrollment {
+					if err := enrollmentChanges(eDB, g, gradesMsg); err != nil {
+						return err
+					}
+				}
+
+				continue
+			}
+
+			// timetable changes are tracked by comparing lessons with the previous run
+			if g.Code == msgtypes.Schedule {
+				if err := scheduleChanges(eDB, g, gradesMsg); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			// absences are unique by date, subject and status, regardless of the affected school period
+			target := g.Fields
+			if g.Code == msgtypes.Absence && len(target) > 2 {
+				target = target[:2]
+			}
+
+			// check if it is an already known alert, flagging it only if not in dry-run
+			check := func(code msgtypes.EventCode, bucket, subBucket string, target []string) (bool, error) {
+				return eDB.CheckAndFlagTTL(code, bucket, subBucket, target, *dbTTL)
+			}
+			if *dryRun {
+				check = eDB.Check
+			}
+
+			found, err := check(g.Code, g.Username, g.Subject, target)
+			if err != nil {
+				return err
+			}
+
+			if found {
+				metrics.DedupHits.Inc()
+			}
+
+			// collect grade rows of every date to tell edited grades apart from new ones
+			isGrade := g.Code == msgtypes.Grade && len(g.Fields) > 0
+
+			var row gradeRow
+			if isGrade {
+				row = gradeRow{g.Username, g.Subject, g.Fields[0]}
+				gradeRows[row] = append(gradeRows[row], g.Fields)
+			}
+
+			// check if is the initial run and send only if not (or if seeding)
+			if !found && sendAlerts {
+				// check if it is an old event that should be ignored
+				period := config.relevance(g.Code)
+				if period > 0 && slices.Contains(relevanceCodes, g.Code) && len(g.Fields) > 0 {
+					t, err := scrape.EventDate(g.Fields[0], now)
+					if err != nil {
+						logger.Error().Msgf("Unable to parse date for: %v/%v: %+v: %v", g.Username, g.Subject, g, err)
+					} else {
+						if time.Since(t) > period {
+							logger.Warn().Msgf("Ignoring changes in an old event: %v/%v: %+v", g.Username, g.Subject, g)
+
+							continue
+						}
+					}
+				}
+
+				if !filtered(g) {
+					// new grades are sent once all grade rows are known
+					if isGrade {
+						newGrades = append(newGrades, newGrade{g, row, len(gradeRows[row]) - 1})
+					} else {
+						logger.Info().Msgf("New alert for: %v/%v: %+v", g.Username, g.Subject, g)
+						tracing.Event(span, "alert", tracing.User(g.Username), tracing.Subject(g.Subject))
+						gradesMsg <- g
+					}
+				}
+			}
+
+			// remind once of exams within the reminder window, skipping the initial run and consuming the reminder
+			// without sending if the exam has just been alerted of
+			reminded := false
+
+			if *remindDays > 0 && g.Code == msgtypes.Exam && !*dryRun && (found || sendAlerts) &&
+				remindDue(g.Timestamp, *remindDays, now) {
+				due, err := eDB.Remind(g.Username, g.Subject, g.Fields, g.Timestamp, now)
+				if err != nil {
+					return err
+				}
+
+				if due && found && !filtered(g) {
+					logger.Info().Msgf("Reminding of an upcoming exam for: %v/%v: %+v", g.Username, g.Subject, g)
+
+					g.Reminder = true
+					gradesMsg <- g
+					reminded = true
+				}
+			}
+
+			// re-notify of upcoming exams in regular intervals
+			if *renotifyInterval > 0 && g.Code == msgtypes.Exam && !*dryRun {
+				due, err := eDB.Renotify(g.Username, g.Subject, g.Fields, *renotifyInterval, g.Timestamp, now)
+				if err != nil {
+					return err
+				}
+
+				if due && !reminded && !filtered(g) {
+					logger.Info().Msgf("Re-notifying of an upcoming exam for: %v/%v: %+v", g.Username, g.Subject, g)
+
+					g.Reminder = true
+					gradesMsg <- g
+				}
+			}
+		}
+	}
+
+	// compare grade rows with the previous run to tell what has been edited
+	edits, err := gradeEdits(eDB, gradeRows)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range newGrades {
+		g := n.msg
+		g.PreviousFields = edits[n.row][n.index]
+
+		logger.Info().Msgf("New alert for: %v/%v: %+v", g.Username, g.Subject, g)
+		tracing.Event(span, "alert", tracing.User(g.Username), tracing.Subject(g.Subject))
+		gradesMsg <- g
+	}
+
+	// all alerts are flagged with current keys once every user has been scraped
+	if !*dryRun && p.scrapedAll() {
+		if err := eDB.PutSchemaVersion(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// remindDue checks if an exam is still upcoming and takes place in at most days days.
+func remindDue(eventTime time.Time, days int, now time.Time) bool {
+	if eventTime.IsZero() {
+		return false
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	return eventTime.After(now) && eventTime.Before(today.AddDate(0, 0, days+1))
+}
+
+// gradeRow identifies grades of a user in a subject on a date.
+type gradeRow struct {
+	username, subject, date string
+}
+
+// newGrade is a new grade alert waiting for all grade rows to be known, along with its position among grades of the
+// same date.
+type newGrade struct {
+	msg   msgtypes.Message
+	row   gradeRow
+	index int
+}
+
+// gradeEdits compares grade rows of every user, subject and date with the ones stored in the previous run, returning
+// previous fields of edited grades keyed by their position among grades of the same date, and storing current rows
+// for the next run (unless in dry-run). A grade added on a date is never reported as an edit of another one.
+func gradeEdits(eDB *db.Edb, gradeRows map[gradeRow][][]string) (map[gradeRow]map[int][]string, error) {
+	edits := make(map[gradeRow]map[int][]string)
+
+	for row, current := range gradeRows {
+		subBucket := strings.Join([]string{gradeBucket, row.subject, row.date}, "/")
+
+		previous, found, err := eDB.GetRows(row.username, subBucket)
+		if err != nil {
+			return nil, err
+		}
+
+		if found && slices.EqualFunc(previous, current, slices.Equal[[]string]) {
+			continue
+		}
+
+		if found {
+			edits[row] = db.RowEdits(previous, current)
+		}
+
+		if !*dryRun {
+			if err := eDB.PutRows(row.username, subBucket, current); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return edits, nil
+}
+
+// enrollmentChanges compares active classes with the ones stored in the previous run, sending an alert for every added
+// and removed class and storing active classes for the next run (unless in dry-run).
+func enrollmentChanges(eDB *db.Edb, g msgtypes.Message, gradesMsg chan<- msgtypes.Message) error {
+	added, removed, err := setChanges(eDB, g.Username, enrollmentBucket, g.Fields)
+	if err != nil {
+		return err
+	}
+
+	for _, changes := range []struct {
+		classes []string
+		change  string
+	}{
+		{added, scrape.EnrollmentAdded},
+		{removed, scrape.EnrollmentRemove},
+	} {
+		for _, c := range changes.classes {
+			m := msgtypes.Message{
+				Code:         msgtypes.EnrollmentChange,
+				Username:     g.Username,
+				Student:      g.Student,
+				School:       g.School,
+				URL:          g.URL,
+				Subject:      c,
+				Descriptions: []string{scrape.EnrollmentChange},
+				Fields:       []string{changes.change},
+			}
+
+			logger.Info().Msgf("New alert for: %v/%v: %+v", m.Username, m.Subject, m)
+			gradesMsg <- m
+		}
+	}
+
+	return nil
+}
+
+// scheduleChanges compares class timetable lessons with the ones stored in the previous run, sending an alert for every
+// added and removed lesson.
+func scheduleChanges(eDB *db.Edb, g msgtypes.Message, gradesMsg chan<- msgtypes.Message) error {
+	added, removed, err := setChanges(eDB, g.Username, strings.Join([]string{scheduleBucket, g.Subject}, "/"), g.Fields)
+	if err != nil {
+		return err
+	}
+
+	for _, changes := range []struct {
+		lessons []string
+		change  string
+	}{
+		{added, scrape.ScheduleAdded},
+		{removed, scrape.ScheduleRemoved},
+	} {
+		for _, l := range changes.lessons {
+			m := msgtypes.Message{
+				Code:         msgtypes.Schedule,
+				Username:     g.Username,
+				Student:      g.Student,
+				School:       g.School,
+				URL:          g.URL,
+				Subject:      g.Subject,
+				Descriptions: []string{scrape.ScheduleLesson, scrape.ScheduleChange},
+				Fields:       []string{l, changes.change},
+			}
+
+			logger.Info().Msgf("New alert for: %v/%v: %+v", m.Username, m.Subject, m)
+			gradesMsg <- m
+		}
+	}
+
+	return nil
+}
+
+// setChanges compares a set with the one stored in the previous run under the sub-bucket, returning added and removed
+// elements and storing the current set for the next run (unless in dry-run). Nothing is reported in the initial run.
+func setChanges(eDB *db.Edb, username, subBucket string, current []string) ([]string, []string, error) {
+	previous, found, err := eDB.GetSet(username, subBucket)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !*dryRun {
+		if err := eDB.PutSet(username, subBucket, current); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// nothing to compare with in the initial run
+	if !found || !eDB.Existing() {
+		return nil, nil, nil
+	}
+
+	added, removed := db.DiffSets(previous, current)
+
+	return added, removed, nil
 }
 
 // spinner shows a spiffy terminal spinner while waiting endlessly.
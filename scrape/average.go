@@ -0,0 +1,81 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package scrape
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	GradeDescription = "Ocjena" // grade value field description
+	MinGrade         = 1        // lowest numeric grade
+	MaxGrade         = 5        // highest numeric grade
+)
+
+// gradeColumn returns index of the grade value column, or -1 if there is no such column.
+func gradeColumn(descriptions []string) int {
+	for i, d := range descriptions {
+		if strings.EqualFold(d, GradeDescription) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// subjectAverage computes arithmetic mean of all numeric grades in the grade value column of subject grade rows,
+// skipping empty and non-numeric cells and returning zero if there are no numeric grades.
+func subjectAverage(descriptions []string, rows [][]string) float64 {
+	col := gradeColumn(descriptions)
+	if col < 0 {
+		return 0
+	}
+
+	var sum, count int
+
+	for _, r := range rows {
+		if col >= len(r) {
+			continue
+		}
+
+		// grade cell might contain additional text after the numeric grade
+		f := strings.Fields(r[col])
+		if len(f) == 0 {
+			continue
+		}
+
+		g, err := strconv.Atoi(f[0])
+		if err != nil || g < MinGrade || g > MaxGrade {
+			continue
+		}
+
+		sum += g
+		count++
+	}
+
+	if count == 0 {
+		return 0
+	}
+
+	return float64(sum) / float64(count)
+}
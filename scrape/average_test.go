@@ -0,0 +1,56 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package scrape
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSubjectAverage(t *testing.T) {
+	descriptions := []string{"Datum", "Bilješka", "Ocjena"}
+
+	tests := []struct {
+		name string
+		rows [][]string
+		want float64
+	}{
+		{"no rows", nil, 0},
+		{"single grade", [][]string{{"2.1.", "Usmeno", "5"}}, 5},
+		{"mixed grades", [][]string{{"2.1.", "", "5"}, {"3.1.", "", "4"}, {"4.1.", "", "4"}}, 13.0 / 3},
+		{"skips non-numeric and empty", [][]string{{"2.1.", "", "5"}, {"3.1.", "", ""}, {"4.1.", "", "+"}, {"5.1.", ""}}, 5},
+		{"grade with remark", [][]string{{"2.1.", "", "3 (ispravak)"}, {"3.1.", "", "4"}}, 3.5},
+		{"out of range", [][]string{{"2.1.", "", "0"}, {"3.1.", "", "7"}}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := subjectAverage(descriptions, tt.rows); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("subjectAverage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := subjectAverage([]string{"Datum", "Bilješka"}, [][]string{{"2.1.", "5"}}); got != 0 {
+		t.Errorf("subjectAverage() without grade column = %v, want 0", got)
+	}
+}
@@ -0,0 +1,58 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package scrape
+
+import (
+	"context"
+	"time"
+)
+
+// deadline cancels a scrape once its time budget has been used up, where the budget is extended as the number of
+// needed requests becomes known.
+type deadline struct {
+	timer *time.Timer
+	at    time.Time
+}
+
+// withDeadline returns a context cancelled with context.DeadlineExceeded as the cause once the time budget has been
+// used up, along with the deadline to extend the budget and a function releasing its resources.
+func withDeadline(ctx context.Context, budget time.Duration) (context.Context, *deadline, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	d := &deadline{at: time.Now().Add(budget)}
+	d.timer = time.AfterFunc(budget, func() { cancel(context.DeadlineExceeded) })
+
+	return ctx, d, func() {
+		d.timer.Stop()
+		cancel(context.Canceled)
+	}
+}
+
+// extend extends the time budget, unless it has already been used up.
+func (d *deadline) extend(budget time.Duration) {
+	if !d.timer.Stop() {
+		return
+	}
+
+	d.at = d.at.Add(budget)
+	d.timer.Reset(time.Until(d.at))
+}
@@ -0,0 +1,56 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package scrape
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDeadline(t *testing.T) {
+	ctx, d, stop := withDeadline(context.Background(), 50*time.Millisecond)
+	defer stop()
+
+	d.extend(100 * time.Millisecond)
+
+	// extended budget has not been used up yet
+	time.Sleep(80 * time.Millisecond)
+
+	if err := ctx.Err(); err != nil {
+		t.Fatalf("context done before the extended deadline: %v", err)
+	}
+
+	<-ctx.Done()
+
+	if cause := context.Cause(ctx); !errors.Is(cause, context.DeadlineExceeded) {
+		t.Errorf("context.Cause() = %v, want %v", cause, context.DeadlineExceeded)
+	}
+
+	// used up budget is never extended
+	d.extend(time.Hour)
+
+	if ctx.Err() == nil {
+		t.Error("context not done after extending a used up budget")
+	}
+}
@@ -0,0 +1,78 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package scrape
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/dkorunic/e-dnevnik-bot/fetch"
+)
+
+// ErrorClass is a class of scrape failure.
+type ErrorClass int
+
+const (
+	ParseError   ErrorClass = iota // unexpected page contents, likely a site change
+	AuthError                      // rejected credentials
+	NetworkError                   // network, timeout or server-side failure, likely transient
+)
+
+// String returns a lowercase name of the error class.
+func (c ErrorClass) String() string {
+	switch c {
+	case AuthError:
+		return "auth"
+	case NetworkError:
+		return "network"
+	default:
+		return "parse"
+	}
+}
+
+// Transient reports if failures of the error class are likely to go away on their own.
+func (c ErrorClass) Transient() bool {
+	return c == NetworkError
+}
+
+// ErrParse is returned when a fetched e-dnevnik page could not be parsed.
+var ErrParse = errors.New("unable to parse e-dnevnik page")
+
+// Classify returns the class of a scrape error, where errors not known to be caused by credentials or network are
+// treated as parse errors.
+func Classify(err error) ErrorClass {
+	var netErr net.Error
+
+	switch {
+	case errors.Is(err, fetch.ErrLogin):
+		return AuthError
+	case errors.Is(err, ErrParse), errors.Is(err, fetch.ErrCSRF):
+		return ParseError
+	case errors.Is(err, fetch.ErrSiteUnavailable), errors.Is(err, fetch.ErrSessionExpired),
+		errors.Is(err, fetch.ErrNilBody), errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, context.Canceled), errors.As(err, &netErr):
+		return NetworkError
+	default:
+		return ParseError
+	}
+}
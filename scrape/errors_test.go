@@ -0,0 +1,60 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package scrape
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/avast/retry-go/v4"
+	"github.com/dkorunic/e-dnevnik-bot/fetch"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ErrorClass
+	}{
+		{fmt.Errorf("%w: Neispravni podaci", fetch.ErrLogin), AuthError},
+		{retry.Error{fetch.ErrLogin}, AuthError},
+		{&url.Error{Op: "Get", URL: "https://ocjene.skole.hr", Err: errors.New("connection refused")}, NetworkError},
+		{retry.Error{context.DeadlineExceeded}, NetworkError},
+		{fmt.Errorf("%w: %w: 503", fetch.ErrSiteUnavailable, fetch.ErrUnexpectedStatus), NetworkError},
+		{fmt.Errorf("%w: 403", fetch.ErrUnexpectedStatus), ParseError},
+		{fmt.Errorf("%w: %w", ErrParse, errors.New("EOF")), ParseError},
+		{fetch.ErrCSRF, ParseError},
+		{errors.New("unable to parse"), ParseError},
+	}
+
+	for _, tt := range tests {
+		if got := Classify(tt.err); got != tt.want {
+			t.Errorf("Classify(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+
+	if !NetworkError.Transient() || AuthError.Transient() || ParseError.Transient() {
+		t.Error("only network errors should be transient")
+	}
+}
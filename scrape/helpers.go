@@ -23,6 +23,7 @@ package scrape
 
 import (
 	"strings"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/dkorunic/e-dnevnik-bot/fetch"
@@ -31,51 +32,134 @@ import (
 )
 
 const (
-	TimeFormat       = "02.01.2006."  // DD.MM.YYYY. format
-	DateDescription  = "Datum ispita" // exam date field description
-	EventSummary     = "Predmet"      // exam summary field description (typically a subject name)
-	EventDescription = "Napomena"     // exam remark field description (typically a target of the exam)
+	TimeFormat       = "02.01.2006."    // DD.MM.YYYY. format
+	DateFormat       = "2.1.2006."      // D.M.YYYY. format (absences, notes)
+	DateFormatNoYear = "2.1."           // D.M. format (grades)
+	DateDescription  = "Datum ispita"   // exam date field description
+	EventSummary     = "Predmet"        // exam summary field description (typically a subject name)
+	EventDescription = "Napomena"       // exam remark field description (typically a target of the exam)
+	AbsenceDate      = "Datum"          // absence date field description
+	AbsenceStatus    = "Status"         // absence status field description (excused, unexcused, pending)
+	AbsencePeriod    = "Sat"            // absence school period field description
+	AbsenceCells     = 4                // absence row cells: date, period, subject and status
+	EnrollmentChange = "Promjena"       // enrollment change field description
+	EnrollmentAdded  = "Upisan razred"  // enrollment change value for an added class
+	EnrollmentRemove = "Ispisan razred" // enrollment change value for a removed class
+	NoteSubject      = "Bilješke"       // teacher notes subject
+	NoteDate         = "Datum"          // note date field description
+	NoteText         = "Bilješka"       // note text field description
+	NoteCells        = 2                // note row cells: date and note text
+	ScheduleSubject  = "Raspored sati"  // class timetable subject
+	ScheduleLesson   = "Sat"            // class timetable lesson (day, period and subject) field description
+	ScheduleChange   = "Promjena"       // class timetable change field description
+	ScheduleAdded    = "dodan"          // class timetable change value for an added lesson
+	ScheduleRemoved  = "uklonjen"       // class timetable change value for a removed lesson
+	ScheduleCells    = 3                // class timetable row cells: day, period and subject
+	NationalDate     = "Datum"          // national exam date field description
+	NationalResult   = "Rezultat"       // national exam result field description
+	NationalCells    = 3                // national exam row cells: subject, date and result
 )
 
+// Selectors of page sections, where the first one matches the current site layout and the following ones are
+// fallbacks for known layout variants.
+var (
+	gradeTableSelectors = []string{
+		"div.content > div.flex-table.new-grades-table",
+		"div.flex-table.new-grades-table",
+		"div.flex-table.grades-table",
+	}
+	gradeHeaderSelectors = []string{"div.row.header div.cell > span", "div.row.header div.cell"}
+	gradeCellSelectors   = []string{"div.cell > span", "div.cell"}
+	absenceRowSelectors  = []string{
+		"div.content > div.flex-table.absent-table > div.row:not(.header)",
+		"div.flex-table.absent-table > div.row:not(.header)",
+	}
+	noteRowSelectors = []string{
+		"div.content > div.flex-table.notes-table > div.row:not(.header)",
+		"div.flex-table.notes-table > div.row:not(.header)",
+	}
+	scheduleRowSelectors = []string{
+		"div.content > div.flex-table.schedule-table > div.row:not(.header)",
+		"div.flex-table.schedule-table > div.row:not(.header)",
+	}
+	nationalRowSelectors = []string{
+		"div.content > div.flex-table.national-exams-table > div.row:not(.header)",
+		"div.flex-table.national-exams-table > div.row:not(.header)",
+		"div.content > div.flex-table > div.row:not(.header)",
+	}
+)
+
+// findSection returns elements matching the first of the selectors that matches anything, logging at debug level
+// if only a fallback selector matched or if the section is missing, so that site layout changes surface instead of
+// silently producing no results.
+func findSection(s *goquery.Selection, section, username string, selectors []string) *goquery.Selection {
+	for i, sel := range selectors {
+		found := s.Find(sel)
+		if found.Length() == 0 {
+			continue
+		}
+
+		if i > 0 {
+			logger.Debug().Msgf("Found %v for user %v with fallback selector %q, site layout may have changed",
+				section, username, sel)
+		}
+
+		return found
+	}
+
+	logger.Debug().Msgf("Missing %v for user %v, none of the selectors %q matched", section, username, selectors)
+
+	return s.Find(selectors[0])
+}
+
 // parseGrades extracts grades per subject from raw string (grade scrape response body) and grade descriptions,
-// constructs grade messages and sends them a message channel, optionally returning an error.
-func parseGrades(ch chan<- msgtypes.Message, username, rawGrades string, multiClass bool, className string) error {
+// constructs grade messages and sends them a message channel, optionally returning an error. Grades dated before the
+// cutoff (if set) are skipped.
+func parseGrades(ch chan<- msgtypes.Message, username, rawGrades string, multiClass bool, c fetch.Class,
+	cutoff time.Time,
+) error {
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(rawGrades))
 	if err != nil {
 		return err
 	}
 
-	var parsedGrades int
+	var parsedGrades, skippedGrades int
+
+	now := time.Now()
 
 	// each subject has a div with class "flex-table new-grades-table"
-	doc.Find("div.content > div.flex-table.new-grades-table").
+	findSection(doc.Selection, "grade tables", username, gradeTableSelectors).
 		Each(func(_ int, table *goquery.Selection) {
 			// subject name is in data-action-id attribute
 			subject, subjectOK := table.Attr("data-action-id")
 			if !subjectOK {
+				logger.Debug().Msgf("Skipping grade table without a subject name for user %v", username)
+
 				return
 			}
 
 			// if multiclass, append class name to subject
 			if multiClass {
-				subject = strings.Join([]string{subject, className}, " / ")
+				subject = strings.Join([]string{subject, c.Name}, " / ")
 			}
 
 			var descriptions []string
 			// row descriptions are in div with class "row header" in each div with class "cell" in a span
-			table.Find("div.row.header div.cell > span").
+			findSection(table, "grade descriptions of "+subject, username, gradeHeaderSelectors).
 				Each(func(_ int, column *goquery.Selection) {
 					txt := strings.TrimSpace(column.Text())
 					descriptions = append(descriptions, txt)
 				})
 
+			var rows [][]string
+
 			// grades are in each div with class "row" (header rows excluded) ...
 			table.Find("div.row:not(.header)").
 				Each(func(_ int, row *goquery.Selection) {
 					var spans []string
 
 					// ... and in each div with class "cell" in a span
-					row.Find("div.cell > span").
+					findSection(row, "grade cells of "+subject, username, gradeCellSelectors).
 						Each(func(_ int, column *goquery.Selection) {
 							// clean excess whitespace and newlines
 							txt := strings.TrimSpace(column.Text())
@@ -87,20 +171,133 @@ func parseGrades(ch chan<- msgtypes.Message, username, rawGrades string, multiCl
 							spans = append(spans, txt)
 						})
 
-					// once we have a single grade with all required fields, send it through the channel
-					ch <- msgtypes.Message{
-						Username:     username,
-						Subject:      subject,
-						Descriptions: descriptions,
-						Fields:       spans,
+					rows = append(rows, spans)
+				})
+
+			if len(rows) == 0 {
+				logger.Debug().Msgf("Missing grade rows of %v for user %v", subject, username)
+			}
+
+			average := subjectAverage(descriptions, rows)
+
+			// once we have all grades of a subject with all required fields, send them through the channel
+			for _, spans := range rows {
+				// skip grades older than cutoff, keeping grades with an unknown date
+				if !cutoff.IsZero() && len(spans) > 0 {
+					if t, err := EventDate(spans[0], now); err == nil && t.Before(cutoff) {
+						skippedGrades++
+
+						continue
 					}
+				}
+
+				ch <- msgtypes.Message{
+					Username:     username,
+					Student:      c.Student,
+					School:       c.School,
+					URL:          fetch.ClassLink(c.ID),
+					Subject:      subject,
+					Descriptions: descriptions,
+					Fields:       spans,
+					Average:      average,
+				}
+
+				parsedGrades++
+			}
+		})
+
+	if parsedGrades == 0 && skippedGrades == 0 {
+		logger.Info().Msgf("No grades found in the scraped content for user %v", username)
+	}
+
+	if skippedGrades > 0 {
+		logger.Debug().Msgf("Skipped %v grades older than %v for user %v", skippedGrades, cutoff.Format(TimeFormat),
+			username)
+	}
+
+	return nil
+}
+
+// EventDate parses a full date (absences, notes) or a date without a year (grades), assuming the current or previous
+// year in the latter case.
+func EventDate(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(DateFormat, s); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(DateFormatNoYear, s)
+	if err != nil {
+		return t, err
+	}
+
+	// assume current or previous year
+	if t.Month() > now.Month() {
+		return t.AddDate(now.Year()-1, 0, 0), nil
+	}
+
+	return t.AddDate(now.Year(), 0, 0), nil
+}
+
+// parseAbsences extracts absences from raw string (absences scrape response body), constructs absence messages and
+// sends them a message channel, optionally returning an error.
+func parseAbsences(ch chan<- msgtypes.Message, username, rawAbsences string, multiClass bool, c fetch.Class) error {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(rawAbsences))
+	if err != nil {
+		return err
+	}
 
-					parsedGrades++
+	var parsedAbsences int
+
+	// each absence is a div with class "row" (header rows excluded) in a div with class "flex-table absent-table"
+	findSection(doc.Selection, "absences", username, absenceRowSelectors).
+		Each(func(_ int, row *goquery.Selection) {
+			var spans []string
+
+			// ... and in each div with class "cell" in a span
+			row.Find("div.cell > span").
+				Each(func(_ int, column *goquery.Selection) {
+					// clean excess whitespace and newlines
+					txt := strings.Join(strings.Fields(column.Text()), " ")
+					spans = append(spans, txt)
 				})
+
+			// expecting date, school period, subject and status
+			if len(spans) < AbsenceCells {
+				return
+			}
+
+			date, period, subject, status := spans[0], spans[1], spans[2], spans[3]
+
+			// if multiclass, append class name to subject
+			if multiClass {
+				subject = strings.Join([]string{subject, c.Name}, " / ")
+			}
+
+			// send each absence through channel
+			ch <- msgtypes.Message{
+				Code:     msgtypes.Absence,
+				Username: username,
+				Student:  c.Student,
+				School:   c.School,
+				URL:      fetch.ClassLink(c.ID),
+				Subject:  subject,
+				Descriptions: []string{
+					AbsenceDate,
+					AbsenceStatus,
+					AbsencePeriod,
+				},
+				Fields: []string{
+					date,
+					status,
+					period,
+				},
+			}
+
+			parsedAbsences++
 		})
 
-	if parsedGrades == 0 {
-		logger.Info().Msgf("No grades found in the scraped content for user %v", username)
+	if parsedAbsences == 0 {
+		logger.Info().Msgf("No absences found in the scraped content for user %v", username)
 	}
 
 	return nil
@@ -115,11 +312,199 @@ func cleanEventDescription(summary string) string {
 	return summary
 }
 
+// parseNotes extracts teacher notes from raw string (notes scrape response body), constructs note messages and sends
+// them a message channel, optionally returning an error. Notes are deduplicated on date and text, so edited notes get
+// alerted on again.
+func parseNotes(ch chan<- msgtypes.Message, username, rawNotes string, multiClass bool, c fetch.Class) error {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(rawNotes))
+	if err != nil {
+		return err
+	}
+
+	subject := NoteSubject
+
+	// if multiclass, append class name to subject
+	if multiClass {
+		subject = strings.Join([]string{subject, c.Name}, " / ")
+	}
+
+	var parsedNotes int
+
+	// each note is a div with class "row" (header rows excluded) in a div with class "flex-table notes-table"
+	findSection(doc.Selection, "notes", username, noteRowSelectors).
+		Each(func(_ int, row *goquery.Selection) {
+			var spans []string
+
+			// ... and in each div with class "cell" in a span
+			row.Find("div.cell > span").
+				Each(func(_ int, column *goquery.Selection) {
+					// clean excess whitespace and newlines
+					txt := strings.Join(strings.Fields(column.Text()), " ")
+					spans = append(spans, txt)
+				})
+
+			// expecting date and note text
+			if len(spans) < NoteCells || spans[1] == "" {
+				return
+			}
+
+			// send each note through channel
+			ch <- msgtypes.Message{
+				Code:     msgtypes.Note,
+				Username: username,
+				Student:  c.Student,
+				School:   c.School,
+				URL:      fetch.ClassLink(c.ID),
+				Subject:  subject,
+				Descriptions: []string{
+					NoteDate,
+					NoteText,
+				},
+				Fields: []string{
+					spans[0],
+					spans[1],
+				},
+			}
+
+			parsedNotes++
+		})
+
+	if parsedNotes == 0 {
+		logger.Debug().Msgf("No notes found in the scraped content for user %v", username)
+	}
+
+	return nil
+}
+
+// parseNationalExams extracts national exam results from raw string (national exams scrape response body), constructs
+// national exam messages and sends them a message channel, optionally returning an error. Exams without a result yet
+// are skipped.
+func parseNationalExams(ch chan<- msgtypes.Message, username, rawNational string, multiClass bool, c fetch.Class) error {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(rawNational))
+	if err != nil {
+		return err
+	}
+
+	var parsedExams int
+
+	// each national exam is a div with class "row" (header rows excluded) in a div with class
+	// "flex-table national-exams-table"
+	findSection(doc.Selection, "national exams", username, nationalRowSelectors).
+		Each(func(_ int, row *goquery.Selection) {
+			var spans []string
+
+			// ... and in each div with class "cell" in a span
+			row.Find("div.cell > span").
+				Each(func(_ int, column *goquery.Selection) {
+					// clean excess whitespace and newlines
+					txt := strings.Join(strings.Fields(column.Text()), " ")
+					spans = append(spans, txt)
+				})
+
+			// expecting subject, date and result
+			if len(spans) < NationalCells || spans[2] == "" {
+				return
+			}
+
+			subject, date, result := spans[0], spans[1], spans[2]
+
+			// if multiclass, append class name to subject
+			if multiClass {
+				subject = strings.Join([]string{subject, c.Name}, " / ")
+			}
+
+			// send each national exam result through channel
+			ch <- msgtypes.Message{
+				Code:     msgtypes.NationalExam,
+				Username: username,
+				Student:  c.Student,
+				School:   c.School,
+				URL:      fetch.ClassLink(c.ID),
+				Subject:  subject,
+				Descriptions: []string{
+					NationalDate,
+					NationalResult,
+				},
+				Fields: []string{
+					date,
+					result,
+				},
+			}
+
+			parsedExams++
+		})
+
+	if parsedExams == 0 {
+		logger.Debug().Msgf("No national exam results found in the scraped content for user %v", username)
+	}
+
+	return nil
+}
+
+// parseSchedule extracts weekly class timetable from raw string (schedule scrape response body) and sends a single
+// message listing all lessons (day, period and subject) through a message channel for timetable change tracking,
+// optionally returning an error.
+func parseSchedule(ch chan<- msgtypes.Message, username, rawSchedule string, multiClass bool, c fetch.Class) error {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(rawSchedule))
+	if err != nil {
+		return err
+	}
+
+	subject := ScheduleSubject
+
+	// if multiclass, append class name to subject
+	if multiClass {
+		subject = strings.Join([]string{subject, c.Name}, " / ")
+	}
+
+	var lessons []string
+
+	// each lesson is a div with class "row" (header rows excluded) in a div with class "flex-table schedule-table"
+	findSection(doc.Selection, "timetable", username, scheduleRowSelectors).
+		Each(func(_ int, row *goquery.Selection) {
+			var spans []string
+
+			// ... and in each div with class "cell" in a span
+			row.Find("div.cell > span").
+				Each(func(_ int, column *goquery.Selection) {
+					// clean excess whitespace and newlines
+					txt := strings.Join(strings.Fields(column.Text()), " ")
+					spans = append(spans, txt)
+				})
+
+			// expecting day, period and subject, skipping free periods
+			if len(spans) < ScheduleCells || spans[2] == "" {
+				return
+			}
+
+			lessons = append(lessons, strings.Join(spans[:ScheduleCells], ", "))
+		})
+
+	// an empty timetable is most likely a parsing issue, so never report it as all lessons being removed
+	if len(lessons) == 0 {
+		logger.Debug().Msgf("No class timetable found in the scraped content for user %v", username)
+
+		return nil
+	}
+
+	ch <- msgtypes.Message{
+		Code:     msgtypes.Schedule,
+		Username: username,
+		Student:  c.Student,
+		School:   c.School,
+		URL:      fetch.ClassLink(c.ID),
+		Subject:  subject,
+		Fields:   lessons,
+	}
+
+	return nil
+}
+
 // parseEvents processes Events array, emitting a single exam message for each event, optionally returning an
 // error.
 //
 //nolint:unparam
-func parseEvents(ch chan<- msgtypes.Message, username string, events fetch.Events, multiClass bool, className string) error {
+func parseEvents(ch chan<- msgtypes.Message, username string, events fetch.Events, multiClass bool, c fetch.Class) error {
 	if len(events) == 0 {
 		logger.Info().Msgf("No scheduled exams for user %v", username)
 	}
@@ -131,13 +516,16 @@ func parseEvents(ch chan<- msgtypes.Message, username string, events fetch.Event
 
 		// if multiclass, append class name to subject
 		if multiClass {
-			subject = strings.Join([]string{subject, className}, " / ")
+			subject = strings.Join([]string{subject, c.Name}, " / ")
 		}
 
 		// send each event through channel
 		ch <- msgtypes.Message{
-			IsExam:   true,
+			Code:     msgtypes.Exam,
 			Username: username,
+			Student:  c.Student,
+			School:   c.School,
+			URL:      fetch.ClassLink(c.ID),
 			Subject:  subject,
 			Descriptions: []string{
 				EventSummary,
@@ -157,7 +545,7 @@ func parseEvents(ch chan<- msgtypes.Message, username string, events fetch.Event
 }
 
 // parseClasses extracts active classes from raw string (classes scrape response body) and constructs Classes structure
-// with class ID, name, school name and year of enlistment.
+// with class ID, name, school name, year of enlistment and student full name.
 func parseClasses(username, rawClasses string) (fetch.Classes, error) {
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(rawClasses))
 	if err != nil {
@@ -171,6 +559,9 @@ func parseClasses(username, rawClasses string) (fetch.Classes, error) {
 	// fetch all active classes
 	doc.Find("div.student-list > div.classes").
 		Each(func(_ int, row *goquery.Selection) {
+			// student full name is listed above the classes
+			student := strings.Join(strings.Fields(row.Parent().Find("div.student-info span.bold").First().Text()), " ")
+
 			// div active classes are class-menu-vertical and not past-schoolyear
 			row.Find("div.class-menu-vertical:not(div.past-schoolyear) > div.class-info").
 				Each(func(_ int, column *goquery.Selection) {
@@ -202,6 +593,8 @@ func parseClasses(username, rawClasses string) (fetch.Classes, error) {
 							c.School = strings.TrimSpace(span.Text())
 						})
 
+					c.Student = student
+
 					classes = append(classes, c)
 					parsedClasses++
 				})
@@ -213,3 +606,26 @@ func parseClasses(username, rawClasses string) (fetch.Classes, error) {
 
 	return classes, nil
 }
+
+// classesMessage constructs a message listing all active classes (name, school year and school) for enrollment change
+// tracking.
+func classesMessage(username string, classes fetch.Classes) msgtypes.Message {
+	var student string
+
+	fields := make([]string, 0, len(classes))
+	for _, c := range classes {
+		fields = append(fields, strings.Join([]string{c.Name, c.Year, c.School}, ", "))
+
+		if student == "" {
+			student = c.Student
+		}
+	}
+
+	return msgtypes.Message{
+		Code:     msgtypes.EnrollmentChange,
+		Username: username,
+		Student:  student,
+		URL:      fetch.ClassURL,
+		Fields:   fields,
+	}
+}
@@ -0,0 +1,291 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package scrape
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dkorunic/e-dnevnik-bot/fetch"
+	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+)
+
+func TestParseGradesCutoff(t *testing.T) {
+	raw := `<html><body><div class="content"><div class="flex-table new-grades-table" data-action-id="Matematika">
+<div class="row header"><div class="cell"><span>Datum</span></div><div class="cell"><span>Ocjena</span></div></div>
+<div class="row"><div class="cell"><span>2.1.</span></div><div class="cell"><span>5</span></div></div>
+<div class="row"><div class="cell"><span>20.5.</span></div><div class="cell"><span>4</span></div></div>
+<div class="row"><div class="cell"><span></span></div><div class="cell"><span>3</span></div></div>
+</div></div></body></html>`
+
+	now := time.Now()
+	cutoff := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	ch := make(chan msgtypes.Message, 10)
+
+	// grade dated today is kept, grade dated a day before cutoff is skipped and grade without a date is kept
+	today := cutoff.Format("2.1.")
+	old := cutoff.AddDate(0, 0, -1).Format("2.1.")
+	raw = strings.Replace(strings.Replace(raw, "2.1.", today, 1), "20.5.", old, 1)
+
+	if err := parseGrades(ch, "korisnik@skole.hr", raw, false, fetch.Class{}, cutoff); err != nil {
+		t.Fatalf("parseGrades() = %v", err)
+	}
+
+	close(ch)
+
+	var grades []string
+	for m := range ch {
+		grades = append(grades, m.Fields[1])
+	}
+
+	if len(grades) != 2 || grades[0] != "5" || grades[1] != "3" {
+		t.Errorf("parseGrades() sent grades %q, want [5 3]", grades)
+	}
+}
+
+func TestParseGradesLayouts(t *testing.T) {
+	layouts := map[string]string{
+		"current": `<html><body><div class="content"><div class="flex-table new-grades-table" data-action-id="Fizika">
+<div class="row header"><div class="cell"><span>Datum</span></div><div class="cell"><span>Ocjena</span></div></div>
+<div class="row"><div class="cell"><span>3.2.</span></div><div class="cell"><span>4</span></div></div>
+</div></div></body></html>`,
+		"without content wrapper": `<html><body><main><div class="flex-table new-grades-table" data-action-id="Fizika">
+<div class="row header"><div class="cell"><span>Datum</span></div><div class="cell"><span>Ocjena</span></div></div>
+<div class="row"><div class="cell"><span>3.2.</span></div><div class="cell"><span>4</span></div></div>
+</div></main></body></html>`,
+		"old table class without spans": `<html><body><div class="content"><div class="flex-table grades-table" data-action-id="Fizika">
+<div class="row header"><div class="cell">Datum</div><div class="cell">Ocjena</div></div>
+<div class="row"><div class="cell">3.2.</div><div class="cell"> 4
+</div></div>
+</div></div></body></html>`,
+	}
+
+	for name, raw := range layouts {
+		ch := make(chan msgtypes.Message, 10)
+
+		if err := parseGrades(ch, "korisnik@skole.hr", raw, false, fetch.Class{}, time.Time{}); err != nil {
+			t.Fatalf("parseGrades() %v layout = %v", name, err)
+		}
+
+		close(ch)
+
+		var msgs []msgtypes.Message
+		for m := range ch {
+			msgs = append(msgs, m)
+		}
+
+		if len(msgs) != 1 || msgs[0].Subject != "Fizika" || strings.Join(msgs[0].Descriptions, ",") != "Datum,Ocjena" ||
+			strings.Join(msgs[0].Fields, ",") != "3.2.,4" {
+			t.Errorf("parseGrades() %v layout sent %+v", name, msgs)
+		}
+	}
+}
+
+func TestEventDate(t *testing.T) {
+	now := time.Date(2025, time.March, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		s    string
+		want time.Time
+	}{
+		{"12.1.2025.", time.Date(2025, time.January, 12, 0, 0, 0, 0, time.UTC)},
+		{"2.3.", time.Date(2025, time.March, 2, 0, 0, 0, 0, time.UTC)},
+		{"20.11.", time.Date(2024, time.November, 20, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		got, err := EventDate(tt.s, now)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("EventDate(%q) = %v, %v, want %v", tt.s, got, err, tt.want)
+		}
+	}
+
+	if _, err := EventDate("", now); err == nil {
+		t.Error("EventDate() with an empty date did not fail")
+	}
+}
+
+func TestParseNotes(t *testing.T) {
+	raw := `<html><body><div class="content"><div class="flex-table notes-table">
+<div class="row header"><div class="cell"><span>Datum</span></div><div class="cell"><span>Bilješka</span></div></div>
+<div class="row"><div class="cell"><span>12.01.2025.</span></div><div class="cell"><span>Zaboravio
+   domaću zadaću</span></div></div>
+<div class="row"><div class="cell"><span>13.01.2025.</span></div><div class="cell"><span></span></div></div>
+</div></div></body></html>`
+
+	ch := make(chan msgtypes.Message, 10)
+
+	if err := parseNotes(ch, "korisnik@skole.hr", raw, true, fetch.Class{Name: "8.a"}); err != nil {
+		t.Fatalf("parseNotes() = %v", err)
+	}
+
+	close(ch)
+
+	var msgs []msgtypes.Message
+	for m := range ch {
+		msgs = append(msgs, m)
+	}
+
+	if len(msgs) != 1 {
+		t.Fatalf("parseNotes() sent %d messages, want 1", len(msgs))
+	}
+
+	m := msgs[0]
+	if m.Code != msgtypes.Note || m.Subject != "Bilješke / 8.a" {
+		t.Errorf("unexpected note message: %+v", m)
+	}
+
+	if len(m.Fields) != 2 || m.Fields[0] != "12.01.2025." || m.Fields[1] != "Zaboravio domaću zadaću" {
+		t.Errorf("unexpected note fields: %q", m.Fields)
+	}
+}
+
+func TestParseNationalExams(t *testing.T) {
+	raw := `<html><body><div class="content"><div class="flex-table national-exams-table">
+<div class="row header"><div class="cell"><span>Predmet</span></div><div class="cell"><span>Datum</span></div>
+<div class="cell"><span>Rezultat</span></div></div>
+<div class="row"><div class="cell"><span>Matematika</span></div><div class="cell"><span>18.03.2025.</span></div>
+<div class="cell"><span>  78,5 %
+  </span></div></div>
+<div class="row"><div class="cell"><span>Hrvatski jezik</span></div><div class="cell"><span>20.03.2025.</span></div>
+<div class="cell"><span></span></div></div>
+</div></div></body></html>`
+
+	ch := make(chan msgtypes.Message, 10)
+
+	if err := parseNationalExams(ch, "korisnik@skole.hr", raw, false, fetch.Class{Name: "8.a"}); err != nil {
+		t.Fatalf("parseNationalExams() = %v", err)
+	}
+
+	close(ch)
+
+	var msgs []msgtypes.Message
+	for m := range ch {
+		msgs = append(msgs, m)
+	}
+
+	if len(msgs) != 1 {
+		t.Fatalf("parseNationalExams() sent %d messages, want 1", len(msgs))
+	}
+
+	m := msgs[0]
+	if m.Code != msgtypes.NationalExam || m.Subject != "Matematika" {
+		t.Errorf("unexpected national exam message: %+v", m)
+	}
+
+	if len(m.Fields) != 2 || m.Fields[0] != "18.03.2025." || m.Fields[1] != "78,5 %" {
+		t.Errorf("unexpected national exam fields: %q", m.Fields)
+	}
+
+	// generic flex table without the section class is still parsed through a fallback selector
+	raw = strings.Replace(raw, "flex-table national-exams-table", "flex-table", 1)
+	ch = make(chan msgtypes.Message, 10)
+
+	if err := parseNationalExams(ch, "korisnik@skole.hr", raw, false, fetch.Class{Name: "8.a"}); err != nil {
+		t.Fatalf("parseNationalExams() fallback layout = %v", err)
+	}
+
+	close(ch)
+
+	if n := len(ch); n != 1 {
+		t.Errorf("parseNationalExams() fallback layout sent %d messages, want 1", n)
+	}
+}
+
+func TestParseSchedule(t *testing.T) {
+	raw := `<html><body><div class="content"><div class="flex-table schedule-table">
+<div class="row header"><div class="cell"><span>Dan</span></div><div class="cell"><span>Sat</span></div><div class="cell"><span>Predmet</span></div></div>
+<div class="row"><div class="cell"><span>Ponedjeljak</span></div><div class="cell"><span>1.</span></div><div class="cell"><span>Matematika</span></div></div>
+<div class="row"><div class="cell"><span>Ponedjeljak</span></div><div class="cell"><span>2.</span></div><div class="cell"><span></span></div></div>
+<div class="row"><div class="cell"><span>Utorak</span></div><div class="cell"><span>1.</span></div><div class="cell"><span>Hrvatski
+   jezik</span></div></div>
+</div></div></body></html>`
+
+	ch := make(chan msgtypes.Message, 10)
+
+	if err := parseSchedule(ch, "korisnik@skole.hr", raw, false, fetch.Class{Name: "8.a"}); err != nil {
+		t.Fatalf("parseSchedule() = %v", err)
+	}
+
+	if err := parseSchedule(ch, "korisnik@skole.hr", "<html></html>", false, fetch.Class{Name: "8.a"}); err != nil {
+		t.Fatalf("parseSchedule() of an empty page = %v", err)
+	}
+
+	close(ch)
+
+	var msgs []msgtypes.Message
+	for m := range ch {
+		msgs = append(msgs, m)
+	}
+
+	if len(msgs) != 1 {
+		t.Fatalf("parseSchedule() sent %d messages, want 1", len(msgs))
+	}
+
+	m := msgs[0]
+	if m.Code != msgtypes.Schedule || m.Subject != ScheduleSubject {
+		t.Errorf("unexpected schedule message: %+v", m)
+	}
+
+	want := []string{"Ponedjeljak, 1., Matematika", "Utorak, 1., Hrvatski jezik"}
+	if len(m.Fields) != len(want) || m.Fields[0] != want[0] || m.Fields[1] != want[1] {
+		t.Errorf("unexpected schedule lessons: %q, want %q", m.Fields, want)
+	}
+}
+
+func TestParseClasses(t *testing.T) {
+	raw := `<html><body><div class="student-list">
+<div class="student-info"><span class="bold">Ana
+   Anić</span></div>
+<div class="classes">
+<div class="class-menu-vertical"><div class="class-info" data-action-id="123">
+<div class="class"><span class="bold">8.a</span><span class="class-schoolyear">2024./2025.</span></div>
+<div class="school"><div><span class="school-name">Osnovna škola Zagreb</span></div></div>
+</div></div>
+<div class="class-menu-vertical past-schoolyear"><div class="class-info" data-action-id="99">
+<div class="class"><span class="bold">7.a</span><span class="class-schoolyear">2023./2024.</span></div>
+</div></div>
+</div></div></body></html>`
+
+	classes, err := parseClasses("korisnik@skole.hr", raw)
+	if err != nil {
+		t.Fatalf("parseClasses() = %v", err)
+	}
+
+	want := fetch.Classes{{
+		ID:      "123",
+		Name:    "8.a",
+		Year:    "2024./2025.",
+		School:  "Osnovna škola Zagreb",
+		Student: "Ana Anić",
+	}}
+
+	if len(classes) != 1 || classes[0] != want[0] {
+		t.Fatalf("parseClasses() = %+v, want %+v", classes, want)
+	}
+
+	if m := classesMessage("korisnik@skole.hr", classes); m.Student != "Ana Anić" {
+		t.Errorf("classesMessage() student = %q, want %q", m.Student, "Ana Anić")
+	}
+}
@@ -23,46 +23,51 @@ package scrape
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/avast/retry-go/v4"
 	"github.com/dkorunic/e-dnevnik-bot/fetch"
 	"github.com/dkorunic/e-dnevnik-bot/logger"
 	"github.com/dkorunic/e-dnevnik-bot/msgtypes"
+	"github.com/dkorunic/e-dnevnik-bot/tracing"
 	"github.com/reiver/go-cast"
+	"golang.org/x/sync/errgroup"
 )
 
-// GetGradesAndEvents initiates fetching subjects, grades and exam events from remote e-dnevnik site, sends
-// individual messages to a message channel and optionally returning an error.
-func GetGradesAndEvents(ctx context.Context, ch chan<- msgtypes.Message, username, password string, retries uint) error {
+// GetGradesAndEvents initiates fetching subjects, grades and exam events from remote e-dnevnik site (optionally through
+// a proxy and with tuned HTTP client options), sends individual messages to a message channel and optionally
+// returning an error. Multiple active classes are scraped with up to the given concurrency and failed requests are
+// retried with exponential backoff starting with the given delay. Weekly class timetable is scraped only if requested
+// and grades older than the grade cutoff period (if set) are skipped.
+func GetGradesAndEvents(ctx context.Context, ch chan<- msgtypes.Message, username, password string, opts fetch.Options,
+	concurrency int, retries uint, retryDelay time.Duration, schedule bool, gradeCutoff time.Duration,
+) error {
+	ctx, span := tracing.Start(ctx, "scrape", tracing.User(username))
+
 	err := func() error {
 		r64, err := cast.Int64(retries)
 		if err != nil {
 			r64 = 1
 		}
 
-		ctx, stop := context.WithTimeout(ctx, time.Duration(r64)*fetch.Timeout)
+		// time budget of a single request with all its attempts, extended once the number of classes is known
+		perRequest := time.Duration(r64) * opts.RequestTimeout()
+
+		ctx, budget, stop := withDeadline(ctx, perRequest)
 		defer stop()
 
-		client, err := fetch.NewClientWithContext(ctx, username, password)
+		logger.Debug().Msgf("Scraping user %v with up to %v attempts, exponential backoff from %v and up to %v jitter",
+			username, retries, retryDelay, retryDelay)
+
+		client, err := newClient(ctx, username, password, opts, retries, retryDelay)
 		if err != nil {
 			return err
 		}
 
 		defer client.CloseConnections()
 
-		// attempt to login (CSRF, SSO/SAML, etc.)
-		err = retry.Do(
-			func() error {
-				return client.Login()
-			},
-			retry.Attempts(retries),
-			retry.Context(ctx),
-		)
-		if err != nil {
-			return err
-		}
-
 		var rawClasses string
 
 		// fetch classes (multiple classes possible)
@@ -73,8 +78,7 @@ func GetGradesAndEvents(ctx context.Context, ch chan<- msgtypes.Message, usernam
 
 				return err
 			},
-			retry.Attempts(retries),
-			retry.Context(ctx),
+			retryOptions(ctx, retries, retryDelay)...,
 		)
 		if err != nil {
 			return err
@@ -83,7 +87,12 @@ func GetGradesAndEvents(ctx context.Context, ch chan<- msgtypes.Message, usernam
 		// parse active classes
 		classes, err := parseClasses(username, rawClasses)
 		if err != nil {
-			return err
+			return fmt.Errorf("%w: %w", ErrParse, err)
+		}
+
+		// send active classes through channel for enrollment change tracking
+		if len(classes) > 0 {
+			ch <- classesMessage(username, classes)
 		}
 
 		multiClass := len(classes) > 1
@@ -94,48 +103,271 @@ func GetGradesAndEvents(ctx context.Context, ch chan<- msgtypes.Message, usernam
 			logger.Debug().Msgf("Found active class for user %v: %+v", username, classes)
 		}
 
-		// iterate all active classes
-		for _, c := range classes {
-			cID := c.ID
-			cName := c.Name
+		concurrency = max(concurrency, 1)
+		parallel := multiClass && concurrency > 1
 
-			logger.Debug().Msgf("Fetching grades and calendar events for user %v, class %v, class ID %v", username,
-				cName, cID)
+		// every class needs class events, absences and notes requests and optionally a timetable request, along with
+		// its own login if scraped concurrently, while national exam results are fetched once, and classes are scraped
+		// in waves of up to concurrency classes
+		perClass := 3
+		if schedule {
+			perClass++
+		}
 
-			var rawGrades string
+		if parallel {
+			perClass++
+		}
 
-			var events fetch.Events
+		waves := (len(classes) + concurrency - 1) / concurrency
+		budget.extend(time.Duration(waves*perClass+1) * perRequest)
 
-			// fetch subjects/grades/exams
-			err = retry.Do(
-				func() error {
+		g, gCtx := errgroup.WithContext(ctx)
+		g.SetLimit(concurrency)
+
+		// iterate all active classes
+		for i, c := range classes {
+			g.Go(func() error {
+				// active class is tracked server-side per session, so concurrently scraped classes each need their
+				// own logical session, except for the first one using the initial session
+				classClient := client
+
+				if parallel && i > 0 {
 					var err error
-					rawGrades, events, err = client.GetClassEvents(cID)
-
-					return err
-				},
-				retry.Attempts(retries),
-				retry.Context(ctx),
-			)
-			if err != nil {
-				return err
-			}
 
-			// parse all subjects and corresponding grades
-			err = parseGrades(ch, username, rawGrades, multiClass, cName)
-			if err != nil {
-				return err
-			}
+					classClient, err = newClient(gCtx, username, password, opts, retries, retryDelay)
+					if err != nil {
+						return err
+					}
 
-			// parse all exam events
-			err = parseEvents(ch, username, events, multiClass, cName)
-			if err != nil {
-				return err
-			}
+					defer classClient.CloseConnections()
+				}
+
+				// national exam results are not class-specific, so they are scraped only with the first class
+				return scrapeClass(gCtx, ch, classClient, username, c, multiClass, retries, retryDelay, i == 0,
+					schedule, gradeCutoff)
+			})
+		}
+
+		err = g.Wait()
+
+		// report the used up time budget instead of a cancelled request
+		if err != nil && errors.Is(context.Cause(ctx), context.DeadlineExceeded) {
+			return fmt.Errorf("%w: %w", context.DeadlineExceeded, err)
 		}
 
-		return nil
+		return err
 	}()
 
+	tracing.End(span, err)
+
 	return err
 }
+
+// CheckLogin verifies user credentials by logging in to remote e-dnevnik site (optionally through a proxy), returning
+// an error if the login has failed.
+func CheckLogin(ctx context.Context, username, password string, opts fetch.Options, retries uint,
+	retryDelay time.Duration,
+) error {
+	r64, err := cast.Int64(retries)
+	if err != nil {
+		r64 = 1
+	}
+
+	ctx, stop := context.WithTimeout(ctx, time.Duration(r64)*opts.RequestTimeout())
+	defer stop()
+
+	client, err := newClient(ctx, username, password, opts, retries, retryDelay)
+	if err != nil {
+		return err
+	}
+
+	client.CloseConnections()
+
+	return nil
+}
+
+// newClient creates a new e-dnevnik client and attempts to login (CSRF, SSO/SAML, etc.). Rejected credentials are
+// not retried.
+func newClient(ctx context.Context, username, password string, opts fetch.Options, retries uint,
+	retryDelay time.Duration,
+) (*fetch.Client, error) {
+	ctx, span := tracing.Start(ctx, "login", tracing.User(username))
+
+	client, err := fetch.NewClientWithContext(ctx, username, password, opts)
+	if err != nil {
+		tracing.End(span, err)
+
+		return nil, err
+	}
+
+	err = retry.Do(
+		func() error {
+			return client.Login()
+		},
+		append(retryOptions(ctx, retries, retryDelay), retry.RetryIf(func(err error) bool {
+			return !errors.Is(err, fetch.ErrLogin)
+		}))...,
+	)
+	tracing.End(span, err)
+
+	if err != nil {
+		client.CloseConnections()
+
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// scrapeClass fetches and parses subjects, grades, absences, teacher notes, exam events and optionally national exam
+// results and weekly timetable of a single active class.
+func scrapeClass(ctx context.Context, ch chan<- msgtypes.Message, client *fetch.Client, username string, c fetch.Class,
+	multiClass bool, retries uint, retryDelay time.Duration, national, schedule bool, gradeCutoff time.Duration,
+) (err error) {
+	logger.Debug().Msgf("Fetching grades, absences, notes, national exams and calendar events for user %v, class %v, "+
+		"class ID %v", username, c.Name, c.ID)
+
+	var rawGrades string
+
+	var events fetch.Events
+
+	_, span := tracing.Start(ctx, "fetch", tracing.User(username), tracing.Class(c.Name))
+
+	// fetch subjects/grades/exams
+	err = retry.Do(
+		func() error {
+			var err error
+			rawGrades, events, err = client.GetClassEvents(c.ID)
+
+			return err
+		},
+		retryOptions(ctx, retries, retryDelay)...,
+	)
+
+	tracing.End(span, err)
+
+	if err != nil {
+		return err
+	}
+
+	// absences, notes, national exams and timetable are fetched separately, so that their failure does not affect
+	// grades and exams
+	rawAbsences, absencesErr := fetchSection(ctx, username, c, "absences", client.GetAbsences, retries, retryDelay)
+	rawNotes, notesErr := fetchSection(ctx, username, c, "notes", client.GetNotes, retries, retryDelay)
+
+	var rawNational string
+
+	if national {
+		var nationalErr error
+
+		rawNational, nationalErr = fetchSection(ctx, username, c, "national exams", client.GetNationalExams, retries,
+			retryDelay)
+		national = nationalErr == nil
+	}
+
+	var rawSchedule string
+
+	if schedule {
+		var scheduleErr error
+
+		rawSchedule, scheduleErr = fetchSection(ctx, username, c, "timetable", client.GetSchedule, retries, retryDelay)
+		schedule = scheduleErr == nil
+	}
+
+	_, span = tracing.Start(ctx, "parse", tracing.User(username), tracing.Class(c.Name))
+	defer func() { tracing.End(span, err) }()
+
+	// parse all subjects and corresponding grades
+	var cutoff time.Time
+	if gradeCutoff > 0 {
+		cutoff = time.Now().Add(-gradeCutoff)
+	}
+
+	err = parseGrades(ch, username, rawGrades, multiClass, c, cutoff)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrParse, err)
+	}
+
+	// parse all absences
+	if absencesErr == nil {
+		err = parseAbsences(ch, username, rawAbsences, multiClass, c)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrParse, err)
+		}
+	}
+
+	// parse all teacher notes
+	if notesErr == nil {
+		err = parseNotes(ch, username, rawNotes, multiClass, c)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrParse, err)
+		}
+	}
+
+	// parse all national exam results
+	if national {
+		err = parseNationalExams(ch, username, rawNational, multiClass, c)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrParse, err)
+		}
+	}
+
+	// parse weekly timetable
+	if schedule {
+		err = parseSchedule(ch, username, rawSchedule, multiClass, c)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrParse, err)
+		}
+	}
+
+	// parse all exam events
+	if err = parseEvents(ch, username, events, multiClass, c); err != nil {
+		return fmt.Errorf("%w: %w", ErrParse, err)
+	}
+
+	return nil
+}
+
+// fetchSection fetches an optional page section of a class with its own retries and tracing span, logging a failure
+// as a warning instead of failing the whole class.
+func fetchSection(ctx context.Context, username string, c fetch.Class, section string, fetchFn func() (string, error),
+	retries uint, retryDelay time.Duration,
+) (string, error) {
+	_, span := tracing.Start(ctx, "fetch "+section, tracing.User(username), tracing.Class(c.Name))
+
+	var raw string
+
+	err := retry.Do(
+		func() error {
+			var err error
+			raw, err = fetchFn()
+
+			return err
+		},
+		retryOptions(ctx, retries, retryDelay)...,
+	)
+
+	tracing.End(span, err)
+
+	if err != nil {
+		logger.Warn().Msgf("Unable to fetch %v for user %v, class %v, skipping them in this run: %v", section,
+			username, c.Name, err)
+	}
+
+	return raw, err
+}
+
+// retryOptions returns retry options with exponential backoff (starting with a given delay) combined with random jitter
+// (up to the same delay), logging every failed attempt.
+func retryOptions(ctx context.Context, retries uint, delay time.Duration) []retry.Option {
+	return []retry.Option{
+		retry.Attempts(retries),
+		retry.Context(ctx),
+		retry.Delay(delay),
+		retry.MaxJitter(delay),
+		retry.DelayType(retry.CombineDelay(retry.BackOffDelay, retry.RandomDelay)),
+		retry.OnRetry(func(n uint, err error) {
+			logger.Debug().Msgf("Scrape attempt %v failed, retrying: %v", n+1, err)
+		}),
+	}
+}
@@ -0,0 +1,274 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/google/renameio/v2/maybe"
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+const (
+	SecretPrefix = "enc:"          // prefix of encrypted configuration values
+	SecretKeyEnv = "E_DNEVNIK_KEY" // environment variable holding base64 encoded secrets key
+	SecretKeyLen = 32              // secretbox key length
+	nonceLen     = 24              // secretbox nonce length
+	ConfigPerms  = 0o600           // permissions of a newly created configuration file
+)
+
+var (
+	ErrMissingKey      = errors.New("encrypted configuration value found, but no key in " + SecretKeyEnv + " or key file")
+	ErrInvalidKey      = errors.New("secrets key must be 32 bytes encoded in base64")
+	ErrInvalidSecret   = errors.New("invalid encrypted configuration value")
+	ErrDecryptedSecret = errors.New("unable to decrypt configuration value, wrong key?")
+
+	// secretKeys lists sensitive keys per configuration block (blocks can be tables or arrays of tables), which
+	// will be encrypted in --encrypt-config mode.
+	secretKeys = map[string][]string{
+		"user":          {"password"},
+		"telegram":      {"token"},
+		"discord":       {"token", "webhooks"},
+		"slack":         {"token"},
+		"teams":         {"webhooks"},
+		"pushover":      {"token"},
+		"gotify":        {"token"},
+		"mastodon":      {"token"},
+		"twilio":        {"token"},
+		"rocketchat":    {"token"},
+		"mqtt":          {"password"},
+		"irc":           {"sasl_password"},
+		"pushbullet":    {"token"},
+		"homeassistant": {"token"},
+		"mail":          {"password"},
+	}
+
+	// rootSecretKeys lists sensitive top-level keys, which will be encrypted in --encrypt-config mode.
+	rootSecretKeys = []string{"apprise", "trigger_secret"}
+)
+
+// loadKey returns secrets key from the key file if set, or from the environment, returning nil if there is no key.
+func loadKey() ([]byte, error) {
+	encoded := os.Getenv(SecretKeyEnv)
+
+	if *keyFile != "" {
+		b, err := os.ReadFile(*keyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		encoded = string(b)
+	}
+
+	encoded = strings.TrimSpace(encoded)
+	if encoded == "" {
+		return nil, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != SecretKeyLen {
+		return nil, ErrInvalidKey
+	}
+
+	return key, nil
+}
+
+// EncryptField encrypts a configuration value with NaCl secretbox, returning it prefixed with SecretPrefix. Already
+// encrypted values are returned unchanged.
+func EncryptField(key []byte, value string) (string, error) {
+	if strings.HasPrefix(value, SecretPrefix) {
+		return value, nil
+	}
+
+	if len(key) != SecretKeyLen {
+		return "", ErrInvalidKey
+	}
+
+	var (
+		k     [SecretKeyLen]byte
+		nonce [nonceLen]byte
+	)
+
+	copy(k[:], key)
+
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return "", err
+	}
+
+	sealed := secretbox.Seal(nonce[:], []byte(value), &nonce, &k)
+
+	return SecretPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptField decrypts a configuration value prefixed with SecretPrefix. Values without the prefix are returned
+// unchanged.
+func decryptField(key []byte, value string) (string, error) {
+	if !strings.HasPrefix(value, SecretPrefix) {
+		return value, nil
+	}
+
+	if key == nil {
+		return "", ErrMissingKey
+	}
+
+	if len(key) != SecretKeyLen {
+		return "", ErrInvalidKey
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, SecretPrefix))
+	if err != nil || len(sealed) < nonceLen+secretbox.Overhead {
+		return "", ErrInvalidSecret
+	}
+
+	var (
+		k     [SecretKeyLen]byte
+		nonce [nonceLen]byte
+	)
+
+	copy(k[:], key)
+	copy(nonce[:], sealed[:nonceLen])
+
+	plain, ok := secretbox.Open(nil, sealed[nonceLen:], &nonce, &k)
+	if !ok {
+		return "", ErrDecryptedSecret
+	}
+
+	return string(plain), nil
+}
+
+// decryptSecrets walks through all exported string fields of the configuration (including nested structures and
+// slices) and decrypts every value prefixed with SecretPrefix in place.
+func decryptSecrets(v reflect.Value, key []byte) error {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := range v.NumField() {
+			if f := v.Field(i); f.CanSet() {
+				if err := decryptSecrets(f, key); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Slice:
+		for i := range v.Len() {
+			if err := decryptSecrets(v.Index(i), key); err != nil {
+				return err
+			}
+		}
+	case reflect.String:
+		s, err := decryptField(key, v.String())
+		if err != nil {
+			return err
+		}
+
+		v.SetString(s)
+	}
+
+	return nil
+}
+
+// encryptConfig rewrites configuration file with all sensitive values encrypted using the secrets key. Note that
+// comments in the configuration file are not preserved.
+func encryptConfig() error {
+	key, err := loadKey()
+	if err != nil {
+		return err
+	}
+
+	if key == nil {
+		return fmt.Errorf("%w: no key in %v or key file", ErrInvalidKey, SecretKeyEnv)
+	}
+
+	// configuration is rewritten in place
+	if *confFile == ConfigStdin || *confFile == configEnvFile {
+		return fmt.Errorf("%w: %v", ErrConfigNotFile, *confFile)
+	}
+
+	var raw map[string]any
+	if _, err := toml.DecodeFile(*confFile, &raw); err != nil {
+		return err
+	}
+
+	for _, k := range rootSecretKeys {
+		if err := encryptValue(raw, k, key); err != nil {
+			return err
+		}
+	}
+
+	for block, keys := range secretKeys {
+		var tables []map[string]any
+
+		switch t := raw[block].(type) {
+		case map[string]any:
+			tables = append(tables, t)
+		case []map[string]any:
+			tables = t
+		}
+
+		for _, t := range tables {
+			for _, k := range keys {
+				if err := encryptValue(t, k, key); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return SaveConfig(*confFile, raw)
+}
+
+// encryptValue encrypts a single string or a list of strings stored under the key in the configuration table.
+func encryptValue(table map[string]any, name string, key []byte) error {
+	var err error
+
+	switch v := table[name].(type) {
+	case string:
+		table[name], err = EncryptField(key, v)
+	case []any:
+		for i := range v {
+			if s, ok := v[i].(string); ok {
+				if v[i], err = EncryptField(key, s); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return err
+}
+
+// SaveConfig atomically writes configuration in TOML format to the file, keeping permissions of an existing file.
+func SaveConfig(path string, config any) error {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(config); err != nil {
+		return err
+	}
+
+	return maybe.WriteFile(path, buf.Bytes(), ConfigPerms)
+}
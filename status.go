@@ -0,0 +1,118 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/google/renameio/v2/maybe"
+)
+
+var ErrStatusFile = errors.New("unable to write status file")
+
+// runStatus is the last run status written to the status file for external monitoring.
+type runStatus struct {
+	Time     time.Time       `json:"time"`
+	Success  bool            `json:"success"`
+	Profiles []profileStatus `json:"profiles"`
+}
+
+// profileStatus is the last run status of a single configuration profile.
+type profileStatus struct {
+	Name       string                  `json:"name,omitempty"`
+	Success    bool                    `json:"success"`
+	Users      map[string]userStatus   `json:"users"`
+	Messengers map[string]resultStatus `json:"messengers"`
+}
+
+// userStatus is the scrape result and the number of new alerts of a single user.
+type userStatus struct {
+	resultStatus
+	Alerts int `json:"alerts"`
+}
+
+// resultStatus is the result of a single operation, with the error message on failure.
+type resultStatus struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// newResultStatus converts an error to the operation result.
+func newResultStatus(err error) resultStatus {
+	if err != nil {
+		return resultStatus{Error: err.Error()}
+	}
+
+	return resultStatus{Success: true}
+}
+
+// newRunStatus builds the last run status from the results of all profiles.
+func newRunStatus(profiles []*profile, now time.Time) runStatus {
+	status := runStatus{
+		Time:     now,
+		Success:  true,
+		Profiles: make([]profileStatus, 0, len(profiles)),
+	}
+
+	for _, p := range profiles {
+		ps := profileStatus{
+			Name:       p.name,
+			Success:    !p.failed.Load(),
+			Users:      make(map[string]userStatus),
+			Messengers: make(map[string]resultStatus),
+		}
+
+		if p.results != nil {
+			p.results.mu.Lock()
+
+			for username, err := range p.results.scrapes {
+				ps.Users[username] = userStatus{resultStatus: newResultStatus(err), Alerts: p.results.alerts[username]}
+			}
+
+			for name, err := range p.results.sends {
+				ps.Messengers[name] = newResultStatus(err)
+			}
+
+			p.results.mu.Unlock()
+		}
+
+		if !ps.Success {
+			status.Success = false
+		}
+
+		status.Profiles = append(status.Profiles, ps)
+	}
+
+	return status
+}
+
+// writeStatus atomically writes the last run status of all profiles to the status file in JSON format.
+func writeStatus(profiles []*profile) error {
+	b, err := json.MarshalIndent(newRunStatus(profiles, time.Now()), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return maybe.WriteFile(*statusFile, b, ConfigPerms)
+}
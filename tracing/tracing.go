@@ -0,0 +1,137 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/dkorunic/e-dnevnik-bot/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ServiceName     = "e-dnevnik-bot"
+	TracerName      = "github.com/dkorunic/e-dnevnik-bot"
+	ShutdownTimeout = 5 * time.Second
+)
+
+var ErrInvalidEndpoint = errors.New("invalid OTLP endpoint, must be an absolute HTTP or HTTPS URL")
+
+// provider is the configured tracer provider (nil if tracing is disabled).
+var provider *sdktrace.TracerProvider
+
+// Init configures global OTLP/HTTP trace exporter sending spans to the endpoint URL (ie. http://localhost:4318). Until
+// Init is called all spans are no-op.
+func Init(ctx context.Context, endpoint, version string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: %v", ErrInvalidEndpoint, endpoint)
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return err
+	}
+
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceName(ServiceName),
+			semconv.ServiceVersion(version),
+		)),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Warn().Msgf("Unable to export traces: %v", err)
+	}))
+
+	return nil
+}
+
+// Shutdown flushes all pending spans and shuts down the trace exporter, if tracing is enabled.
+func Shutdown() {
+	if provider == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+
+	_ = provider.Shutdown(ctx)
+}
+
+// Start starts a span with given name and attributes, returning a context holding the span.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(TracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends a span, recording an error if encountered.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// Event adds a named event with given attributes to a span.
+func Event(span trace.Span, name string, attrs ...attribute.KeyValue) {
+	span.AddEvent(name, trace.WithAttributes(attrs...))
+}
+
+// Profile returns a span attribute holding a configuration profile name.
+func Profile(name string) attribute.KeyValue {
+	return attribute.String("profile", name)
+}
+
+// User returns a span attribute holding a username.
+func User(username string) attribute.KeyValue {
+	return attribute.String("user", username)
+}
+
+// Subject returns a span attribute holding a subject name.
+func Subject(subject string) attribute.KeyValue {
+	return attribute.String("subject", subject)
+}
+
+// Class returns a span attribute holding a class name.
+func Class(class string) attribute.KeyValue {
+	return attribute.String("class", class)
+}
+
+// Messenger returns a span attribute holding a messenger name.
+func Messenger(name string) attribute.KeyValue {
+	return attribute.String("messenger", name)
+}
@@ -0,0 +1,75 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestInitInvalidEndpoint(t *testing.T) {
+	for _, endpoint := range []string{"localhost:4318", "ftp://localhost:4318", "http://"} {
+		if err := Init(context.Background(), endpoint, ""); !errors.Is(err, ErrInvalidEndpoint) {
+			t.Errorf("Init(%q) = %v, want %v", endpoint, err, ErrInvalidEndpoint)
+		}
+	}
+}
+
+func TestSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	ctx, run := Start(context.Background(), "run")
+
+	_, scrape := Start(ctx, "scrape", User("korisnik@skole.hr"))
+	Event(scrape, "alert", Subject("Matematika"))
+	End(scrape, errors.New("login failed"))
+
+	End(run, nil)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %v spans, want 2", len(spans))
+	}
+
+	if s := spans[0]; s.Name != "scrape" || s.Parent.SpanID() != spans[1].SpanContext.SpanID() {
+		t.Errorf("scrape span %v is not a child of run span", s.Name)
+	}
+
+	if s := spans[0]; s.Status.Code != codes.Error || len(s.Events) != 2 {
+		t.Errorf("scrape span status = %v with %v events, want error status with alert and error events",
+			s.Status.Code, len(s.Events))
+	}
+
+	if s := spans[1]; s.Status.Code != codes.Unset {
+		t.Errorf("run span status = %v, want unset", s.Status.Code)
+	}
+}
@@ -0,0 +1,107 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package trigger
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dkorunic/e-dnevnik-bot/logger"
+)
+
+const (
+	ScrapePath        = "/scrape"
+	BearerPrefix      = "Bearer "
+	ReadHeaderTimeout = 10 * time.Second
+	ShutdownTimeout   = 5 * time.Second
+)
+
+// Handler returns HTTP handler accepting authenticated POST requests on ScrapePath and signalling an immediate
+// scrape on a given channel. Pending requests are coalesced, so a single scrape is triggered at most.
+func Handler(secret string, ch chan<- struct{}) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc(ScrapePath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			_, _ = w.Write([]byte("method not allowed\n"))
+
+			return
+		}
+
+		if !authorized(r, secret) {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte("unauthorized\n"))
+
+			return
+		}
+
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte("accepted\n"))
+	})
+
+	return mux
+}
+
+// authorized checks Authorization header for a bearer token matching the shared secret in constant time.
+func authorized(r *http.Request, secret string) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), BearerPrefix)
+	if !ok || secret == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+}
+
+// Serve starts on-demand scrape HTTP listener on a given address, shutting it down when context gets cancelled.
+func Serve(ctx context.Context, addr, secret string, ch chan<- struct{}) {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           Handler(secret, ch),
+		ReadHeaderTimeout: ReadHeaderTimeout,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		sCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+		defer cancel()
+
+		_ = srv.Shutdown(sCtx) //nolint:contextcheck
+	}()
+
+	logger.Info().Msgf("Serving on-demand scrape trigger on %v%v", addr, ScrapePath)
+
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error().Msgf("Unable to serve on-demand scrape trigger: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+// @license
+// Copyright (C) 2025  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package trigger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	ch := make(chan struct{}, 1)
+	h := Handler("secret", ch)
+
+	check := func(method, auth string, want int) {
+		t.Helper()
+
+		req := httptest.NewRequest(method, ScrapePath, nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != want {
+			t.Errorf("%v %v with %q = %v, want %v", method, ScrapePath, auth, rec.Code, want)
+		}
+	}
+
+	check(http.MethodGet, "Bearer secret", http.StatusMethodNotAllowed)
+	check(http.MethodPost, "", http.StatusUnauthorized)
+	check(http.MethodPost, "Bearer wrong", http.StatusUnauthorized)
+	check(http.MethodPost, "secret", http.StatusUnauthorized)
+
+	if len(ch) != 0 {
+		t.Fatalf("rejected requests triggered a scrape")
+	}
+
+	check(http.MethodPost, "Bearer secret", http.StatusAccepted)
+	check(http.MethodPost, "Bearer secret", http.StatusAccepted)
+
+	if len(ch) != 1 {
+		t.Errorf("pending triggers = %v, want 1", len(ch))
+	}
+}
+
+func TestHandlerEmptySecret(t *testing.T) {
+	ch := make(chan struct{}, 1)
+	h := Handler("", ch)
+
+	req := httptest.NewRequest(http.MethodPost, ScrapePath, nil)
+	req.Header.Set("Authorization", "Bearer ")
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("POST %v with empty secret = %v, want %v", ScrapePath, rec.Code, http.StatusUnauthorized)
+	}
+}